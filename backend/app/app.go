@@ -1,66 +1,266 @@
 package app
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/alanqchen/Bear-Post/backend/config"
 	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/logging"
+	"github.com/alanqchen/Bear-Post/backend/metrics"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	"github.com/alanqchen/Bear-Post/backend/util"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"gopkg.in/ezzarghili/recaptcha-go.v4"
 )
 
-// App holds the Config struct, database connections for Postgres and Redis
-// and the reCaptcha configuration
+// App holds the Config struct, database connections for Postgres and Redis,
+// the reCaptcha configuration and the site settings
 type App struct {
 	Config    config.Config
 	Database  *database.Postgres
 	Redis     *database.Redis
 	Recaptcha recaptcha.ReCAPTCHA
+	Settings  *services.SettingsService
+	// One of the states below, read by the readiness check
+	state int32
+
+	// Background workers run with a context canceled on shutdown
+	workersOnce   sync.Once
+	workersCtx    context.Context
+	cancelWorkers context.CancelFunc
+	workers       sync.WaitGroup
 }
 
+// States of the API
+const (
+	stateStarting int32 = iota
+	stateReady
+	stateStopping
+)
+
 // New connects to the databases and stores the connection in the returned
 // App struct
 func New(appConfig config.Config) *App {
-	log.Println("Connecting to Postgres...")
+	slog.Info("Connecting to Postgres...")
 	db, err := database.NewPostgres(appConfig.PostgreSQL)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to connect to Postgres", "err", err)
 	}
 
-	log.Println("Connecting to Redis...")
+	slog.Info("Migrating database...")
+	if err := database.Migrate(context.Background(), db); err != nil {
+		logging.Fatal("Failed to migrate database", "err", err)
+	}
+
+	slog.Info("Connecting to Redis...")
 	redis, err := database.NewRedis(appConfig.RedisDB)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to connect to Redis", "err", err)
 	}
 
-	log.Println("Successfully connected to databases")
+	slog.Info("Successfully connected to databases")
 
-	log.Println("Setting up ReCaptcha...")
+	if err := util.SetTrustedProxies(appConfig.Proxy.TrustedProxies, appConfig.Proxy.ClientIPHeader); err != nil {
+		logging.Fatal("Failed to set the trusted proxies", "err", err)
+	}
+
+	slog.Debug("Setting up ReCaptcha...")
 	captcha, err := recaptcha.NewReCAPTCHA(appConfig.CaptchaSecret, recaptcha.V2, 10*time.Second)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to set up ReCaptcha", "err", err)
 	}
 
-	log.Println("Successfully set ReCaptcha secret")
+	slog.Debug("Successfully set ReCaptcha secret")
+
+	if err := metrics.RegisterPools(db, redis); err != nil {
+		logging.Fatal("Failed to register the pool metrics", "err", err)
+	}
 
-	return &App{appConfig, db, redis, captcha}
+	slog.Debug("Loading site settings...")
+	settings := services.NewSettingsService(repositories.NewSettingsRepository(db), &appConfig)
+
+	return &App{Config: appConfig, Database: db, Redis: redis, Recaptcha: captcha, Settings: settings}
 }
 
-// Run sets up CORS policy and allows the API listen and serve
+// Run sets up CORS policy and allows the API listen and serve until it gets SIGTERM or SIGINT.
+// It then stops accepting connections, waits for the requests being served, stops the background
+// workers and closes the database connections.
 func (a *App) Run(r *mux.Router) {
-	headersOk := handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "X-Requested-With"})
-	originsOk := handlers.AllowedOrigins(a.Config.AllowedOrigins)
-	log.Println("Allowed origins:", a.Config.AllowedOrigins)
+	headersOk := handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "X-Requested-With", "If-Match", "X-Request-ID"})
+	// The post version is sent as an ETag for optimistic concurrency
+	exposedOk := handlers.ExposedHeaders([]string{"ETag", "X-Request-ID"})
+	// The allowed origins are a site setting so they can change while running
+	originsOk := handlers.AllowedOriginValidator(a.Settings.IsAllowedOrigin)
+	slog.Info("Allowed origins", "origins", a.Settings.Get().AllowedOrigins)
 	methodsOk := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"})
 	port := a.Config.Port
 	addr := fmt.Sprintf(":%v", port)
 
-	fmt.Printf("API is listening on port: %v\n", port)
-	log.Fatal(http.ListenAndServe(addr, handlers.CORS(originsOk, headersOk, methodsOk, handlers.AllowCredentials())(r)))
+	handler := handlers.CORS(originsOk, headersOk, exposedOk, methodsOk, handlers.AllowCredentials())(r)
+	var servers []*http.Server
+	if a.Config.TLS.Enabled() {
+		certs, err := newCertReloader(a.Config.TLS.CertFile, a.Config.TLS.KeyFile)
+		if err != nil {
+			logging.Fatal("Failed to load the TLS certificate", "err", err)
+		}
+		a.Go(certs.watch)
+
+		tlsPort := a.Config.TLS.ListenPort()
+		tlsServer := a.newServer(fmt.Sprintf(":%v", tlsPort), hsts(a.Config.TLS.HSTS(), a.Config.TLS.HSTSIncludeSubDomains, handler))
+		tlsServer.TLSConfig = newTLSConfig(certs)
+		servers = append(servers, tlsServer)
+		slog.Info("HTTPS is listening", "port", tlsPort)
+
+		if a.Config.TLS.RedirectHTTP {
+			handler = redirectToHTTPS(tlsPort)
+		}
+	}
+	servers = append(servers, a.newServer(addr, handler))
+	// A separate address keeps the metrics off the public port
+	if metricsAddr := a.Config.Metrics.Address; metricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler(a.Config.Metrics.Token))
+		servers = append(servers, a.newServer(metricsAddr, metricsMux))
+		slog.Info("Metrics are listening", "address", metricsAddr)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			var err error
+			if server.TLSConfig != nil {
+				// The certificate comes from the TLS config
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err != http.ErrServerClosed {
+				errs <- err
+			}
+		}(server)
+	}
+	slog.Info("API is listening", "port", port)
+	atomic.StoreInt32(&a.state, stateReady)
+
+	select {
+	case err := <-errs:
+		logging.Fatal("API stopped", "err", err)
+	case sig := <-signals:
+		slog.Info("Shutting down", "signal", sig.String())
+	}
+
+	a.MarkStopping()
+	timeouts := a.Config.Server.Timeouts()
+	if timeouts.Drain > 0 {
+		// Load balancers see /readyz fail and stop sending requests before connections are refused
+		slog.Info("Draining before shutdown", "delay", timeouts.Drain)
+		time.Sleep(timeouts.Drain)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Shutdown)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("Requests were still running at the shutdown deadline", "address", server.Addr, "err", err)
+		}
+	}
+
+	a.stopWorkers(ctx)
+	a.Close()
+	slog.Info("API stopped")
+}
+
+// Returns an HTTP server with the configured limits
+func (a *App) newServer(addr string, handler http.Handler) *http.Server {
+	timeouts := a.Config.Server.Timeouts()
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+		MaxHeaderBytes:    a.Config.Server.MaxHeaderBytesOrDefault(),
+	}
+}
+
+// Go runs a background worker, whose context is canceled when the API shuts down. Run waits for
+// the workers to return before closing the database connections.
+func (a *App) Go(worker func(ctx context.Context)) {
+	ctx := a.context()
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		worker(ctx)
+	}()
+}
+
+// Returns the context of the background workers
+func (a *App) context() context.Context {
+	a.workersOnce.Do(func() {
+		a.workersCtx, a.cancelWorkers = context.WithCancel(context.Background())
+	})
+	return a.workersCtx
+}
+
+// Cancels the context of the background workers and waits for them to return until ctx is done
+func (a *App) stopWorkers(ctx context.Context) {
+	a.context()
+	a.cancelWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("Background workers were still running at the shutdown deadline")
+	}
+}
+
+// Close closes the Postgres pool and the Redis client
+func (a *App) Close() {
+	if a.Database != nil {
+		a.Database.Close()
+	}
+	if a.Redis != nil {
+		if err := a.Redis.Close(); err != nil {
+			slog.Warn("Failed to close the Redis client", "err", err)
+		}
+	}
+}
+
+// MarkStopping makes the readiness check fail while the API shuts down
+func (a *App) MarkStopping() {
+	atomic.StoreInt32(&a.state, stateStopping)
+}
+
+// State returns if the API is starting, serving requests (ok) or stopping
+func (a *App) State() string {
+	switch atomic.LoadInt32(&a.state) {
+	case stateReady:
+		return models.HealthOK
+	case stateStopping:
+		return models.HealthStopping
+	}
+	return models.HealthStarting
 }
 
 // IsProd returns if the App struct is configured for production
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the certificate files are checked for changes
+const certReloadInterval = 30 * time.Second
+
+// Holds the certificate served over TLS and reloads it when its files change
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// Loads the certificate and key files
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Returns the latest modification time of the certificate and key files
+func (cr *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Loads the files again if they changed since they were loaded, and returns if they did.
+// The previous certificate is kept if the new files are invalid.
+func (cr *certReloader) reload() (bool, error) {
+	modTime, err := cr.filesModTime()
+	if err != nil {
+		return false, err
+	}
+	cr.mu.RLock()
+	changed := cr.cert == nil || !modTime.Equal(cr.modTime)
+	cr.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return false, err
+	}
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.modTime = modTime
+	cr.mu.Unlock()
+	return true, nil
+}
+
+// Checks the files every certReloadInterval until the context is done
+func (cr *certReloader) watch(ctx context.Context) {
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := cr.reload()
+		if err != nil {
+			// Certificates are often replaced one file at a time, so this is retried
+			slog.Warn("Failed to reload the TLS certificate", "err", err)
+		} else if reloaded {
+			slog.Info("Reloaded the TLS certificate", "file", cr.certFile)
+		}
+	}
+}
+
+// GetCertificate returns the latest certificate for the tls.Config of the server
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// Returns a TLS config with modern defaults that serves the certificate of the reloader
+func newTLSConfig(cr *certReloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		// Only used by TLS 1.2, TLS 1.3 suites aren't configurable
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: cr.GetCertificate,
+	}
+}
+
+// Adds the Strict-Transport-Security header to the responses
+func hsts(maxAge int, includeSubDomains bool, next http.Handler) http.Handler {
+	if maxAge <= 0 {
+		return next
+	}
+	value := "max-age=" + strconv.Itoa(maxAge)
+	if includeSubDomains {
+		value += "; includeSubDomains"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Redirects requests to the same URL over HTTPS on the given port
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/config"
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	"github.com/alanqchen/Bear-Post/backend/util"
+)
+
+/*
+ * Subcommands
+ *
+ * config print       Prints the config with the secrets redacted, and its problems
+ * export [-o file]   Writes a zip archive of the posts, users and media
+ * import [-format wxr|ghost|markdown] [-dry-run] [-author username] [-site-url url] path
+ *                    Imports a WordPress or Ghost export, or a directory or zip of Markdown files
+ */
+
+// Runs the subcommand and returns the exit code
+func runCommand(cfg config.Config, name string, args []string) int {
+	switch name {
+	case "config":
+		return runConfig(cfg, args)
+	case "export":
+		return runExport(cfg, args)
+	case "import":
+		return runImport(cfg, args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %v. Commands: config, export, import\n", name)
+		return 2
+	}
+}
+
+// Prints the config with the secrets redacted, then the problems Validate finds
+func runConfig(cfg config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: config print")
+		return 2
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		slog.Error("Failed to encode config", "err", err)
+		return 1
+	}
+
+	var invalid *config.ValidationError
+	if errors.As(cfg.Validate(), &invalid) {
+		for _, problem := range invalid.Problems {
+			fmt.Fprintln(os.Stderr, "Invalid config:", problem)
+		}
+		return 1
+	}
+	return 0
+}
+
+// Writes an export archive to a file
+func runExport(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("o", "bearpost-export-"+time.Now().UTC().Format("20060102-150405")+".zip", "archive file to write")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := database.NewPostgres(cfg.PostgreSQL)
+	if err != nil {
+		slog.Error("Failed to connect to Postgres", "err", err)
+		return 1
+	}
+	defer db.Close()
+	if err := database.Migrate(context.Background(), db); err != nil {
+		slog.Error("Failed to migrate database", "err", err)
+		return 1
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		slog.Error("Failed to create archive", "err", err)
+		return 1
+	}
+
+	err = services.ExportArchive(context.Background(), f, repositories.NewPostRepository(db), repositories.NewUserRespository(db))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		slog.Error("Failed to export archive", "err", err)
+		os.Remove(*out)
+		return 1
+	}
+
+	slog.Info("Exported archive", "file", *out)
+	return 0
+}
+
+// Imports posts from a file or a directory of Markdown files and prints the report
+func runImport(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "wxr, ghost or markdown, by default from the file extension")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving")
+	authorName := fs.String("author", "", "username of the author of posts without one, by default the first admin")
+	siteURL := fs.String("site-url", "", "URL of the Ghost site, used to download its media")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: import [flags] path")
+		fs.PrintDefaults()
+		return 2
+	}
+	src := fs.Arg(0)
+	ctx := context.Background()
+
+	db, err := database.NewPostgres(cfg.PostgreSQL)
+	if err != nil {
+		slog.Error("Failed to connect to Postgres", "err", err)
+		return 1
+	}
+	defer db.Close()
+	if err := database.Migrate(context.Background(), db); err != nil {
+		slog.Error("Failed to migrate database", "err", err)
+		return 1
+	}
+
+	pr := repositories.NewPostRepository(db)
+	ur := repositories.NewUserRespository(db)
+	settings := services.NewSettingsService(repositories.NewSettingsRepository(db), &cfg)
+
+	author, err := importAuthor(ctx, ur, *authorName)
+	if err != nil {
+		slog.Error("Failed to find import author", "err", err)
+		return 1
+	}
+
+	var posts []*models.ImportPost
+	var source services.MediaSource
+	info, err := os.Stat(src)
+	if err != nil {
+		slog.Error("Failed to read import", "err", err)
+		return 1
+	}
+	if info.IsDir() {
+		*format = models.ImportMarkdown
+		posts, err = services.ReadMarkdownDir(src)
+		source = services.DirSource(src)
+	} else {
+		if *format == "" {
+			*format = services.ImportFormat(src)
+		}
+		var f *os.File
+		f, err = os.Open(src)
+		if err != nil {
+			slog.Error("Failed to read import", "err", err)
+			return 1
+		}
+		defer f.Close()
+		posts, source, err = services.ReadImport(*format, f, info.Size())
+	}
+	if err != nil {
+		slog.Error("Failed to read import", "err", err)
+		return 1
+	}
+
+	importer := services.NewImporter(pr, ur, author, settings.Get().DefaultFeatureImageURL, source, *dryRun)
+	importer.SiteURL = *siteURL
+	report := importer.Import(ctx, *format, posts)
+
+	// A running server would keep serving the cached pages without the imported posts
+	if !*dryRun && report.Created > 0 {
+		redis, err := database.NewRedis(cfg.RedisDB)
+		if err != nil {
+			slog.Warn("Failed to flush cache, restart the server to see the imported posts", "err", err)
+		} else {
+			keys := append(util.PostCacheKeys(), report.Tags...)
+			if res := redis.Del(keys...); res.Err() != nil {
+				slog.Warn("Failed to flush cache, restart the server to see the imported posts", "err", res.Err())
+			}
+			redis.Close()
+		}
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Returns the user with the username, or the first admin
+func importAuthor(ctx context.Context, ur repositories.UserRepository, username string) (*models.User, error) {
+	if username != "" {
+		user, err := ur.FindByUsername(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		if user.Username == "" {
+			return nil, fmt.Errorf("user %v not found", username)
+		}
+		return user, nil
+	}
+
+	users, err := ur.GetAllDetailed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.Before(users[j].CreatedAt) })
+	for _, u := range users {
+		if u.Admin {
+			return u.User, nil
+		}
+	}
+	return nil, errors.New("no admin to import posts as, create one or pass -author")
+}
@@ -1,9 +1,7 @@
 package config
 
 import (
-	"encoding/json"
-	"log"
-	"os"
+	"time"
 )
 
 // PostgreSQLConfig holds the configuration for the Postgres database
@@ -11,14 +9,14 @@ type PostgreSQLConfig struct {
 	Host     string `json:"host"`
 	Port     string `json:"port"`
 	User     string `json:"user"`
-	Password string `json:"password"`
+	Password string `json:"password" secret:"true"`
 	Database string `json:"database"`
 	Timezone string `json:"timezone"`
 }
 
 // JWTConfig holds the configuration for the JWT authentication
 type JWTConfig struct {
-	Secret     string `json:"secret"`
+	Secret     string `json:"secret" secret:"true"`
 	PublicKey  string `json:"public_key"`
 	PrivateKey string `json:"private_key"`
 }
@@ -27,32 +25,184 @@ type JWTConfig struct {
 type RedisConfig struct {
 	Host     string `json:"host"`
 	Port     string `json:"port"`
-	Password string `json:"password"`
+	Password string `json:"password" secret:"true"`
+}
+
+// LogConfig holds the configuration for the logs
+type LogConfig struct {
+	// json or text
+	Format string `json:"format"`
+	// debug, info, warn or error
+	Level string `json:"level"`
+}
+
+// MetricsConfig holds the configuration for the Prometheus metrics. They are only served if an
+// address or a token is set.
+type MetricsConfig struct {
+	// Address to serve /metrics on instead of the API's port, such as 127.0.0.1:9100
+	Address string `json:"address"`
+	// Bearer token needed to read /metrics
+	Token string `json:"token" secret:"true"`
+}
+
+// Enabled returns if the metrics are served
+func (c MetricsConfig) Enabled() bool {
+	return c.Address != "" || c.Token != ""
+}
+
+// ProxyConfig holds the proxies trusted to send the client IP of requests
+type ProxyConfig struct {
+	// CIDRs or IPs of the proxies, defaults to the loopback networks and [] trusts none
+	TrustedProxies []string `json:"trustedProxies"`
+	// Header the proxies send the client IP in, defaults to X-Forwarded-For
+	ClientIPHeader string `json:"clientIPHeader"`
+}
+
+// TLSConfig holds the configuration for serving HTTPS. It is served if the cert and key files are
+// set, and the files are reloaded when they change.
+type TLSConfig struct {
+	// Port of the HTTPS listener
+	Port     string `json:"port"`
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// Redirect the requests to the HTTP port to HTTPS instead of serving them
+	RedirectHTTP bool `json:"redirectHTTP"`
+	// max-age of the Strict-Transport-Security header in seconds, defaults to a day and 0 disables it
+	HSTSMaxAge *int `json:"hstsMaxAge"`
+	// Also apply the Strict-Transport-Security header to the subdomains, which must all serve HTTPS
+	HSTSIncludeSubDomains bool `json:"hstsIncludeSubDomains"`
+}
+
+// Default max-age of the Strict-Transport-Security header, short so a mistake doesn't lock browsers
+// out of plain HTTP for long. Raise it once HTTPS works.
+const defaultHSTSMaxAge = 24 * 60 * 60
+
+// Enabled returns if HTTPS is served
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ListenPort returns the port of the HTTPS listener, 443 if it is unset
+func (c TLSConfig) ListenPort() string {
+	if c.Port == "" {
+		return "443"
+	}
+	return c.Port
+}
+
+// HSTS returns the max-age of the Strict-Transport-Security header in seconds, or 0 if it isn't sent
+func (c TLSConfig) HSTS() int {
+	if c.HSTSMaxAge == nil {
+		return defaultHSTSMaxAge
+	}
+	if *c.HSTSMaxAge < 0 {
+		return 0
+	}
+	return *c.HSTSMaxAge
+}
+
+// ServerConfig holds the limits of the HTTP server. Timeouts are in seconds, and unset ones use
+// the defaults below.
+type ServerConfig struct {
+	ReadHeaderTimeout int `json:"readHeaderTimeout"`
+	// Includes the body, so it bounds how long an upload may take
+	ReadTimeout  int `json:"readTimeout"`
+	WriteTimeout int `json:"writeTimeout"`
+	IdleTimeout  int `json:"idleTimeout"`
+	// How long /readyz reports stopping on SIGTERM or SIGINT before new connections are refused, so
+	// load balancers stop sending requests first. Unset or 0 doesn't wait.
+	DrainDelay int `json:"drainDelay"`
+	// How long in-flight requests have to finish on SIGTERM or SIGINT
+	ShutdownTimeout int `json:"shutdownTimeout"`
+	MaxHeaderBytes  int `json:"maxHeaderBytes"`
+}
+
+// ServerTimeouts holds the timeouts of the HTTP server
+type ServerTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	Shutdown   time.Duration
+	Drain      time.Duration
+}
+
+// Default limits of the HTTP server
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 5 * time.Minute
+	defaultWriteTimeout      = 5 * time.Minute
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultShutdownTimeout   = 30 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+)
+
+// Timeouts returns the timeouts of the HTTP server
+func (c ServerConfig) Timeouts() ServerTimeouts {
+	return ServerTimeouts{
+		ReadHeader: seconds(c.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		Read:       seconds(c.ReadTimeout, defaultReadTimeout),
+		Write:      seconds(c.WriteTimeout, defaultWriteTimeout),
+		Idle:       seconds(c.IdleTimeout, defaultIdleTimeout),
+		Shutdown:   seconds(c.ShutdownTimeout, defaultShutdownTimeout),
+		Drain:      time.Duration(c.DrainDelay) * time.Second,
+	}
+}
+
+// MaxHeaderBytesOrDefault returns the maximum size of request headers
+func (c ServerConfig) MaxHeaderBytesOrDefault() int {
+	if c.MaxHeaderBytes <= 0 {
+		return defaultMaxHeaderBytes
+	}
+	return c.MaxHeaderBytes
+}
+
+// Returns the seconds as a duration, or def if they aren't positive
+func seconds(s int, def time.Duration) time.Duration {
+	if s <= 0 {
+		return def
+	}
+	return time.Duration(s) * time.Second
 }
 
 // Config holds the configuration for the whole API
 type Config struct {
-	Env            string           `json:"env"`
-	PostgreSQL     PostgreSQLConfig `json:"postgreSQL"`
-	JWT            JWTConfig        `json:"jwt"`
-	RedisDB        RedisConfig      `json:"RedisDB"`
-	Port           string           `json:"port"`
-	AllowedOrigins []string         `json:"allowedOrigins"`
-	CaptchaSecret  string           `json:"captchaSecret"`
+	Env                string           `json:"env"`
+	PostgreSQL         PostgreSQLConfig `json:"postgreSQL"`
+	JWT                JWTConfig        `json:"jwt"`
+	RedisDB            RedisConfig      `json:"RedisDB"`
+	Port               string           `json:"port"`
+	AllowedOrigins     []string         `json:"allowedOrigins"`
+	CaptchaSecret      string           `json:"captchaSecret" secret:"true"`
+	TrashRetentionDays *int             `json:"trashRetentionDays"`
+	Log                LogConfig        `json:"log"`
+	Metrics            MetricsConfig    `json:"metrics"`
+	Server             ServerConfig     `json:"server"`
+	TLS                TLSConfig        `json:"tls"`
+	Proxy              ProxyConfig      `json:"proxy"`
+}
+
+// Default number of days deleted posts and users stay in the trash
+const defaultTrashRetentionDays = 30
+
+// TrashRetention returns how long deleted posts and users stay in the trash, or 0 if they are
+// never purged automatically. trashRetentionDays defaults to 30 and 0 disables the purge.
+func (c *Config) TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if c.TrashRetentionDays != nil {
+		days = *c.TrashRetentionDays
+	}
+	if days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
 }
 
-// New returns a Config struct based on a given JSON file
+// New returns a Config struct based on a given JSON file over the defaults
 func New(path string) (Config, error) {
-	file, err := os.Open(path)
-	defer file.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
-	decoder := json.NewDecoder(file)
-	cfg := Config{}
-	err = decoder.Decode(&cfg)
-	if err != nil {
-		log.Fatal(err)
+	cfg := Default()
+	if err := readFile(path, &cfg); err != nil {
+		return Config{}, err
 	}
 
 	return cfg, nil
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of the environment variables that override the config
+const EnvPrefix = "MGBLOG_"
+
+// Suffix of the environment variables that read a setting from a file, such as a Docker secret.
+// Flags use -file instead.
+const fileSuffix = "_FILE"
+
+// SearchPaths are the config files read if none is given, the first one that exists is used
+var SearchPaths = []string{"config/app-custom.json", "config/app.json", "../app.json", "config/app-docker.json"}
+
+// Shown instead of secrets when the config is printed
+const redacted = "REDACTED"
+
+// Default returns the config used for the settings the other layers don't set
+func Default() Config {
+	return Config{
+		Env:        "prod",
+		PostgreSQL: PostgreSQLConfig{Host: "localhost", Port: "5432"},
+		JWT:        JWTConfig{PublicKey: "config/api.rsa.pub", PrivateKey: "config/api.rsa"},
+		RedisDB:    RedisConfig{Host: "localhost", Port: "6379"},
+		Port:       "8080",
+		Log:        LogConfig{Format: "json", Level: "info"},
+	}
+}
+
+// Load builds the config from the defaults, then the config file, then the MGBLOG_ environment
+// variables, then the flags at the start of args, each overriding the ones before. It returns the
+// config, the file it read or "" if none was found, and the arguments after the flags. Environment
+// variables and flags that can't be set are returned as a ValidationError listing all of them.
+func Load(args []string) (Config, string, []string, error) {
+	cfg := Default()
+	settings := settingsOf(&cfg)
+
+	// Flags are applied last, but the file can be one of them
+	fs := flag.NewFlagSet("bearpost", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "config file to read, instead of the first of "+strings.Join(SearchPaths, ", "))
+	var flagValues []func() error
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flagName(), s.usage(), func(value string) error {
+			flagValues = append(flagValues, func() error { return s.set(value) })
+			return nil
+		})
+		if s.value.Kind() == reflect.String {
+			fs.Func(s.flagName()+"-file", "file to read "+s.flagName()+" from", func(file string) error {
+				flagValues = append(flagValues, func() error { return s.setFromFile(file) })
+				return nil
+			})
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, "", nil, err
+	}
+
+	file := *path
+	if file == "" {
+		for _, candidate := range SearchPaths {
+			if _, err := os.Stat(candidate); err == nil {
+				file = candidate
+				break
+			}
+		}
+	}
+	if file != "" {
+		if err := readFile(file, &cfg); err != nil {
+			return Config{}, "", nil, err
+		}
+	}
+
+	v := &validator{}
+	for _, s := range settings {
+		if err := s.setFromEnv(); err != nil {
+			v.addf("%v", err)
+		}
+	}
+	for _, apply := range flagValues {
+		if err := apply(); err != nil {
+			v.addf("%v", err)
+		}
+	}
+	if len(v.problems) > 0 {
+		return Config{}, "", nil, &ValidationError{v.problems}
+	}
+
+	return cfg, file, fs.Args(), nil
+}
+
+// Decodes the JSON file over the config
+func readFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(cfg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("%v: %v must be a %v, not a %v", path, typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with the secrets replaced, to be printed
+func (c Config) Redacted() Config {
+	cfg := c
+	for _, s := range settingsOf(&cfg) {
+		if s.secret && s.value.String() != "" {
+			s.value.SetString(redacted)
+		}
+	}
+	return cfg
+}
+
+// A setting is a field of the config that isn't a struct
+type setting struct {
+	// JSON names of the field and the structs it is in
+	path   []string
+	value  reflect.Value
+	secret bool
+}
+
+// Returns the settings of the config, which can be set through them
+func settingsOf(cfg *Config) []*setting {
+	var settings []*setting
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fieldPath := append(append([]string(nil), path...), name)
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), fieldPath)
+				continue
+			}
+			settings = append(settings, &setting{fieldPath, v.Field(i), field.Tag.Get("secret") == "true"})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), nil)
+	return settings
+}
+
+// Name of the setting's flag, such as postgresql.password
+func (s *setting) flagName() string {
+	return strings.ToLower(strings.Join(s.path, "."))
+}
+
+// Name of the setting's environment variable, such as MGBLOG_POSTGRESQL_PASSWORD
+func (s *setting) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.Join(s.path, "_"))
+}
+
+func (s *setting) usage() string {
+	switch s.value.Kind() {
+	case reflect.Slice:
+		return "comma-separated values of " + strings.Join(s.path, ".")
+	}
+	return "sets " + strings.Join(s.path, ".")
+}
+
+// Sets the setting from its environment variable, or the file its _FILE variable names
+func (s *setting) setFromEnv() error {
+	if value, ok := os.LookupEnv(s.envName()); ok {
+		if err := s.set(value); err != nil {
+			return fmt.Errorf("%v: %v", s.envName(), err)
+		}
+	}
+	if file, ok := os.LookupEnv(s.envName() + fileSuffix); ok && s.value.Kind() == reflect.String {
+		if err := s.setFromFile(file); err != nil {
+			return fmt.Errorf("%v: %v", s.envName()+fileSuffix, err)
+		}
+	}
+	return nil
+}
+
+// Sets the setting to the contents of the file, without the trailing newline
+func (s *setting) setFromFile(file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return s.set(strings.TrimRight(string(content), "\r\n"))
+}
+
+// Parses the value into the setting
+func (s *setting) set(value string) error {
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%v must be an integer, not %q", strings.Join(s.path, "."), value)
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%v must be true or false, not %q", strings.Join(s.path, "."), value)
+		}
+		s.value.SetBool(b)
+	case reflect.Ptr:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%v must be an integer, not %q", strings.Join(s.path, "."), value)
+		}
+		s.value.Set(reflect.ValueOf(&n))
+	case reflect.Slice:
+		values := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		s.value.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("%v can't be set", strings.Join(s.path, "."))
+	}
+	return nil
+}
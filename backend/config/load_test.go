@@ -0,0 +1,270 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Writes the content to a file in the test's temporary directory and returns its path
+func writeTestFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Returns a config that passes Validate
+func validConfig(t *testing.T) Config {
+	cfg := Default()
+	cfg.PostgreSQL.User = "bear"
+	cfg.PostgreSQL.Database = "bearpost"
+	cfg.JWT.Secret = "jwt-secret"
+	cfg.JWT.PublicKey = writeTestFile(t, "api.rsa.pub", "public")
+	cfg.JWT.PrivateKey = writeTestFile(t, "api.rsa", "private")
+	cfg.CaptchaSecret = "captcha-secret"
+	return cfg
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeTestFile(t, "app.json", `{
+		"port": "8081",
+		"postgreSQL": {"host": "file-host", "user": "file-user"},
+		"RedisDB": {"port": "6380"},
+		"log": {"level": "debug"},
+		"allowedOrigins": ["https://file.example"]
+	}`)
+	t.Setenv("MGBLOG_PORT", "8082")
+	t.Setenv("MGBLOG_POSTGRESQL_HOST", "env-host")
+	t.Setenv("MGBLOG_ALLOWEDORIGINS", "https://a.example, https://b.example")
+	t.Setenv("MGBLOG_SERVER_READTIMEOUT", "30")
+
+	cfg, path, args, err := Load([]string{"-config", file, "-port", "8083", "-tls.redirecthttp", "true", "export", "-o", "out.zip"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if path != file {
+		t.Errorf("path = %v, want %v", path, file)
+	}
+	if want := []string{"export", "-o", "out.zip"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	cases := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"flag overrides env and file", cfg.Port, "8083"},
+		{"env overrides file", cfg.PostgreSQL.Host, "env-host"},
+		{"file overrides default", cfg.RedisDB.Port, "6380"},
+		{"file sets unset setting", cfg.PostgreSQL.User, "file-user"},
+		{"file overrides default log level", cfg.Log.Level, "debug"},
+		{"default is kept", cfg.Log.Format, "json"},
+		{"default host is kept", cfg.RedisDB.Host, "localhost"},
+		{"env list is split and trimmed", cfg.AllowedOrigins, []string{"https://a.example", "https://b.example"}},
+		{"env integer", cfg.Server.ReadTimeout, 30},
+		{"flag bool", cfg.TLS.RedirectHTTP, true},
+	}
+	for _, c := range cases {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%v: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	file := writeTestFile(t, "app.json", `{"env": "dev"}`)
+	t.Setenv("MGBLOG_CONFIG", file)
+
+	cfg, path, _, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if path != file || cfg.Env != "dev" {
+		t.Errorf("Load read %v with env %v, want %v with env dev", path, cfg.Env, file)
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	config := writeTestFile(t, "app.json", `{"jwt": {"secret": "file-secret"}}`)
+	t.Setenv("MGBLOG_JWT_SECRET", "env-secret")
+	t.Setenv("MGBLOG_JWT_SECRET_FILE", writeTestFile(t, "jwt", "docker-secret\n"))
+	t.Setenv("MGBLOG_REDISDB_PASSWORD_FILE", writeTestFile(t, "redis", "redis-secret\r\n"))
+	t.Setenv("MGBLOG_CAPTCHASECRET_FILE", writeTestFile(t, "captcha", "  spaced secret \n\n"))
+
+	cfg, _, _, err := Load([]string{"-config", config, "-postgresql.password-file", writeTestFile(t, "postgres", "postgres-secret\n")})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"_FILE overrides the variable and the file", cfg.JWT.Secret, "docker-secret"},
+		{"CRLF is trimmed", cfg.RedisDB.Password, "redis-secret"},
+		{"only trailing newlines are trimmed", cfg.CaptchaSecret, "  spaced secret "},
+		{"-file flag", cfg.PostgreSQL.Password, "postgres-secret"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%v: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	config := writeTestFile(t, "app.json", `{}`)
+	cases := map[string]string{
+		"MGBLOG_SERVER_READTIMEOUT":   "soon",
+		"MGBLOG_TRASHRETENTIONDAYS":   "forever",
+		"MGBLOG_TLS_REDIRECTHTTP":     "maybe",
+		"MGBLOG_JWT_SECRET_FILE":      filepath.Join(t.TempDir(), "missing"),
+		"MGBLOG_METRICS_ADDRESS_FILE": t.TempDir(),
+	}
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			_, _, _, err := Load([]string{"-config", config})
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Load error = %v, want a ValidationError", err)
+			}
+			if len(invalid.Problems) != 1 || !strings.HasPrefix(invalid.Problems[0], name+": ") {
+				t.Errorf("Problems = %q, want one problem with %v", invalid.Problems, name)
+			}
+		})
+	}
+}
+
+func TestLoadListsEveryInvalidSetting(t *testing.T) {
+	config := writeTestFile(t, "app.json", `{}`)
+	t.Setenv("MGBLOG_SERVER_READTIMEOUT", "soon")
+	t.Setenv("MGBLOG_SERVER_IDLETIMEOUT", "later")
+
+	_, _, _, err := Load([]string{"-config", config, "-tls.redirecthttp", "maybe"})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Load error = %v, want a ValidationError", err)
+	}
+	want := []string{
+		`MGBLOG_SERVER_READTIMEOUT: server.readTimeout must be an integer, not "soon"`,
+		`MGBLOG_SERVER_IDLETIMEOUT: server.idleTimeout must be an integer, not "later"`,
+		`tls.redirectHTTP must be true or false, not "maybe"`,
+	}
+	if !reflect.DeepEqual(invalid.Problems, want) {
+		t.Errorf("Problems = %q, want %q", invalid.Problems, want)
+	}
+}
+
+func TestSettingNames(t *testing.T) {
+	cfg := Default()
+	cases := map[string]string{
+		"postgreSQL.password": "MGBLOG_POSTGRESQL_PASSWORD",
+		"RedisDB.host":        "MGBLOG_REDISDB_HOST",
+		"jwt.public_key":      "MGBLOG_JWT_PUBLIC_KEY",
+		"server.drainDelay":   "MGBLOG_SERVER_DRAINDELAY",
+		"captchaSecret":       "MGBLOG_CAPTCHASECRET",
+	}
+	found := 0
+	for _, s := range settingsOf(&cfg) {
+		want, ok := cases[strings.Join(s.path, ".")]
+		if !ok {
+			continue
+		}
+		found++
+		if got := s.envName(); got != want {
+			t.Errorf("envName of %v = %v, want %v", s.path, got, want)
+		}
+		if got, want := s.flagName(), strings.ToLower(strings.Join(s.path, ".")); got != want {
+			t.Errorf("flagName of %v = %v, want %v", s.path, got, want)
+		}
+	}
+	if found != len(cases) {
+		t.Errorf("found %v of the %v settings", found, len(cases))
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.PostgreSQL.Password = "postgres-secret"
+	cfg.RedisDB.Password = "redis-secret"
+	cfg.Metrics.Token = "metrics-token"
+
+	redactedCfg := cfg.Redacted()
+
+	secrets := map[string]bool{}
+	for _, s := range settingsOf(&redactedCfg) {
+		if !s.secret {
+			continue
+		}
+		name := strings.Join(s.path, ".")
+		secrets[name] = true
+		if got := s.value.String(); got != redacted {
+			t.Errorf("%v = %q, want %q", name, got, redacted)
+		}
+	}
+	want := map[string]bool{
+		"postgreSQL.password": true,
+		"jwt.secret":          true,
+		"RedisDB.password":    true,
+		"captchaSecret":       true,
+		"metrics.token":       true,
+	}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("secrets = %v, want %v", secrets, want)
+	}
+
+	if redactedCfg.PostgreSQL.User != cfg.PostgreSQL.User || redactedCfg.Port != cfg.Port {
+		t.Errorf("Redacted changed settings that aren't secret")
+	}
+	if cfg.JWT.Secret != "jwt-secret" {
+		t.Errorf("Redacted changed the original config")
+	}
+	if empty := Default().Redacted(); empty.JWT.Secret != "" {
+		t.Errorf("Redacted set an empty secret to %q", empty.JWT.Secret)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"port out of range", func(c *Config) { c.Port = "0" }, []string{`port must be a port between 1 and 65535, not "0"`}},
+		{"missing secrets", func(c *Config) { c.JWT.Secret = ""; c.CaptchaSecret = "" }, []string{"jwt.secret is required", "captchaSecret is required"}},
+		{"unknown log format", func(c *Config) { c.Log.Format = "xml" }, []string{`log.format must be one of json, text, not "xml"`}},
+		{"negative drain delay", func(c *Config) { c.Server.DrainDelay = -1 }, []string{"server.drainDelay must not be negative, not -1"}},
+		{"redirect without TLS", func(c *Config) { c.TLS.RedirectHTTP = true }, []string{"tls.redirectHTTP needs tls.certFile and tls.keyFile"}},
+		{"proxy isn't an IP", func(c *Config) { c.Proxy.TrustedProxies = []string{"10.0.0.0/8", "localhost"} }, []string{`proxy.trustedProxies[1] must be an IP or CIDR, not "localhost"`}},
+		{"metrics address without port", func(c *Config) { c.Metrics.Address = "localhost" }, []string{`metrics.address must be a host:port address, not "localhost"`}},
+	}
+	for _, c := range cases {
+		cfg := validConfig(t)
+		c.modify(&cfg)
+		err := cfg.Validate()
+		if c.want == nil {
+			if err != nil {
+				t.Errorf("%v: Validate = %v, want nil", c.name, err)
+			}
+			continue
+		}
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			t.Errorf("%v: Validate = %v, want a ValidationError", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(invalid.Problems, c.want) {
+			t.Errorf("%v: Problems = %q, want %q", c.name, invalid.Problems, c.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists every problem found in a config
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Collects the problems of a config
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// Checks that the setting is set
+func (v *validator) required(name string, value string) {
+	if value == "" {
+		v.addf("%v is required", name)
+	}
+}
+
+// Checks that the setting is a TCP port
+func (v *validator) port(name string, value string) {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		v.addf("%v must be a port between 1 and 65535, not %q", name, value)
+	}
+}
+
+// Checks that the setting is a file that exists
+func (v *validator) file(name string, path string) {
+	if path == "" {
+		v.addf("%v is required", name)
+	} else if _, err := os.Stat(path); err != nil {
+		v.addf("%v: %v", name, err)
+	}
+}
+
+// Checks that the setting isn't negative
+func (v *validator) notNegative(name string, value int) {
+	if value < 0 {
+		v.addf("%v must not be negative, not %v", name, value)
+	}
+}
+
+// Checks that the setting is one of the values, ignoring case
+func (v *validator) oneOf(name string, value string, values ...string) {
+	for _, allowed := range values {
+		if strings.EqualFold(value, allowed) {
+			return
+		}
+	}
+	v.addf("%v must be one of %v, not %q", name, strings.Join(values, ", "), value)
+}
+
+// Validate checks that the config can be used to run the API, and returns a ValidationError
+// listing every problem if it can't
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.required("env", c.Env)
+	v.port("port", c.Port)
+
+	v.required("postgreSQL.host", c.PostgreSQL.Host)
+	v.port("postgreSQL.port", c.PostgreSQL.Port)
+	v.required("postgreSQL.user", c.PostgreSQL.User)
+	v.required("postgreSQL.database", c.PostgreSQL.Database)
+
+	v.required("RedisDB.host", c.RedisDB.Host)
+	v.port("RedisDB.port", c.RedisDB.Port)
+
+	v.required("jwt.secret", c.JWT.Secret)
+	v.file("jwt.public_key", c.JWT.PublicKey)
+	v.file("jwt.private_key", c.JWT.PrivateKey)
+
+	v.required("captchaSecret", c.CaptchaSecret)
+
+	if c.TrashRetentionDays != nil {
+		v.notNegative("trashRetentionDays", *c.TrashRetentionDays)
+	}
+
+	if c.Log.Format != "" {
+		v.oneOf("log.format", c.Log.Format, "json", "text")
+	}
+	if c.Log.Level != "" {
+		v.oneOf("log.level", c.Log.Level, "debug", "info", "warn", "warning", "error")
+	}
+
+	if c.Metrics.Address != "" {
+		if _, port, err := net.SplitHostPort(c.Metrics.Address); err != nil {
+			v.addf("metrics.address must be a host:port address, not %q", c.Metrics.Address)
+		} else {
+			v.port("metrics.address port", port)
+		}
+	}
+
+	v.notNegative("server.readHeaderTimeout", c.Server.ReadHeaderTimeout)
+	v.notNegative("server.readTimeout", c.Server.ReadTimeout)
+	v.notNegative("server.writeTimeout", c.Server.WriteTimeout)
+	v.notNegative("server.idleTimeout", c.Server.IdleTimeout)
+	v.notNegative("server.drainDelay", c.Server.DrainDelay)
+	v.notNegative("server.shutdownTimeout", c.Server.ShutdownTimeout)
+	v.notNegative("server.maxHeaderBytes", c.Server.MaxHeaderBytes)
+
+	if c.TLS.CertFile != "" || c.TLS.KeyFile != "" {
+		v.file("tls.certFile", c.TLS.CertFile)
+		v.file("tls.keyFile", c.TLS.KeyFile)
+		if c.TLS.Port != "" {
+			v.port("tls.port", c.TLS.Port)
+		}
+		if c.TLS.ListenPort() == c.Port {
+			v.addf("tls.port must not be the same as port")
+		}
+		if c.TLS.HSTSMaxAge != nil {
+			v.notNegative("tls.hstsMaxAge", *c.TLS.HSTSMaxAge)
+		}
+	} else if c.TLS.RedirectHTTP {
+		v.addf("tls.redirectHTTP needs tls.certFile and tls.keyFile")
+	}
+
+	for i, proxy := range c.Proxy.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			v.addf("proxy.trustedProxies[%v] must be an IP or CIDR, not %q", i, proxy)
+		}
+	}
+	if strings.ContainsAny(c.Proxy.ClientIPHeader, " :") {
+		v.addf("proxy.clientIPHeader must be a header name, not %q", c.Proxy.ClientIPHeader)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{v.problems}
+	}
+	return nil
+}
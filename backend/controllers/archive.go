@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// ArchiveController stores the App config and repositories
+type ArchiveController struct {
+	*app.App
+	repositories.PostRepository
+	repositories.UserRepository
+}
+
+// NewArchiveController creates a new archive controller
+func NewArchiveController(a *app.App, pr repositories.PostRepository, ur repositories.UserRepository) *ArchiveController {
+	return &ArchiveController{a, pr, ur}
+}
+
+// GetAll returns the number of public posts grouped by year and month
+func (ac *ArchiveController) GetAll(w http.ResponseWriter, r *http.Request) {
+	years, err := ac.PostRepository.GetArchive(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch archive", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if years == nil {
+		years = []*models.ArchiveYear{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: years}, w, http.StatusOK)
+}
+
+// GetPage returns a keyset pagination page of the public posts in the given year, and month if given
+func (ac *ArchiveController) GetPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	year, err := strconv.Atoi(vars["year"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid year", http.StatusBadRequest}, w)
+		return
+	}
+
+	// Month 0 means the whole year
+	month := 0
+	if monthString, ok := vars["month"]; ok {
+		month, err = strconv.Atoi(monthString)
+		if err != nil || month < 1 || month > 12 {
+			NewAPIError(&APIError{false, "Month is not within bounds [1, 12]", http.StatusBadRequest}, w)
+			return
+		}
+	}
+
+	q := r.URL.Query()
+	after, _, ok := getPageCursor(r.Context(), ac.PostRepository, q, true, w)
+	if !ok {
+		return
+	}
+
+	settings := ac.App.Settings.Get()
+	perPage, ok := getPerPage(q.Get("num"), settings.PostsPerPage, &settings, w)
+	if !ok {
+		return
+	}
+
+	total, _ := ac.PostRepository.GetArchivePostCount(r.Context(), year, month)
+
+	posts, minID, err := ac.PostRepository.PaginateArchive(r.Context(), after, perPage, year, month)
+	if err != nil && err != pgx.ErrNoRows {
+		slog.ErrorContext(r.Context(), "Could not fetch posts", "err", err)
+		NewAPIError(&APIError{false, "Could not fetch posts", http.StatusBadRequest}, w)
+		return
+	}
+
+	if len(posts) == 0 {
+		posts = make([]*models.Post, 0)
+		NewAPIResponse(&APIResponse{Success: true, Message: "Could not find more posts", Data: posts}, w, http.StatusOK)
+		return
+	}
+
+	setAuthors(r.Context(), ac.UserRepository, ac.PostRepository, posts, true)
+
+	postPaginator := APIPagination{
+		total,
+		perPage,
+		minID,
+		posts[len(posts)-1].Cursor().String(),
+		[]string{},
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator}, w, http.StatusOK)
+}
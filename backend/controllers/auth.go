@@ -1,10 +1,11 @@
 package controllers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/metrics"
 	"github.com/alanqchen/Bear-Post/backend/models"
 	"github.com/alanqchen/Bear-Post/backend/repositories"
 	"github.com/alanqchen/Bear-Post/backend/services"
@@ -49,15 +50,17 @@ func (ac *AuthController) Authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	*/
-	u, err := ac.UserRepository.FindByUsername(username)
+	u, err := ac.UserRepository.FindByUsername(r.Context(), username)
 	if err != nil {
-		log.Println("[BAD LOGIN] - username:", username)
+		slog.WarnContext(r.Context(), "Authentication failed", "reason", "Unknown username", "username", username)
+		metrics.Login(false)
 		NewAPIError(&APIError{false, "Incorrect email or password", http.StatusBadRequest}, w)
 		return
 	}
 
 	if ok := u.CheckPassword(pw); !ok {
-		log.Printf("[BAD LOGIN] - username: %v password %v", username, pw)
+		slog.WarnContext(r.Context(), "Authentication failed", "reason", "Incorrect password", "username", username)
+		metrics.Login(false)
 		NewAPIError(&APIError{false, "Incorrect email or password", http.StatusBadRequest}, w)
 		return
 	}
@@ -81,7 +84,8 @@ func (ac *AuthController) Authenticate(w http.ResponseWriter, r *http.Request) {
 		authUser,
 	}
 
-	log.Println("[LOGIN SUCCESS] - username:", username)
+	metrics.Login(true)
+	slog.InfoContext(r.Context(), "Logged in", "username", username)
 	NewAPIResponse(&APIResponse{Success: true, Message: "Login successful", Data: data}, w, http.StatusOK)
 }
 
@@ -109,7 +113,7 @@ func (ac *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
 	for _, token := range keys.Val() {
 		err := ac.App.Redis.Del(token).Err()
 		if err != nil {
-			log.Printf("Could not delete token: %s ; error: %v", token, err)
+			slog.ErrorContext(r.Context(), "Could not delete token", "err", err)
 			NewAPIError(&APIError{false, "Something went wrong", http.StatusBadRequest}, w)
 			return
 		}
@@ -131,7 +135,7 @@ func (ac *AuthController) LogoutAll(w http.ResponseWriter, r *http.Request) {
 	for _, token := range keys.Val() {
 		err := ac.App.Redis.Del(token).Err()
 		if err != nil {
-			log.Printf("Could not delete token: %s ; error: %v", token, err)
+			slog.ErrorContext(r.Context(), "Could not delete token", "err", err)
 		}
 	}
 
@@ -155,7 +159,7 @@ func (ac *AuthController) RefreshTokens(w http.ResponseWriter, r *http.Request)
 		NewAPIError(&APIError{false, "Something went wrong", http.StatusBadRequest}, w)
 		return
 	}
-	u, err := ac.UserRepository.FindByIDDetailed(uid)
+	u, err := ac.UserRepository.FindByIDDetailed(r.Context(), uid)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not find user", http.StatusBadRequest}, w)
 		return
@@ -170,7 +174,7 @@ func (ac *AuthController) RefreshTokens(w http.ResponseWriter, r *http.Request)
 	for _, token := range keys.Val() {
 		err := ac.App.Redis.Del(token).Err()
 		if err != nil {
-			log.Printf("Could not delete token: %s ; error: %v", token, err)
+			slog.ErrorContext(r.Context(), "Could not delete token", "err", err)
 			NewAPIError(&APIError{false, "Something went wrong", http.StatusBadRequest}, w)
 			return
 		}
@@ -189,7 +193,7 @@ func (ac *AuthController) RefreshTokens(w http.ResponseWriter, r *http.Request)
 		authUser,
 	}
 
-	log.Println("[AUTH] Created new refresh token for user", u.Username)
+	slog.InfoContext(r.Context(), "Created new refresh token", "username", u.Username)
 	NewAPIResponse(&APIResponse{Success: true, Message: "Refresh successful", Data: data}, w, http.StatusOK)
 }
 
@@ -214,10 +218,10 @@ func (ac *AuthController) VerifyCaptcha(w http.ResponseWriter, r *http.Request)
 		err = ac.App.Recaptcha.VerifyWithOptions(token, recaptcha.VerifyOption{RemoteIP: remoteIP})
 	}
 	if err != nil {
-		log.Println("[BAD AUTH] Failed recaptcha verification", err)
+		slog.WarnContext(r.Context(), "Authentication failed", "reason", "Failed recaptcha verification", "err", err)
 		NewAPIError(&APIError{false, "Failed to verify token", http.StatusBadRequest}, w)
 		return
 	}
-	log.Println("[AUTH] Passed reCaptcha verification")
+	slog.DebugContext(r.Context(), "Passed reCaptcha verification")
 	NewAPIResponse(&APIResponse{Success: true, Message: "reCaptcha verification successful"}, w, http.StatusOK)
 }
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// AuthorController stores the App config and repositories
+type AuthorController struct {
+	*app.App
+	repositories.UserRepository
+	repositories.PostRepository
+}
+
+// NewAuthorController creates a new author controller
+func NewAuthorController(a *app.App, ur repositories.UserRepository, pr repositories.PostRepository) *AuthorController {
+	return &AuthorController{a, ur, pr}
+}
+
+// GetByUsername returns the public profile of the author with the given username and a keyset
+// pagination page of their public posts
+func (ac *AuthorController) GetByUsername(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	username := vars["username"]
+
+	user, err := ac.UserRepository.FindByUsername(r.Context(), username)
+	if err != nil || user.Username == "" {
+		NewAPIError(&APIError{false, "Could not find author", http.StatusNotFound}, w)
+		return
+	}
+
+	q := r.URL.Query()
+	after, _, ok := getPageCursor(r.Context(), ac.PostRepository, q, false, w)
+	if !ok {
+		return
+	}
+
+	settings := ac.App.Settings.Get()
+	perPage, ok := getPerPage(q.Get("num"), settings.PostsPerPage, &settings, w)
+	if !ok {
+		return
+	}
+
+	authorID := user.ID.String()
+	total, _ := ac.PostRepository.GetAuthorPostCount(r.Context(), authorID)
+
+	posts, minID, err := ac.PostRepository.PaginateByAuthor(r.Context(), after, perPage, authorID)
+	if err != nil && err != pgx.ErrNoRows {
+		slog.ErrorContext(r.Context(), "Could not fetch posts", "err", err)
+		NewAPIError(&APIError{false, "Could not fetch posts", http.StatusBadRequest}, w)
+		return
+	}
+
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	setAuthors(r.Context(), ac.UserRepository, ac.PostRepository, posts, true)
+
+	if user.SocialHandles == nil {
+		user.SocialHandles = map[string]string{}
+	}
+
+	data := struct {
+		Author *models.AuthorProfile `json:"author"`
+		Posts  []*models.Post        `json:"posts"`
+	}{
+		&models.AuthorProfile{
+			ID:        authorID,
+			Name:      user.Name,
+			Username:  user.Username,
+			CreatedAt: user.CreatedAt,
+			Profile:   user.Profile,
+			PostCount: total,
+		},
+		posts,
+	}
+
+	cursor := ""
+	if len(posts) > 0 {
+		cursor = posts[len(posts)-1].Cursor().String()
+	}
+	postPaginator := APIPagination{
+		total,
+		perPage,
+		minID,
+		cursor,
+		[]string{},
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: data, Pagination: &postPaginator}, w, http.StatusOK)
+}
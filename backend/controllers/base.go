@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 )
 
 // BaseController is the common interface for all controllers
@@ -43,6 +44,7 @@ type APIPagination struct {
 	Total   int      `json:"total"`
 	PerPage int      `json:"perPage"`
 	MinID   int      `json:"minID"`
+	Cursor  string   `json:"cursor"`
 	Tags    []string `json:"tags"`
 }
 
@@ -50,10 +52,11 @@ type APIPagination struct {
 func (p *APIPagination) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(struct {
-		Total   int `json:"total"`
-		PerPage int `json:"perPage"`
-		MinID   int `json:"minID"`
-	}{p.Total, p.PerPage, p.MinID})
+		Total   int    `json:"total"`
+		PerPage int    `json:"perPage"`
+		MinID   int    `json:"minID"`
+		Cursor  string `json:"cursor"`
+	}{p.Total, p.PerPage, p.MinID, p.Cursor})
 }
 
 // GetJSON returns the JSON data from a given io reader
@@ -63,7 +66,7 @@ func GetJSON(reader io.Reader) (*JSONData, error) {
 	d.UseNumber()
 	err := d.Decode(&j.data)
 	if err != nil {
-		log.Print(err)
+		slog.Debug("Failed to decode JSON", "err", err)
 		return nil, err
 	}
 
@@ -85,13 +88,42 @@ func (d *JSONData) GetString(key string) (string, error) {
 func (d *JSONData) GetInt(key string) (int, error) {
 	keys := d.data
 	err := errors.New("Could not find key: " + key)
-	if v, ok := keys[key].(int); ok {
-		return v, nil
+	if v, ok := keys[key].(json.Number); ok {
+		i, convErr := strconv.Atoi(v.String())
+		if convErr != nil {
+			return -1, convErr
+		}
+		return i, nil
 	}
 
 	return -1, err
 }
 
+// GetIntArray gets the int array value of a key in the given JSON data
+func (d *JSONData) GetIntArray(key string) ([]int, error) {
+	keys := d.data
+	err := errors.New("Could not find key: " + key)
+	if v, ok := keys[key].([]interface{}); ok {
+
+		keySlice := make([]int, len(v))
+
+		for i, vIn := range v {
+			num, ok := vIn.(json.Number)
+			if !ok {
+				return []int{}, errors.New("Key " + key + " must only contain integers")
+			}
+			n, convErr := strconv.Atoi(num.String())
+			if convErr != nil {
+				return []int{}, convErr
+			}
+			keySlice[i] = n
+		}
+		return keySlice, nil
+	}
+
+	return []int{}, err
+}
+
 // GetBool gets the bool value of a key in the given JSON data
 func (d *JSONData) GetBool(key string) (bool, error) {
 	keys := d.data
@@ -127,7 +159,7 @@ func NewAPIError(e *APIError, w http.ResponseWriter) {
 
 	err := json.NewEncoder(w).Encode(e)
 	if err != nil {
-		log.Println("[API ERROR]: The server encountered an unexpected error.")
+		slog.Error("Failed to encode API error", "err", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -140,7 +172,7 @@ func NewAPIResponse(res *APIResponse, w http.ResponseWriter, code int) {
 
 	err := json.NewEncoder(w).Encode(res)
 	if err != nil {
-		log.Println("[API RESPONSE]: The website encountered an unexpected error.")
+		slog.Error("Failed to encode API response", "err", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// SwaggerUIDir is the directory of the swagger-ui-dist files the docs page loads, so the page
+// doesn't depend on a CDN
+const SwaggerUIDir = "./public/swagger-ui"
+
+// DocsController serves the OpenAPI document of the API
+type DocsController struct {
+	spec []byte
+}
+
+// NewDocsController creates a new docs controller serving the given OpenAPI document
+func NewDocsController(spec []byte) *DocsController {
+	return &DocsController{spec}
+}
+
+// Spec returns the OpenAPI document
+func (dc *DocsController) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(dc.spec)
+}
+
+// UI returns a Swagger UI page for the OpenAPI document
+func (dc *DocsController) UI(w http.ResponseWriter, r *http.Request) {
+	if _, err := os.Stat(filepath.Join(SwaggerUIDir, "swagger-ui-bundle.js")); err != nil {
+		NewAPIError(&APIError{false, "Swagger UI is not installed in public/swagger-ui", http.StatusNotFound}, w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(swaggerUIPage))
+}
+
+// The spec and Swagger UI are loaded relative to the page, so it works behind a proxy
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Bear Post API</title>
+	<link rel="stylesheet" href="docs/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="docs/swagger-ui-bundle.js"></script>
+	<script src="docs/swagger-ui-standalone-preset.js"></script>
+	<script>
+		window.ui = SwaggerUIBundle({
+			url: "openapi.json",
+			dom_id: "#swagger-ui",
+			presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+			layout: "StandaloneLayout"
+		});
+	</script>
+</body>
+</html>
+`
@@ -1,7 +1,7 @@
 package controllers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/alanqchen/Bear-Post/backend/app"
@@ -19,7 +19,7 @@ func NewErrorController(a *app.App) *ErrorController {
 
 // NotFound returns a not found error
 func (ec *ErrorController) NotFound(w http.ResponseWriter, r *http.Request) {
-	log.Println("[WARN] No matching routes")
+	slog.WarnContext(r.Context(), "No matching routes")
 	NewAPIError(&APIError{false, "Invalid route", http.StatusNotFound}, w)
 	return
 }
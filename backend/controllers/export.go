@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+)
+
+// How long an export or import may take, longer than the server's read and write timeouts
+const transferTimeout = 30 * time.Minute
+
+// ExportController stores the App config and repositories
+type ExportController struct {
+	*app.App
+	repositories.PostRepository
+	repositories.UserRepository
+}
+
+// NewExportController creates a new export controller
+func NewExportController(a *app.App, pr repositories.PostRepository, ur repositories.UserRepository) *ExportController {
+	return &ExportController{a, pr, ur}
+}
+
+// Export streams a zip archive of the posts, users and media as it's built
+func (ec *ExportController) Export(w http.ResponseWriter, r *http.Request) {
+	name := "bearpost-export-" + time.Now().UTC().Format("20060102-150405") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(transferTimeout)); err != nil {
+		slog.WarnContext(r.Context(), "Could not extend the export's write deadline", "err", err)
+	}
+
+	// The status was sent with the first bytes, so a failure can only cut the archive short
+	err := services.ExportArchive(r.Context(), w, ec.PostRepository, ec.UserRepository)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to export archive", "err", err)
+	}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/alanqchen/Bear-Post/backend/graph"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// GraphQLController serves the GraphQL schema
+type GraphQLController struct {
+	schema *graph.Schema
+}
+
+// NewGraphQLController creates a new GraphQL controller
+func NewGraphQLController(schema *graph.Schema) *GraphQLController {
+	return &GraphQLController{schema}
+}
+
+// Query executes a GraphQL request with a JSON body of its query, operationName and variables.
+// Mutations need the bearer token of a user.
+func (gc *GraphQLController) Query(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Content-type"), "application/json") {
+		sendGraphQLError("Request body must be of type application/json", http.StatusBadRequest, w)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1*MB)
+
+	var req graph.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		sendGraphQLError("Invalid request", http.StatusBadRequest, w)
+		return
+	}
+
+	res := gc.schema.Exec(r.Context(), &req)
+	body, err := json.Marshal(res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode GraphQL response", "err", err)
+		sendGraphQLError("Something went wrong", http.StatusInternalServerError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// Sends a response with a single error in the format GraphQL clients expect
+func sendGraphQLError(message string, status int, w http.ResponseWriter) {
+	body, _ := json.Marshal(&graphql.Response{Errors: []*gqlerrors.QueryError{{Message: message}}})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// CreatePost creates a post as the current user with the same validation as Create
+func (pc *PostController) CreatePost(ctx context.Context, input map[string]interface{}) (*models.Post, error) {
+	uid, err := services.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("Authentication required")
+	}
+	post, apiErr := pc.createPost(ctx, uid, &JSONData{input})
+	if apiErr != nil {
+		return nil, errors.New(apiErr.Message)
+	}
+	return post, nil
+}
+
+// UpdatePost updates a post at the given version with the same validation as Update
+func (pc *PostController) UpdatePost(ctx context.Context, id int, version int, input map[string]interface{}) (*models.Post, error) {
+	uid, err := services.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("Authentication required")
+	}
+	post, err := pc.PostRepository.FindByIDAdmin(ctx, id)
+	if err != nil {
+		return nil, errors.New("Could not find post")
+	}
+	if !pc.canEdit(ctx, uid, post) {
+		return nil, errors.New("Only the post's authors or an admin can edit it")
+	}
+	if version != post.Version {
+		return nil, errors.New("Post was changed by someone else")
+	}
+
+	post, apiErr := pc.updatePost(ctx, uid, post, &JSONData{input})
+	if apiErr != nil {
+		return nil, errors.New(apiErr.Message)
+	}
+	return post, nil
+}
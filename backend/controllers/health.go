@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+)
+
+// How long the readiness checks may take together
+const readinessTimeout = 2 * time.Second
+
+// Directories uploads are written to
+var uploadDirs = []string{"./public/images/original", "./public/images/webp", "./public/videos"}
+
+// HealthController stores the App config and the repository the readiness checks use
+type HealthController struct {
+	*app.App
+	repositories.HealthRepository
+}
+
+// NewHealthController returns a new HealthController struct
+func NewHealthController(a *app.App, hr repositories.HealthRepository) *HealthController {
+	return &HealthController{a, hr}
+}
+
+// Live responds as long as the process can serve requests
+func (hc *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	NewAPIResponse(&APIResponse{Success: true, Message: "The API is alive"}, w, http.StatusOK)
+}
+
+// Ready checks the API's dependencies, and responds with 503 if one of them fails or the API is
+// starting or shutting down
+func (hc *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	if state := hc.App.State(); state != models.HealthOK {
+		NewAPIResponse(&APIResponse{Success: false, Message: "The API is not ready", Data: &models.Readiness{Status: state}}, w, http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"postgres": hc.HealthRepository.Ping,
+		"redis":    hc.checkRedis,
+		"schema":   hc.checkSchema,
+		"uploads":  checkUploadDirs,
+	}
+
+	readiness := &models.Readiness{Status: models.HealthOK, Checks: make(map[string]*models.HealthCheck, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			result := &models.HealthCheck{Status: models.HealthOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status = models.HealthFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			readiness.Checks[name] = result
+			if err != nil {
+				readiness.Status = models.HealthFail
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if readiness.Status != models.HealthOK {
+		for name, check := range readiness.Checks {
+			if check.Status != models.HealthOK {
+				slog.WarnContext(r.Context(), "Readiness check failed", "check", name, "err", check.Error)
+			}
+		}
+		NewAPIResponse(&APIResponse{Success: false, Message: "The API is not ready", Data: readiness}, w, http.StatusServiceUnavailable)
+		return
+	}
+	NewAPIResponse(&APIResponse{Success: true, Data: readiness}, w, http.StatusOK)
+}
+
+// Pings Redis
+func (hc *HealthController) checkRedis(ctx context.Context) error {
+	return hc.App.Redis.WithContext(ctx).Ping().Err()
+}
+
+// Checks that the database has every migration the API needs
+func (hc *HealthController) checkSchema(ctx context.Context) error {
+	version, err := hc.HealthRepository.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if latest := database.LatestMigration(); version < latest {
+		return fmt.Errorf("schema version %d is older than %d, the migrations haven't been applied", version, latest)
+	}
+	return nil
+}
+
+// Checks that a file can be written to each upload directory
+func checkUploadDirs(ctx context.Context) error {
+	for _, dir := range uploadDirs {
+		file, err := ioutil.TempFile(dir, ".readyz-")
+		if err != nil {
+			return err
+		}
+		file.Close()
+		os.Remove(file.Name())
+	}
+	return nil
+}
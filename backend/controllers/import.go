@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	"github.com/alanqchen/Bear-Post/backend/util"
+)
+
+// ImportController stores the App config and repositories
+type ImportController struct {
+	*app.App
+	repositories.PostRepository
+	repositories.UserRepository
+}
+
+// NewImportController creates a new import controller
+func NewImportController(a *app.App, pr repositories.PostRepository, ur repositories.UserRepository) *ImportController {
+	return &ImportController{a, pr, ur}
+}
+
+// Import imports the posts of an uploaded WordPress export (.xml), Ghost export (.json) or zip
+// archive of Markdown files (.zip) and returns a report. Posts without an author are imported
+// as the current user. With dryRun=true nothing is saved.
+func (ic *ImportController) Import(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-type")
+	if !strings.Contains(contentType, "multipart/form-data") {
+		NewAPIError(&APIError{false, "Invalid request body. Request body must be of type multipart/form-data", http.StatusBadRequest}, w)
+		return
+	}
+	// Uploading the file and downloading its media can take longer than the server's timeouts
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(transferTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		slog.WarnContext(r.Context(), "Could not extend the import's read deadline", "err", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		slog.WarnContext(r.Context(), "Could not extend the import's write deadline", "err", err)
+	}
+
+	// Limit upload size
+	r.Body = http.MaxBytesReader(w, r.Body, 200*MB)
+
+	if err := r.ParseMultipartForm(32 * MB); err != nil {
+		NewAPIError(&APIError{false, "The file you are uploading is too big. Maximum file size is 200MB", http.StatusBadRequest}, w)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if err == http.ErrMissingFile {
+			NewAPIError(&APIError{false, "File is required", http.StatusBadRequest}, w)
+			return
+		}
+		NewAPIError(&APIError{false, "Error processing multipart data", http.StatusBadRequest}, w)
+		return
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = services.ImportFormat(header.Filename)
+	}
+	if format != models.ImportWXR && format != models.ImportGhost && format != models.ImportMarkdown {
+		NewAPIError(&APIError{false, "Format must be wxr, ghost or markdown", http.StatusBadRequest}, w)
+		return
+	}
+
+	dryRun := false
+	switch r.FormValue("dryRun") {
+	case "", "false":
+	case "true":
+		dryRun = true
+	default:
+		NewAPIError(&APIError{false, "Invalid dryRun value", http.StatusBadRequest}, w)
+		return
+	}
+
+	uid, err := services.UserIDFromContext(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
+		return
+	}
+	user, err := ic.UserRepository.FindByID(r.Context(), uid)
+	if err != nil || user.Username == "" {
+		NewAPIError(&APIError{false, "Could not find user", http.StatusInternalServerError}, w)
+		return
+	}
+
+	posts, source, err := services.ReadImport(format, file, header.Size)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to read import", "format", format, "err", err)
+		NewAPIError(&APIError{false, "Could not read the " + format + " import", http.StatusBadRequest}, w)
+		return
+	}
+
+	importer := services.NewImporter(ic.PostRepository, ic.UserRepository, user, ic.App.Settings.Get().DefaultFeatureImageURL, source, dryRun)
+	importer.SiteURL = r.FormValue("siteUrl")
+	report := importer.Import(r.Context(), format, posts)
+
+	if !dryRun && report.Created > 0 {
+		keys := util.PostCacheKeys()
+		keys = append(keys, report.Tags...)
+		res := ic.App.Redis.Del(keys...)
+		if res.Err() != nil {
+			slog.WarnContext(r.Context(), "Failed to flush import cache", "err", res.Err())
+		}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Import finished", Data: report}, w, http.StatusOK)
+}
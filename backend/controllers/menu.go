@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/metrics"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/go-redis/redis"
+	"github.com/gorilla/mux"
+)
+
+// MenuController stores the App config and repositories
+type MenuController struct {
+	*app.App
+	repositories.MenuRepository
+	repositories.PostRepository
+	repositories.PageRepository
+	repositories.TagRepository
+}
+
+// NewMenuController creates a new menu controller
+func NewMenuController(a *app.App, mr repositories.MenuRepository, pr repositories.PostRepository, pgr repositories.PageRepository, tr repositories.TagRepository) *MenuController {
+	return &MenuController{a, mr, pr, pgr, tr}
+}
+
+// Limits of a single menu
+const (
+	maxMenuDepth      = 3
+	maxMenuItems      = 100
+	maxMenuLabelRunes = 64
+)
+
+var menuNameRegex = regexp.MustCompile(`^[a-z0-9\-]{1,32}$`)
+
+// GetAll returns every menu with its items
+func (mc *MenuController) GetAll(w http.ResponseWriter, r *http.Request) {
+	val, err := mc.App.Redis.Get(util.MenusCacheKey).Result()
+	if err != nil && err != redis.Nil {
+		slog.WarnContext(r.Context(), "Failed to check menus cache", "err", err)
+	}
+	if err == nil && val != "" {
+		var res APIResponse
+		if err := json.Unmarshal([]byte(val), &res); err == nil {
+			metrics.CacheLookup("menus", true)
+			slog.DebugContext(r.Context(), "Returned menus from cache")
+			NewAPIResponse(&res, w, http.StatusOK)
+			return
+		}
+	}
+	metrics.CacheLookup("menus", false)
+
+	menus, err := mc.MenuRepository.GetAll(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch menus", http.StatusBadRequest}, w)
+		return
+	}
+	for _, menu := range menus {
+		menu.Items = mc.publicItems(r.Context(), menu.Items)
+	}
+
+	// If result is nil, set to empty array
+	if menus == nil {
+		menus = []*models.Menu{}
+	}
+
+	res := &APIResponse{Success: true, Data: menus}
+	jMenus, err := json.Marshal(res)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to add menus to cache", "err", err)
+	} else if val := mc.App.Redis.Set(util.MenusCacheKey, jMenus, 0); val.Err() != nil {
+		slog.WarnContext(r.Context(), "Failed to add menus to cache", "err", val.Err())
+	} else {
+		slog.DebugContext(r.Context(), "Menus added to cache")
+	}
+
+	NewAPIResponse(res, w, http.StatusOK)
+}
+
+// Update creates or replaces the menu with the given name
+func (mc *MenuController) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	if !menuNameRegex.MatchString(name) {
+		NewAPIError(&APIError{false, "Menu name may only contain lowercase letters, numbers and dashes", http.StatusBadRequest}, w)
+		return
+	}
+
+	var req struct {
+		Items []*models.MenuItem `json:"items"`
+	}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&req); err != nil || req.Items == nil {
+		NewAPIError(&APIError{false, "Menu items are required", http.StatusBadRequest}, w)
+		return
+	}
+
+	count := 0
+	if !mc.checkItems(r.Context(), req.Items, 1, &count, w) {
+		return
+	}
+
+	now := time.Now()
+	menu := &models.Menu{
+		Name:      name,
+		Items:     req.Items,
+		UpdatedAt: &now,
+	}
+
+	err := mc.MenuRepository.Save(r.Context(), menu)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not save menu", http.StatusBadRequest}, w)
+		return
+	}
+	mc.flushCache(r.Context())
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Menu saved", Data: menu}, w, http.StatusOK)
+}
+
+// Delete deletes the menu with the given name
+func (mc *MenuController) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	_, err := mc.MenuRepository.FindByName(r.Context(), name)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find menu to delete", http.StatusNotFound}, w)
+		return
+	}
+
+	err = mc.MenuRepository.Delete(r.Context(), name)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not delete menu", http.StatusInternalServerError}, w)
+		return
+	}
+	mc.flushCache(r.Context())
+
+	NewAPIResponse(&APIResponse{Success: true, Data: name}, w, http.StatusOK)
+}
+
+// Returns true if every item links to existing content, otherwise sends an error response.
+// Empty labels of posts, pages and tags are filled with their title.
+func (mc *MenuController) checkItems(ctx context.Context, items []*models.MenuItem, depth int, count *int, w http.ResponseWriter) bool {
+	if depth > maxMenuDepth {
+		NewAPIError(&APIError{false, "Menus can only be nested 3 levels deep", http.StatusBadRequest}, w)
+		return false
+	}
+
+	for _, item := range items {
+		*count++
+		if *count > maxMenuItems {
+			NewAPIError(&APIError{false, "Menus can have at most 100 items", http.StatusBadRequest}, w)
+			return false
+		}
+		if item == nil {
+			NewAPIError(&APIError{false, "Menu item is invalid", http.StatusBadRequest}, w)
+			return false
+		}
+
+		item.Label = strings.TrimSpace(item.Label)
+		item.Target = strings.TrimSpace(item.Target)
+		if item.Target == "" {
+			NewAPIError(&APIError{false, "Menu item target is required", http.StatusBadRequest}, w)
+			return false
+		}
+
+		switch item.Type {
+		case models.MenuItemPost:
+			// FindBySlug would count a view
+			post, err := mc.PostRepository.FindBySlugAdmin(ctx, item.Target)
+			if err != nil || post.Hidden {
+				NewAPIError(&APIError{false, "Could not find post " + item.Target, http.StatusBadRequest}, w)
+				return false
+			}
+			if item.Label == "" {
+				item.Label = post.Title
+			}
+		case models.MenuItemPage:
+			page, err := mc.PageRepository.FindBySlug(ctx, item.Target, false)
+			if err != nil {
+				NewAPIError(&APIError{false, "Could not find page " + item.Target, http.StatusBadRequest}, w)
+				return false
+			}
+			if item.Label == "" {
+				item.Label = page.Title
+			}
+		case models.MenuItemTag:
+			tag, err := mc.TagRepository.FindByName(ctx, item.Target, false)
+			if err != nil {
+				NewAPIError(&APIError{false, "Could not find tag " + item.Target, http.StatusBadRequest}, w)
+				return false
+			}
+			if item.Label == "" {
+				item.Label = tag.DisplayName
+			}
+		case models.MenuItemURL:
+			if !util.IsHTTPURL(item.Target) && !(strings.HasPrefix(item.Target, "/") && !strings.HasPrefix(item.Target, "//")) {
+				NewAPIError(&APIError{false, "Menu item URL must be a path starting with / or a http(s) URL", http.StatusBadRequest}, w)
+				return false
+			}
+			if item.Label == "" {
+				NewAPIError(&APIError{false, "Menu item label is required for URLs", http.StatusBadRequest}, w)
+				return false
+			}
+		default:
+			NewAPIError(&APIError{false, "Menu item type must be post, page, tag or url", http.StatusBadRequest}, w)
+			return false
+		}
+
+		if utf8.RuneCountInString(item.Label) > maxMenuLabelRunes {
+			NewAPIError(&APIError{false, "Menu item label is too long", http.StatusBadRequest}, w)
+			return false
+		}
+
+		if len(item.Children) > 0 && !mc.checkItems(ctx, item.Children, depth+1, count, w) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns copies of the items whose targets are still public, with the current slugs of posts whose
+// slug changed. Items of hidden or deleted content are left out with their children.
+func (mc *MenuController) publicItems(ctx context.Context, items []*models.MenuItem) []*models.MenuItem {
+	public := make([]*models.MenuItem, 0, len(items))
+	for _, item := range items {
+		target := item.Target
+		switch item.Type {
+		case models.MenuItemPost:
+			post, err := mc.PostRepository.FindBySlugAdmin(ctx, target)
+			if err != nil || post.Hidden {
+				if target, err = mc.PostRepository.FindRedirect(ctx, target); err != nil {
+					continue
+				}
+			}
+		case models.MenuItemPage:
+			if _, err := mc.PageRepository.FindBySlug(ctx, target, false); err != nil {
+				continue
+			}
+		case models.MenuItemTag:
+			if _, err := mc.TagRepository.FindByName(ctx, target, false); err != nil {
+				continue
+			}
+		}
+
+		publicItem := *item
+		publicItem.Target = target
+		if len(item.Children) > 0 {
+			publicItem.Children = mc.publicItems(ctx, item.Children)
+		}
+		public = append(public, &publicItem)
+	}
+	return public
+}
+
+// Flushes the cached public menus
+func (mc *MenuController) flushCache(ctx context.Context) {
+	flushMenusCache(ctx, mc.App)
+}
+
+// Flushes the cached public menus, whose items depend on the slugs and visibility of posts and
+// pages and on the tag names
+func flushMenusCache(ctx context.Context, a *app.App) {
+	err := a.Redis.Del(util.MenusCacheKey)
+	if err.Err() != nil {
+		slog.WarnContext(ctx, "Failed to flush menus cache", "err", err.Err())
+		return
+	}
+	slog.DebugContext(ctx, "Flushed menus cache")
+	return
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/gorilla/mux"
+)
+
+// PageController stores the App config and repositories
+type PageController struct {
+	*app.App
+	repositories.PageRepository
+	repositories.UserRepository
+}
+
+// NewPageController creates a new page controller
+func NewPageController(a *app.App, pr repositories.PageRepository, ur repositories.UserRepository) *PageController {
+	return &PageController{a, pr, ur}
+}
+
+// Slugs that would be shadowed by the other page routes
+var reservedPageSlugs = map[string]bool{
+	"admin":  true,
+	"search": true,
+}
+
+// GetAll returns the tree of public pages (without bodies)
+func (pc *PageController) GetAll(w http.ResponseWriter, r *http.Request) {
+	pages, err := pc.PageRepository.GetAll(r.Context(), false)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch pages", http.StatusBadRequest}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: buildPageTree(pages)}, w, http.StatusOK)
+}
+
+// GetAllAdmin returns the tree of all pages (without bodies) including hidden pages
+func (pc *PageController) GetAllAdmin(w http.ResponseWriter, r *http.Request) {
+	pages, err := pc.PageRepository.GetAll(r.Context(), true)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch pages", http.StatusBadRequest}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: buildPageTree(pages)}, w, http.StatusOK)
+}
+
+// GetBySlug returns the public page with the given slug
+func (pc *PageController) GetBySlug(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	slug := vars["slug"]
+
+	page, err := pc.PageRepository.FindBySlug(r.Context(), slug, false)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find page", http.StatusNotFound}, w)
+		return
+	}
+
+	author, err := pc.UserRepository.FindByID(r.Context(), page.AuthorID)
+	if err != nil {
+		page.AuthorID = "Unknown"
+	} else {
+		page.AuthorID = author.Name
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: page}, w, http.StatusOK)
+}
+
+// GetByIDAdmin returns the page with the given ID including hidden pages
+func (pc *PageController) GetByIDAdmin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	page, err := pc.PageRepository.FindByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find page", http.StatusNotFound}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: page}, w, http.StatusOK)
+}
+
+// Search for public pages using title. Will not return nil data if search is successful.
+func (pc *PageController) Search(w http.ResponseWriter, r *http.Request) {
+	title := r.URL.Query().Get("title")
+	if title == "" {
+		NewAPIError(&APIError{false, "Title to search for is required", http.StatusBadRequest}, w)
+		return
+	}
+
+	results, err := pc.PageRepository.SearchQuery(r.Context(), title)
+	if err != nil {
+		NewAPIError(&APIError{false, "Failed to search", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if results == nil {
+		results = []*models.Page{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Successful search", Data: results}, w, http.StatusOK)
+}
+
+// Create creates a new page and returns its details
+func (pc *PageController) Create(w http.ResponseWriter, r *http.Request) {
+	uid, err := services.UserIDFromContext(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
+		return
+	}
+
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	page := &models.Page{
+		AuthorID:  uid,
+		CreatedAt: time.Now(),
+	}
+	if !pc.readPage(r.Context(), j, page, w) {
+		return
+	}
+
+	err = pc.PageRepository.Create(r.Context(), page)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not create page", http.StatusBadRequest}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Page created", Data: page}, w, http.StatusOK)
+}
+
+// Update updates the page with the given id and returns its new details
+func (pc *PageController) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	page, err := pc.PageRepository.FindByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find page", http.StatusNotFound}, w)
+		return
+	}
+
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	oldSlug, wasHidden := page.Slug, page.Hidden
+	if !pc.readPage(r.Context(), j, page, w) {
+		return
+	}
+
+	now := time.Now()
+	page.UpdatedAt = &now
+
+	err = pc.PageRepository.Update(r.Context(), page)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not update page", http.StatusBadRequest}, w)
+		return
+	}
+	if page.Slug != oldSlug || page.Hidden != wasHidden {
+		flushMenusCache(r.Context(), pc.App)
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Page updated", Data: page}, w, http.StatusOK)
+}
+
+// Delete deletes the page with the given id. Its child pages become top-level pages.
+func (pc *PageController) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	_, err = pc.PageRepository.FindByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find page to delete", http.StatusNotFound}, w)
+		return
+	}
+
+	err = pc.PageRepository.Delete(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not delete page", http.StatusInternalServerError}, w)
+		return
+	}
+	flushMenusCache(r.Context(), pc.App)
+
+	NewAPIResponse(&APIResponse{Success: true, Data: id}, w, http.StatusOK)
+}
+
+// Reads and validates the page keys of a create or update request into the page. The optional keys
+// keep their current value when not given. Returns false if an error response was sent.
+func (pc *PageController) readPage(ctx context.Context, j *JSONData, page *models.Page, w http.ResponseWriter) bool {
+	title, err := j.GetString("title")
+	if err != nil || title == "" {
+		NewAPIError(&APIError{false, "Title is required", http.StatusBadRequest}, w)
+		return false
+	}
+
+	// Pages keep their slug when the title changes unless a new slug is given
+	slug, err := j.GetString("slug")
+	if err != nil || slug == "" {
+		slug = page.Slug
+		if slug == "" {
+			slug = util.GenerateSlug(title)
+		}
+	}
+	if !checkPageSlug(slug) {
+		NewAPIError(&APIError{false, "Slug may only contain letters, numbers and dashes", http.StatusBadRequest}, w)
+		return false
+	}
+	if slug != page.Slug && pc.PageRepository.Exists(ctx, slug) {
+		NewAPIError(&APIError{false, "A page with this slug already exists", http.StatusBadRequest}, w)
+		return false
+	}
+
+	body, err := j.GetString("body")
+	if err != nil || len(body) < 1 {
+		NewAPIError(&APIError{false, "Content is required", http.StatusBadRequest}, w)
+		return false
+	}
+
+	rendered, err := util.RenderMarkdown(body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not render body", http.StatusBadRequest}, w)
+		return false
+	}
+
+	// A parent ID of 0 makes the page a top-level page
+	if parentID, err := j.GetInt("parentId"); err == nil {
+		if parentID == 0 {
+			page.ParentID = nil
+		} else if !pc.checkParent(ctx, page.ID, parentID, w) {
+			return false
+		} else {
+			page.ParentID = &parentID
+		}
+	}
+
+	if sortOrder, err := j.GetInt("sortOrder"); err == nil {
+		page.SortOrder = sortOrder
+	}
+
+	if template, err := j.GetString("template"); err == nil {
+		if !checkTemplate(template) {
+			NewAPIError(&APIError{false, "Template may only contain letters, numbers, dashes and underscores", http.StatusBadRequest}, w)
+			return false
+		}
+		page.Template = template
+	}
+
+	if hidden, err := j.GetBool("hidden"); err == nil {
+		page.Hidden = hidden
+	}
+
+	page.Title = title
+	page.Slug = slug
+	page.Body = body
+	page.BodyHTML = rendered.HTML
+	page.TOC = rendered.TOC
+
+	return true
+}
+
+// Returns true if the parent exists and isn't the page or one of its children, otherwise sends an
+// error response. pageID is 0 for a new page.
+func (pc *PageController) checkParent(ctx context.Context, pageID int, parentID int, w http.ResponseWriter) bool {
+	if _, err := pc.PageRepository.FindByID(ctx, parentID); err != nil {
+		NewAPIError(&APIError{false, "Could not find parent page", http.StatusBadRequest}, w)
+		return false
+	}
+
+	if pageID != 0 {
+		isDescendant, err := pc.PageRepository.IsDescendant(ctx, parentID, pageID)
+		if err != nil {
+			NewAPIError(&APIError{false, "Could not check parent page", http.StatusInternalServerError}, w)
+			return false
+		}
+		if isDescendant {
+			NewAPIError(&APIError{false, "A page cannot be a child of itself", http.StatusBadRequest}, w)
+			return false
+		}
+	}
+
+	return true
+}
+
+// Nests the pages under their parents. Pages whose parent isn't in the list are top-level pages.
+func buildPageTree(pages []*models.Page) []*models.Page {
+	byID := make(map[int]*models.Page, len(pages))
+	for _, page := range pages {
+		byID[page.ID] = page
+	}
+
+	tree := []*models.Page{}
+	for _, page := range pages {
+		if page.ParentID != nil {
+			if parent, ok := byID[*page.ParentID]; ok {
+				parent.Children = append(parent.Children, page)
+				continue
+			}
+		}
+		tree = append(tree, page)
+	}
+
+	return tree
+}
+
+// Returns true if the slug is a single path segment that isn't shadowed by another page route
+func checkPageSlug(slug string) bool {
+	if matched, _ := regexp.MatchString(`^[a-zA-Z0-9\-]+$`, slug); !matched {
+		return false
+	}
+	return !reservedPageSlugs[slug]
+}
+
+// Returns true if the template hint is empty or a simple name
+func checkTemplate(template string) bool {
+	matched, _ := regexp.MatchString(`^[a-zA-Z0-9_\-]{0,64}$`, template)
+	return matched
+}
@@ -1,14 +1,20 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/metrics"
 	"github.com/alanqchen/Bear-Post/backend/models"
 	"github.com/alanqchen/Bear-Post/backend/repositories"
 	"github.com/alanqchen/Bear-Post/backend/services"
@@ -24,45 +30,40 @@ type PostController struct {
 	*app.App
 	repositories.PostRepository
 	repositories.UserRepository
+	repositories.SeriesRepository
 }
 
+// Most contributors a post can list
+const maxPostAuthors = 10
+
+// How long an edit lock lasts unless the editor refreshes it
+const editLockTTL = 2 * time.Minute
+
 // NewPostController creates a new post controller
-func NewPostController(a *app.App, pr repositories.PostRepository, ur repositories.UserRepository) *PostController {
-	return &PostController{a, pr, ur}
+func NewPostController(a *app.App, pr repositories.PostRepository, ur repositories.UserRepository, sr repositories.SeriesRepository) *PostController {
+	return &PostController{a, pr, ur, sr}
 }
 
 // GetPage returns a keyset pagaination page based on the given post maxID in the page
 func (pc *PostController) GetPage(w http.ResponseWriter, r *http.Request) {
-	total, _ := pc.PostRepository.GetPublicPostCount()
+	total, _ := pc.PostRepository.GetPublicPostCount(r.Context())
 
 	q := r.URL.Query()
-	maxIDString := q.Get("maxID")
-	maxID, err := strconv.Atoi(maxIDString)
-
-	log.Println(maxID)
-	if err != nil {
-		NewAPIError(&APIError{false, "Max ID is required (or -1 if first page)", http.StatusBadRequest}, w)
+	after, cacheKey, ok := getPageCursor(r.Context(), pc.PostRepository, q, true, w)
+	if !ok {
 		return
 	}
+	slog.DebugContext(r.Context(), "Paginating posts", "after", cacheKey)
 
 	tagsSlice := q["tags"]
 	if len(tagsSlice) == 0 {
-		log.Println("No tags slice")
+		slog.DebugContext(r.Context(), "No tags slice")
 	}
 
-	perPageString := q.Get("num")
-	perPage := 5
-
-	if perPageString != "" {
-		perPage, err := strconv.Atoi(perPageString)
-		if err != nil {
-			NewAPIError(&APIError{false, "Invalid num type", http.StatusBadRequest}, w)
-			return
-		}
-		if perPage < 1 || perPage > 10 {
-			NewAPIError(&APIError{false, "Query string num is not within bounds [1, 10]", http.StatusBadRequest}, w)
-			return
-		}
+	settings := pc.App.Settings.Get()
+	perPage, ok := getPerPage(q.Get("num"), settings.PostsPerPage, &settings, w)
+	if !ok {
+		return
 	}
 
 	getAuthorIDString := q.Get("getAuthorID")
@@ -71,108 +72,91 @@ func (pc *PostController) GetPage(w http.ResponseWriter, r *http.Request) {
 		getAuthorID = true
 	}
 
-	if maxID == -1 {
-		maxID, _ = pc.PostRepository.GetLastID()
-		maxID++
-	}
-
-	if !getAuthorID && perPage == 5 {
+	if !getAuthorID && perPage == settings.PostsPerPage {
 		if len(tagsSlice) == 0 {
-			resStatus, resCache := pc.checkCache(maxIDString)
+			resStatus, resCache := pc.checkCache(r.Context(), cacheKey)
 			if resStatus {
 				var res APIResponse
 				err := json.Unmarshal(resCache, &res)
-				if err != nil {
-					log.Fatal(err)
+				if err == nil {
+					slog.DebugContext(r.Context(), "Returned result from cache")
+					NewAPIResponse(&res, w, http.StatusOK)
+					return
 				}
-				log.Println("[INFO] Returned result from cache")
-				NewAPIResponse(&res, w, http.StatusOK)
-				return
+				slog.WarnContext(r.Context(), "Failed to read cached response", "err", err)
 			}
 		} else if len(tagsSlice) == 1 {
-			resStatus, resCache := pc.checkCategoryCache(tagsSlice[0], maxIDString)
+			resStatus, resCache := pc.checkCategoryCache(r.Context(), tagsSlice[0], cacheKey)
 			if resStatus {
 				var res APIResponse
 				err := json.Unmarshal(resCache, &res)
-				if err != nil {
-					log.Fatal(err)
+				if err == nil {
+					slog.DebugContext(r.Context(), "Returned result from category cache")
+					NewAPIResponse(&res, w, http.StatusOK)
+					return
 				}
-				log.Println("[INFO] Returned result from category cache")
-				NewAPIResponse(&res, w, http.StatusOK)
-				return
+				slog.WarnContext(r.Context(), "Failed to read cached response", "err", err)
 			}
 		}
 	}
 
-	posts, minID, err := pc.PostRepository.Paginate(maxID, perPage, tagsSlice)
+	posts, minID, err := pc.PostRepository.Paginate(r.Context(), after, perPage, tagsSlice)
 
 	if err != nil && err != pgx.ErrNoRows {
-		log.Println(err)
+		slog.ErrorContext(r.Context(), "Could not fetch posts", "err", err)
 		NewAPIError(&APIError{false, "Could not fetch posts", http.StatusBadRequest}, w)
 		return
 	}
 
 	if len(posts) == 0 {
-		log.Println("Could not find more posts")
+		slog.DebugContext(r.Context(), "Could not find more posts")
 		posts = make([]*models.Post, 0)
 		NewAPIResponse(&APIResponse{Success: true, Message: "Could not find more posts", Data: posts}, w, http.StatusOK)
 		return
 	}
 
-	if !getAuthorID {
-		for _, post := range posts {
-			author, err := pc.UserRepository.FindByID(post.AuthorID)
-			if err != nil {
-				post.AuthorID = "Unknown"
-			} else {
-				post.AuthorID = author.Name
-			}
-		}
-	}
+	setAuthors(r.Context(), pc.UserRepository, pc.PostRepository, posts, !getAuthorID)
 
 	postPaginator := APIPagination{
 		total,
 		perPage,
 		minID,
+		posts[len(posts)-1].Cursor().String(),
 		tagsSlice,
 	}
 
 	// Only add to cache when the author ID was not requested
-	if !getAuthorID && perPage == 5 {
+	if !getAuthorID && perPage == settings.PostsPerPage {
 		if len(tagsSlice) == 0 {
 			//Add query result to Redis cache, only if no tags is searched for
 			jPosts, err := json.Marshal(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator})
 			if err != nil {
-				log.Println("[WARN] Failed to add posts to cache")
-				log.Println(err)
+				slog.WarnContext(r.Context(), "Failed to add posts to cache", "err", err)
 				// Still send result, but failed to add to cache
 				NewAPIResponse(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator}, w, http.StatusOK)
 				return
 			}
 			// Add query result to Redis cache, only if no tags were searched for
 			//pc.App.Redis.Set(maxIDString, []byte(jPosts), 0)
-			val := pc.App.Redis.HSet("page-hash", maxIDString, []byte(jPosts))
+			val := pc.App.Redis.HSet(util.PageCacheKey, cacheKey, []byte(jPosts))
 			if val.Err() != nil {
-				log.Println("[WARN] Failed to add posts to cache")
-				log.Println(err)
+				slog.WarnContext(r.Context(), "Failed to add posts to cache", "err", err)
 			}
-			log.Println("Query result added to cache")
+			slog.DebugContext(r.Context(), "Query result added to cache")
 		} else if len(tagsSlice) == 1 {
 			// Add query result to Redis cache, only if a single tag (category) is searched for
 			jPosts, err := json.Marshal(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator})
 			if err != nil {
-				log.Println("[WARN] Failed to add category posts to cache")
-				log.Println(err)
+				slog.WarnContext(r.Context(), "Failed to add category posts to cache", "err", err)
 				// Still send result, but failed to add to cache
 				NewAPIResponse(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator}, w, http.StatusOK)
 				return
 			}
-			val := pc.App.Redis.HSet(tagsSlice[0], maxIDString, []byte(jPosts))
+			val := pc.App.Redis.HSet(tagsSlice[0], cacheKey, []byte(jPosts))
 			if val.Err() != nil {
-				log.Println("[WARN] Failed to add category posts to cache")
-				log.Println(err)
+				slog.WarnContext(r.Context(), "Failed to add category posts to cache", "err", err)
 			} else {
-				log.Println("Category query result added to cache")
+				slog.DebugContext(r.Context(), "Category query result added to cache")
 			}
 		}
 	}
@@ -184,7 +168,7 @@ func (pc *PostController) GetPage(w http.ResponseWriter, r *http.Request) {
 // including hidden posts
 func (pc *PostController) GetPageAdmin(w http.ResponseWriter, r *http.Request) {
 	//httpScheme := "https://"
-	total, _ := pc.PostRepository.GetPublicPostCount()
+	total, _ := pc.PostRepository.GetPublicPostCount(r.Context())
 	//page := r.URL.Query().Get("page")
 	//log.Println("Page: ", page)
 	//pageInt, err := strconv.Atoi(page)
@@ -193,33 +177,22 @@ func (pc *PostController) GetPageAdmin(w http.ResponseWriter, r *http.Request) {
 	//}
 
 	q := r.URL.Query()
-	maxIDString := q.Get("maxID")
-	maxID, err := strconv.Atoi(maxIDString)
-
-	log.Println(maxID)
-	if err != nil {
-		NewAPIError(&APIError{false, "Max ID is required (or -1 if first page)", http.StatusBadRequest}, w)
+	after, cacheKey, ok := getPageCursor(r.Context(), pc.PostRepository, q, true, w)
+	if !ok {
 		return
 	}
+	slog.DebugContext(r.Context(), "Paginating posts", "after", cacheKey)
 
 	tagsSlice := q["tags"]
 	if len(tagsSlice) == 0 {
-		log.Println("No tags slice")
+		slog.DebugContext(r.Context(), "No tags slice")
 	}
 
-	perPageString := q.Get("num")
-	perPage := 10
-
-	if perPageString != "" {
-		perPage, err := strconv.Atoi(perPageString)
-		if err != nil {
-			NewAPIError(&APIError{false, "Invalid num type", http.StatusBadRequest}, w)
-			return
-		}
-		if perPage < 1 || perPage > 10 {
-			NewAPIError(&APIError{false, "Query string num is not within bounds [1, 10]", http.StatusBadRequest}, w)
-			return
-		}
+	// Admins get the largest pages by default
+	settings := pc.App.Settings.Get()
+	perPage, ok := getPerPage(q.Get("num"), settings.MaxPostsPerPage, &settings, w)
+	if !ok {
+		return
 	}
 
 	getAuthorIDString := q.Get("getAuthorID")
@@ -228,77 +201,62 @@ func (pc *PostController) GetPageAdmin(w http.ResponseWriter, r *http.Request) {
 		getAuthorID = true
 	}
 
-	if maxID == -1 {
-		maxID, _ = pc.PostRepository.GetLastIDAdmin()
-		maxID++
-	}
-
-	if !getAuthorID && len(tagsSlice) == 0 && perPage == 10 {
-		resStatus, resCache := pc.checkAdminCache(maxIDString)
+	if !getAuthorID && len(tagsSlice) == 0 && perPage == settings.MaxPostsPerPage {
+		resStatus, resCache := pc.checkAdminCache(r.Context(), cacheKey)
 		if resStatus {
 			var res APIResponse
 			err := json.Unmarshal(resCache, &res)
-			if err != nil {
-				log.Fatal(err)
+			if err == nil {
+				slog.DebugContext(r.Context(), "Returned result from cache")
+				NewAPIResponse(&res, w, http.StatusOK)
+				return
 			}
-			log.Println("[INFO] Returned result from cache")
-			NewAPIResponse(&res, w, http.StatusOK)
-			return
+			slog.WarnContext(r.Context(), "Failed to read cached response", "err", err)
 		}
 	}
 
-	posts, minID, err := pc.PostRepository.PaginateAdmin(maxID, perPage, tagsSlice)
+	posts, minID, err := pc.PostRepository.PaginateAdmin(r.Context(), after, perPage, tagsSlice)
 
 	if err != nil && err != pgx.ErrNoRows {
-		log.Println(err)
+		slog.ErrorContext(r.Context(), "Could not fetch posts", "err", err)
 		NewAPIError(&APIError{false, "Could not fetch posts", http.StatusBadRequest}, w)
 		return
 	}
 
 	if len(posts) == 0 {
-		log.Println("Could not find more posts")
+		slog.DebugContext(r.Context(), "Could not find more posts")
 		posts = make([]*models.Post, 0)
 		NewAPIResponse(&APIResponse{Success: true, Message: "Could not find more posts", Data: posts}, w, http.StatusOK)
 		return
 	}
 
-	if !getAuthorID {
-		for _, post := range posts {
-			author, err := pc.UserRepository.FindByID(post.AuthorID)
-			if err != nil {
-				post.AuthorID = "Unknown"
-			} else {
-				post.AuthorID = author.Name
-			}
-		}
-	}
+	setAuthors(r.Context(), pc.UserRepository, pc.PostRepository, posts, !getAuthorID)
 
 	postPaginator := APIPagination{
 		total,
 		perPage,
 		minID,
+		posts[len(posts)-1].Cursor().String(),
 		tagsSlice,
 	}
 
 	// Only add to cache when the author ID was not requested
-	if !getAuthorID && len(tagsSlice) == 0 && perPage == 10 {
+	if !getAuthorID && len(tagsSlice) == 0 && perPage == settings.MaxPostsPerPage {
 		//Add query result to Redis cache, only if no tags is searched for
 		jPosts, err := json.Marshal(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator})
 		if err != nil {
-			log.Println("[WARN] Failed to add posts to admin cache")
-			log.Println(err)
+			slog.WarnContext(r.Context(), "Failed to add posts to admin cache", "err", err)
 			// Still send result, but failed to add to cache
 			NewAPIResponse(&APIResponse{Success: true, Data: posts, Pagination: &postPaginator}, w, http.StatusOK)
 			return
 		}
 		// Add query result to Redis cache, only if no tags were searched for
 		//pc.App.Redis.Set(maxIDString, []byte(jPosts), 0)
-		val := pc.App.Redis.HSet("admin-page-hash", maxIDString, []byte(jPosts))
+		val := pc.App.Redis.HSet(util.AdminPageCacheKey, cacheKey, []byte(jPosts))
 		if val.Err() != nil {
-			log.Println("[WARN] Failed to add posts to admin cache")
-			log.Println(err)
+			slog.WarnContext(r.Context(), "Failed to add posts to admin cache", "err", err)
 		} else {
-			log.Println("Query result added to admin cache")
+			slog.DebugContext(r.Context(), "Query result added to admin cache")
 		}
 	}
 
@@ -314,39 +272,44 @@ func (pc *PostController) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resStatus, resCache := pc.checkIDCache(id)
+	resStatus, resCache := pc.checkIDCache(r.Context(), id)
 	if resStatus {
 		var res APIResponse
 		err := json.Unmarshal(resCache, &res)
-		if err != nil {
-			log.Fatal(err)
+		if err == nil {
+			slog.DebugContext(r.Context(), "Returned result from cache")
+			NewAPIResponse(&res, w, http.StatusOK)
+			return
 		}
-		log.Println("[INFO] Returned result from cache")
-		NewAPIResponse(&res, w, http.StatusOK)
-		return
+		slog.WarnContext(r.Context(), "Failed to read cached response", "err", err)
 	}
 
-	post, err := pc.PostRepository.FindByID(id)
+	post, err := pc.PostRepository.FindByID(r.Context(), id)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
 		return
 	}
+
+	post.Series, err = pc.SeriesRepository.FindNavByPostID(r.Context(), post.ID)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to get series of post", "post", post.ID)
+	}
+	setAuthor(r.Context(), pc.UserRepository, pc.PostRepository, post, false)
+
 	//Add query result to Redis cache
 	jPosts, err := json.Marshal(&APIResponse{Success: true, Data: post})
 	if err != nil {
-		log.Println("[WARN] Failed to add posts to cache")
-		log.Println(err)
+		slog.WarnContext(r.Context(), "Failed to add posts to cache", "err", err)
 		// Still send result, but failed to add to cache
 		NewAPIResponse(&APIResponse{Success: true, Data: post}, w, http.StatusOK)
 		return
 	}
 
-	val := pc.App.Redis.HSet("ID-hash", string(id), []byte(jPosts))
+	val := pc.App.Redis.HSet(util.IDCacheKey, strconv.Itoa(id), []byte(jPosts))
 	if val.Err() != nil {
-		log.Println("[WARN] Failed to add posts to cache")
-		log.Println(err)
+		slog.WarnContext(r.Context(), "Failed to add posts to cache", "err", err)
 	} else {
-		log.Println("Query result added to ID cache")
+		slog.DebugContext(r.Context(), "Query result added to ID cache")
 	}
 
 	NewAPIResponse(&APIResponse{Success: true, Data: post}, w, http.StatusOK)
@@ -360,12 +323,14 @@ func (pc *PostController) GetByIDAdmin(w http.ResponseWriter, r *http.Request) {
 		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
 		return
 	}
-	post, err := pc.PostRepository.FindByIDAdmin(id)
+	post, err := pc.PostRepository.FindByIDAdmin(r.Context(), id)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
 		return
 	}
+	setAuthor(r.Context(), pc.UserRepository, pc.PostRepository, post, false)
 
+	setPostETag(w, post.Version)
 	NewAPIResponse(&APIResponse{Success: true, Data: post}, w, http.StatusOK)
 }
 
@@ -384,50 +349,53 @@ func (pc *PostController) GetBySlug(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !getAuthorID {
-		resStatus, resCache := pc.checkSlugCache(slug)
+		resStatus, resCache := pc.checkSlugCache(r.Context(), slug)
 		if resStatus {
 			var res APIResponse
 			err := json.Unmarshal(resCache, &res)
-			if err != nil {
-				log.Fatal(err)
+			if err == nil {
+				slog.DebugContext(r.Context(), "Returned result from cache")
+				NewAPIResponse(&res, w, http.StatusOK)
+				return
 			}
-			log.Println("[INFO] Returned result from cache")
-			NewAPIResponse(&res, w, http.StatusOK)
-			return
+			slog.WarnContext(r.Context(), "Failed to read cached response", "err", err)
 		}
 	}
 
-	post, err := pc.PostRepository.FindBySlug(slug)
+	post, err := pc.PostRepository.FindBySlug(r.Context(), slug)
 	if err != nil {
-		NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
+		// The slug may have belonged to a post before its title changed
+		current, redirectErr := pc.PostRepository.FindRedirect(r.Context(), slug)
+		if redirectErr != nil {
+			NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
+			return
+		}
+		redirect(w, r, current)
 		return
 	}
 
-	if !getAuthorID {
-		// Get author name
-		author, err := pc.UserRepository.FindByID(post.AuthorID)
-		if err != nil {
-			post.AuthorID = "Unknown"
-		} else {
-			post.AuthorID = author.Name
-		}
+	post.Series, err = pc.SeriesRepository.FindNavByPostID(r.Context(), post.ID)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to get series of post", "post", post.ID)
+	}
 
+	setAuthor(r.Context(), pc.UserRepository, pc.PostRepository, post, !getAuthorID)
+
+	if !getAuthorID {
 		// Cache result if author ID is not returned
 		jPost, err := json.Marshal(&APIResponse{Success: true, Data: post})
 		if err != nil {
-			log.Println("[WARN] Failed to add post to cache")
-			log.Println(err)
+			slog.WarnContext(r.Context(), "Failed to add post to cache", "err", err)
 			// Still send result, but failed to add to cache
 			NewAPIResponse(&APIResponse{Success: true, Data: post}, w, http.StatusOK)
 			return
 		}
 		// Add query result to Redis cache
-		val := pc.App.Redis.Set(slug, []byte(jPost), 0)
+		val := pc.App.Redis.Set(util.SlugCacheKey(slug), []byte(jPost), 0)
 		if val.Err() != nil {
-			log.Println("[WARN] Failed to add posts to cache")
-			log.Println(err)
+			slog.WarnContext(r.Context(), "Failed to add posts to cache", "err", err)
 		} else {
-			log.Println("Query result added to cache")
+			slog.DebugContext(r.Context(), "Query result added to cache")
 		}
 	}
 
@@ -447,53 +415,52 @@ func (pc *PostController) GetBySlugAdmin(w http.ResponseWriter, r *http.Request)
 	}
 
 	if !getAuthorID {
-		resStatus, resCache := pc.checkAdminSlugCache(slug)
+		resStatus, resCache := pc.checkAdminSlugCache(r.Context(), slug)
 		if resStatus {
 			var res APIResponse
 			err := json.Unmarshal(resCache, &res)
-			if err != nil {
-				log.Fatal(err)
+			if err == nil {
+				slog.DebugContext(r.Context(), "Returned result from admin cache")
+				if data, ok := res.Data.(map[string]interface{}); ok {
+					if version, ok := data["version"].(float64); ok {
+						setPostETag(w, int(version))
+					}
+				}
+				NewAPIResponse(&res, w, http.StatusOK)
+				return
 			}
-			log.Println("[INFO] Returned result from admin cache")
-			NewAPIResponse(&res, w, http.StatusOK)
-			return
+			slog.WarnContext(r.Context(), "Failed to read cached response", "err", err)
 		}
 	}
 
-	post, err := pc.PostRepository.FindBySlugAdmin(slug)
+	post, err := pc.PostRepository.FindBySlugAdmin(r.Context(), slug)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
 		return
 	}
 
-	if !getAuthorID {
-		// Get author name
-		author, err := pc.UserRepository.FindByID(post.AuthorID)
-		if err != nil {
-			post.AuthorID = "Unknown"
-		} else {
-			post.AuthorID = author.Name
-		}
+	setAuthor(r.Context(), pc.UserRepository, pc.PostRepository, post, !getAuthorID)
 
+	if !getAuthorID {
 		// Cache result if author ID is not returned
 		jPost, err := json.Marshal(&APIResponse{Success: true, Data: post})
 		if err != nil {
-			log.Println("[WARN] Failed to add posts to admin cache")
-			log.Println(err)
+			slog.WarnContext(r.Context(), "Failed to add posts to admin cache", "err", err)
 			// Still send result, but failed to add to cache
+			setPostETag(w, post.Version)
 			NewAPIResponse(&APIResponse{Success: true, Data: post}, w, http.StatusOK)
 			return
 		}
 
-		val := pc.App.Redis.HSet("admin-slug-hash", slug, []byte(jPost))
+		val := pc.App.Redis.HSet(util.AdminSlugCacheKey, slug, []byte(jPost))
 		if val.Err() != nil {
-			log.Println("[WARN] Failed to add posts to admin cache")
-			log.Println(err)
+			slog.WarnContext(r.Context(), "Failed to add posts to admin cache", "err", err)
 		} else {
-			log.Println("Query result added to admin cache")
+			slog.DebugContext(r.Context(), "Query result added to admin cache")
 		}
 	}
 
+	setPostETag(w, post.Version)
 	NewAPIResponse(&APIResponse{Success: true, Data: post}, w, http.StatusOK)
 }
 
@@ -511,51 +478,70 @@ func (pc *PostController) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	post, apiErr := pc.createPost(r.Context(), uid, j)
+	if apiErr != nil {
+		NewAPIError(apiErr, w)
+		return
+	}
+
+	defer r.Body.Close()
+	NewAPIResponse(&APIResponse{Success: true, Message: "Post created", Data: post}, w, http.StatusOK)
+}
+
+// Creates a post as the user from the JSON body of Create. Returns an error if the body is invalid.
+func (pc *PostController) createPost(ctx context.Context, uid string, j *JSONData) (*models.Post, *APIError) {
 	title, err := j.GetString("title")
 	if err != nil {
-		NewAPIError(&APIError{false, "Title is required", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Title is required", http.StatusBadRequest}
 	}
 
 	//title = util.CleanZalgoText(title)
 
 	if len(title) < 4 {
-		NewAPIError(&APIError{false, "Title is too short", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Title is too short", http.StatusBadRequest}
 	}
 
 	subtitle, err := j.GetString("subtitle")
 	if err != nil {
-		NewAPIError(&APIError{false, "Subtitle is required", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Subtitle is required", http.StatusBadRequest}
 	}
 
-	slug := strconv.Itoa(time.Now().Year()) + "/"
-	monthNum := int(time.Now().Month())
-	if monthNum < 10 {
-		slug = slug + "0" + strconv.Itoa(monthNum)
-	} else {
-		slug = slug + strconv.Itoa(monthNum)
+	slug := slugDatePrefix(time.Now()) + util.GenerateSlug(title)
+
+	// Admins can pin a custom slug so it isn't regenerated from the title
+	customSlug, slugPinned, apiErr := pc.getCustomSlug(ctx, j, uid, -1, false)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if customSlug != "" {
+		slug = customSlug
 	}
-	slug = slug + "/" + util.GenerateSlug(title)
 
 	if len(title) == 0 {
-		NewAPIError(&APIError{false, "Title is invalid", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Title is invalid", http.StatusBadRequest}
 	}
 
 	body, err := j.GetString("body")
 	if err != nil {
-		NewAPIError(&APIError{false, "Content is required", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Content is required", http.StatusBadRequest}
 	}
 
 	//body = util.CleanZalgoText(body)
 
 	if len(body) < 1 {
-		NewAPIError(&APIError{false, "Body is too short", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Body is too short", http.StatusBadRequest}
+	}
+
+	rendered, err := util.RenderMarkdown(body)
+	if err != nil {
+		return nil, &APIError{false, "Could not render body", http.StatusBadRequest}
+	}
+
+	excerpt, customExcerpt, apiErr := getExcerpt(j, rendered, "", false)
+	if apiErr != nil {
+		return nil, apiErr
 	}
+
 	hidden, err := j.GetBool("hidden")
 	if err != nil {
 		hidden = false
@@ -563,19 +549,30 @@ func (pc *PostController) Create(w http.ResponseWriter, r *http.Request) {
 
 	tags, err := j.GetStringArray("tags")
 	if err != nil {
-		NewAPIError(&APIError{false, "Missing tags key", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Missing tags key", http.StatusBadRequest}
 	}
 
 	tags = rmDuplicateTags(tags)
 
 	if !checkTags(tags) {
-		NewAPIError(&APIError{false, "Contains bad tag", http.StatusBadRequest}, w)
+		return nil, &APIError{false, "Contains bad tag", http.StatusBadRequest}
 	}
 
 	imgURL, err := j.GetString("featureImgUrl")
 	if err != nil || imgURL == "" {
-		imgURL = "/assets/images/feature-default.png"
+		imgURL = pc.App.Settings.Get().DefaultFeatureImageURL
+	}
+
+	authors, authorsGiven, apiErr := pc.getAuthors(ctx, j)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if !authorsGiven {
+		creator, err := pc.UserRepository.FindByID(ctx, uid)
+		if err != nil {
+			return nil, &APIError{false, "Could not find user", http.StatusInternalServerError}
+		}
+		authors = []*models.PostAuthor{{AuthorSummary: *creator.Summary(), Role: models.RoleAuthor}}
 	}
 
 	views := 0
@@ -584,43 +581,53 @@ func (pc *PostController) Create(w http.ResponseWriter, r *http.Request) {
 		Title:         title,
 		Slug:          slug,
 		Body:          body,
+		BodyHTML:      rendered.HTML,
+		TOC:           rendered.TOC,
+		Excerpt:       excerpt,
+		CustomExcerpt: customExcerpt,
+		WordCount:     rendered.WordCount,
+		ReadingTime:   rendered.ReadingTime,
 		CreatedAt:     time.Now(),
 		Tags:          tags,
 		Hidden:        hidden,
-		AuthorID:      uid,
+		AuthorID:      primaryAuthorID(authors),
 		FeatureImgURL: imgURL,
 		Subtitle:      subtitle,
 		Views:         views,
+		SlugPinned:    slugPinned,
 	}
 
-	err = pc.PostRepository.Create(post)
+	err = pc.PostRepository.Create(ctx, post)
 	if err != nil {
-		NewAPIError(&APIError{false, "Could not create post", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Could not create post", http.StatusBadRequest}
 	}
 
+	post.Version, err = pc.PostRepository.SetAuthors(ctx, post.ID, authors)
+	if err != nil {
+		return nil, &APIError{false, "Could not add contributors to post", http.StatusBadRequest}
+	}
+	setAuthor(ctx, pc.UserRepository, pc.PostRepository, post, false)
+
 	// TODO: Change this maybe put the user object into a context and get the author from there.
 	//u, err := pc.UserRepository.FindById(uid)
 	/*
 		if err != nil {
-			NewAPIError(&APIError{false, "Content is required", http.StatusBadRequest}, w)
-			return
+			return nil, &APIError{false, "Content is required", http.StatusBadRequest}
 		}
 	*/
-	pc.flushCache()
-	pc.flushTagsCache(tags)
-	pc.flushSlugCache(slug)
-	pc.flushIDCache()
-	pc.flushAdminCache()
-	pc.flushAdminSlugCache(slug)
-
-	defer r.Body.Close()
-	NewAPIResponse(&APIResponse{Success: true, Message: "Post created", Data: post}, w, http.StatusOK)
+	pc.flushCache(ctx)
+	pc.flushTagsCache(ctx, tags)
+	pc.flushSlugCache(ctx, slug)
+	pc.flushIDCache(ctx)
+	pc.flushAdminCache(ctx)
+	pc.flushAdminSlugCache(ctx, slug)
+
+	return post, nil
 }
 
 // Update updates the post with the given id and returns its new details
 func (pc *PostController) Update(w http.ResponseWriter, r *http.Request) {
-	_, err := services.UserIDFromContext(r.Context())
+	uid, err := services.UserIDFromContext(r.Context())
 	if err != nil {
 		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
 		return
@@ -631,78 +638,133 @@ func (pc *PostController) Update(w http.ResponseWriter, r *http.Request) {
 		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
 		return
 	}
-	post, err := pc.PostRepository.FindByIDAdmin(postID)
+	post, err := pc.PostRepository.FindByIDAdmin(r.Context(), postID)
 	if err != nil {
 		// post was not found
 		NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
 		return
 	}
 
+	if !pc.canEdit(r.Context(), uid, post) {
+		NewAPIError(&APIError{false, "Only the post's authors or an admin can edit it", http.StatusForbidden}, w)
+		return
+	}
+
 	j, err := GetJSON(r.Body)
 	if err != nil {
 		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
 		return
 	}
 
+	version, ok := getVersion(r, j, w)
+	if !ok {
+		return
+	}
+	if version != post.Version {
+		pc.sendConflict(r.Context(), post, w)
+		return
+	}
+
+	post, apiErr := pc.updatePost(r.Context(), uid, post, j)
+	if apiErr != nil && apiErr.Status == http.StatusConflict {
+		pc.sendConflict(r.Context(), post, w)
+		return
+	}
+	if apiErr != nil {
+		NewAPIError(apiErr, w)
+		return
+	}
+
+	setPostETag(w, post.Version)
+	NewAPIResponse(&APIResponse{Success: true, Message: "Post updated", Data: post}, w, http.StatusOK)
+}
+
+// Updates the post, which the user can edit, from the JSON body of Update. Returns an error if the
+// body is invalid, with the current copy of the post if it was saved by someone else meanwhile.
+func (pc *PostController) updatePost(ctx context.Context, uid string, post *models.Post, j *JSONData) (*models.Post, *APIError) {
 	title, err := j.GetString("title")
 	if err != nil {
-		NewAPIError(&APIError{false, "Title is required", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Title is required", http.StatusBadRequest}
 	}
 
 	//title = util.CleanZalgoText(title)
 
 	if len(title) < 10 {
-		NewAPIError(&APIError{false, "Title is too short", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Title is too short", http.StatusBadRequest}
+	}
+
+	oldSlug := post.Slug
+	wasHidden := post.Hidden
+	customSlug, slugPinned, apiErr := pc.getCustomSlug(ctx, j, uid, post.ID, post.SlugPinned)
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
-	slug := post.Slug[:8] + util.GenerateSlug(title)
+	// A pinned slug is kept when the title changes
+	slug := post.Slug
+	if customSlug != "" {
+		slug = customSlug
+	} else if !slugPinned {
+		prefix := slugDatePrefix(post.CreatedAt)
+		if matched, _ := regexp.MatchString(`^\d{4}/\d{2}/`, post.Slug); matched {
+			prefix = post.Slug[:8]
+		}
+		slug = prefix + util.GenerateSlug(title)
+	}
 	if len(slug) == 0 {
-		NewAPIError(&APIError{false, "Title is invalid", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Title is invalid", http.StatusBadRequest}
 	}
 
 	subtitle, err := j.GetString("subtitle")
 	if err != nil {
-		NewAPIError(&APIError{false, "Subtitle is required", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Subtitle is required", http.StatusBadRequest}
 	}
 
 	body, err := j.GetString("body")
 	if err != nil {
-		NewAPIError(&APIError{false, "Content is required", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Content is required", http.StatusBadRequest}
 	}
 
 	//body = util.CleanZalgoText(body)
 
 	if len(body) < 1 {
-		NewAPIError(&APIError{false, "Body is too short", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Body is too short", http.StatusBadRequest}
+	}
+
+	rendered, err := util.RenderMarkdown(body)
+	if err != nil {
+		return nil, &APIError{false, "Could not render body", http.StatusBadRequest}
+	}
+
+	excerpt, customExcerpt, apiErr := getExcerpt(j, rendered, post.Excerpt, post.CustomExcerpt)
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
 	hidden, err := j.GetBool("hidden")
 	if err != nil {
-		NewAPIError(&APIError{false, "Missing hidden key", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Missing hidden key", http.StatusBadRequest}
 	}
 
 	tags, err := j.GetStringArray("tags")
 	if err != nil {
-		NewAPIError(&APIError{false, "Missing tags key", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Missing tags key", http.StatusBadRequest}
 	}
 
 	tags = rmDuplicateTags(tags)
 
 	if !checkTags(tags) {
-		NewAPIError(&APIError{false, "Contains bad tag", http.StatusBadRequest}, w)
+		return nil, &APIError{false, "Contains bad tag", http.StatusBadRequest}
 	}
 
 	imgURL, err := j.GetString("featureImgUrl")
 	if err != nil || imgURL == "" {
-		imgURL = "/assets/images/feature-default.png"
+		imgURL = pc.App.Settings.Get().DefaultFeatureImageURL
+	}
+
+	authors, authorsGiven, apiErr := pc.getAuthors(ctx, j)
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
 	//post.UserID = uid
@@ -710,57 +772,396 @@ func (pc *PostController) Update(w http.ResponseWriter, r *http.Request) {
 	post.Title = title
 	post.Subtitle = subtitle
 	post.Body = body
+	post.BodyHTML = rendered.HTML
+	post.TOC = rendered.TOC
+	post.Excerpt = excerpt
+	post.CustomExcerpt = customExcerpt
+	post.WordCount = rendered.WordCount
+	post.ReadingTime = rendered.ReadingTime
 	post.Slug = slug
+	post.SlugPinned = slugPinned
 	post.Hidden = hidden
 	post.Tags = tags
 	post.FeatureImgURL = imgURL
 	//post.ID = postId
 
-	err = pc.PostRepository.Update(post)
+	err = pc.PostRepository.Update(ctx, post)
+	if err == repositories.ErrVersionConflict {
+		// Saved by someone else since the post was read
+		current, err := pc.PostRepository.FindByIDAdmin(ctx, post.ID)
+		if err != nil {
+			return nil, &APIError{false, "Could not find post", http.StatusNotFound}
+		}
+		return current, &APIError{false, "Post was changed by someone else", http.StatusConflict}
+	}
 	if err != nil {
-		NewAPIError(&APIError{false, "Could not update post", http.StatusBadRequest}, w)
-		return
+		return nil, &APIError{false, "Could not update post", http.StatusBadRequest}
 	}
-	pc.flushCache()
-	pc.flushTagsCache(tags)
-	pc.flushSlugCache(slug)
-	pc.flushIDCache()
-	pc.flushAdminCache()
-	pc.flushAdminSlugCache(slug)
 
-	NewAPIResponse(&APIResponse{Success: true, Message: "Post updated", Data: post}, w, http.StatusOK)
+	if authorsGiven {
+		post.Version, err = pc.PostRepository.SetAuthors(ctx, post.ID, authors)
+		if err != nil {
+			return nil, &APIError{false, "Could not update post contributors", http.StatusBadRequest}
+		}
+		post.AuthorID = primaryAuthorID(authors)
+	}
+	setAuthor(ctx, pc.UserRepository, pc.PostRepository, post, false)
+	pc.flushSeriesCache(ctx, post.ID)
+	pc.flushCache(ctx)
+	pc.flushTagsCache(ctx, tags)
+	pc.flushSlugCache(ctx, oldSlug)
+	pc.flushSlugCache(ctx, post.Slug)
+	pc.flushIDCache(ctx)
+	pc.flushAdminCache(ctx)
+	pc.flushAdminSlugCache(ctx, slug)
+	if post.Slug != oldSlug || post.Hidden != wasHidden {
+		flushMenusCache(ctx, pc.App)
+	}
+
+	return post, nil
 }
 
-// Delete deletes the post with the given id
+// Delete moves the post with the given id to the trash
 func (pc *PostController) Delete(w http.ResponseWriter, r *http.Request) {
+	uid, err := services.UserIDFromContext(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
+		return
+	}
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
 		return
 	}
-	post, err := pc.PostRepository.FindByIDAdmin(id)
+	post, err := pc.PostRepository.FindByIDAdmin(r.Context(), id)
 	if err != nil {
-		log.Println(err)
+		slog.ErrorContext(r.Context(), "Could not find post to delete", "err", err)
 		NewAPIError(&APIError{false, "Could not find post to delete", http.StatusNotFound}, w)
 		return
 	}
-	err = pc.PostRepository.Delete(id)
+	if !pc.canEdit(r.Context(), uid, post) {
+		NewAPIError(&APIError{false, "Only the post's authors or an admin can delete it", http.StatusForbidden}, w)
+		return
+	}
+	// Flush the other parts of the series before the membership is removed
+	pc.flushSeriesCache(r.Context(), id)
+	err = pc.PostRepository.Delete(r.Context(), id)
 	if err != nil {
-		log.Println(err)
+		slog.ErrorContext(r.Context(), "Could not find post to delete", "err", err)
 		NewAPIError(&APIError{false, "Could not find post to delete", http.StatusNotFound}, w)
 		return
 	}
-	pc.flushCache()
-	pc.flushTagsCache(post.Tags)
-	pc.flushSlugCache(post.Slug)
-	pc.flushIDCache()
-	pc.flushAdminCache()
-	pc.flushAdminSlugCache(post.Slug)
+	pc.flushCache(r.Context())
+	pc.flushTagsCache(r.Context(), post.Tags)
+	pc.flushSlugCache(r.Context(), post.Slug)
+	pc.flushIDCache(r.Context())
+	pc.flushAdminCache(r.Context())
+	pc.flushAdminSlugCache(r.Context(), post.Slug)
+	flushMenusCache(r.Context(), pc.App)
 
 	NewAPIResponse(&APIResponse{Success: true, Data: id}, w, http.StatusOK)
 }
 
+// Bulk applies an action to every post in the ids list and matching the filter, in one
+// transaction. With dryRun the matched posts are returned without changing them.
+func (pc *PostController) Bulk(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs    []int              `json:"ids"`
+		Filter *models.PostFilter `json:"filter"`
+		models.BulkAction
+		DryRun bool `json:"dryRun"`
+	}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&req); err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	filter := req.Filter
+	if filter == nil {
+		filter = &models.PostFilter{}
+	}
+	if req.IDs != nil {
+		filter.IDs = req.IDs
+	}
+	// An empty list of ids matches no posts, but a missing filter would match them all
+	if filter.IsEmpty() {
+		NewAPIError(&APIError{false, "Post ids or a filter are required", http.StatusBadRequest}, w)
+		return
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		NewAPIError(&APIError{false, "Filter from must be before to", http.StatusBadRequest}, w)
+		return
+	}
+
+	action := &req.BulkAction
+	switch action.Action {
+	case models.BulkHide, models.BulkPublish, models.BulkDelete:
+	case models.BulkAddTags, models.BulkRemoveTags:
+		action.Tags = rmDuplicateTags(action.Tags)
+		if len(action.Tags) == 0 {
+			NewAPIError(&APIError{false, "Tags are required", http.StatusBadRequest}, w)
+			return
+		}
+		if action.Action == models.BulkAddTags && !checkTags(action.Tags) {
+			NewAPIError(&APIError{false, "Contains bad tag", http.StatusBadRequest}, w)
+			return
+		}
+	case models.BulkSetAuthor:
+		author, err := pc.UserRepository.FindByID(r.Context(), action.AuthorID)
+		if err != nil {
+			NewAPIError(&APIError{false, "Could not find author", http.StatusBadRequest}, w)
+			return
+		}
+		action.AuthorID = author.ID.String()
+	default:
+		NewAPIError(&APIError{false, "Action must be hide, publish, addTags, removeTags, setAuthor or delete", http.StatusBadRequest}, w)
+		return
+	}
+
+	posts, err := pc.PostRepository.BulkUpdate(r.Context(), filter, action, req.DryRun)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not update posts", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	if !req.DryRun && len(posts) > 0 {
+		pc.flushBulkCache(r.Context(), posts, action.Tags)
+	}
+
+	result := &models.BulkResult{
+		Action: action.Action,
+		DryRun: req.DryRun,
+		Count:  len(posts),
+		Posts:  posts,
+	}
+	slog.InfoContext(r.Context(), "Bulk post action", "action", action.Action, "posts", len(posts), "dryRun", req.DryRun)
+	NewAPIResponse(&APIResponse{Success: true, Data: result}, w, http.StatusOK)
+}
+
+// GetTrash returns the posts in the trash (without bodies), most recently deleted first
+func (pc *PostController) GetTrash(w http.ResponseWriter, r *http.Request) {
+	posts, err := pc.PostRepository.GetTrash(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch trash", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	setAuthors(r.Context(), pc.UserRepository, pc.PostRepository, posts, true)
+
+	NewAPIResponse(&APIResponse{Success: true, Data: posts}, w, http.StatusOK)
+}
+
+// Restore takes the post with the given id out of the trash
+func (pc *PostController) Restore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+	post, err := pc.PostRepository.FindDeletedByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find post in trash", http.StatusNotFound}, w)
+		return
+	}
+	err = pc.PostRepository.Restore(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not restore post", http.StatusInternalServerError}, w)
+		return
+	}
+	pc.flushSeriesCache(r.Context(), id)
+	pc.flushCache(r.Context())
+	pc.flushTagsCache(r.Context(), post.Tags)
+	pc.flushSlugCache(r.Context(), post.Slug)
+	pc.flushIDCache(r.Context())
+	pc.flushAdminCache(r.Context())
+	pc.flushAdminSlugCache(r.Context(), post.Slug)
+	flushMenusCache(r.Context(), pc.App)
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Post restored", Data: id}, w, http.StatusOK)
+}
+
+// Purge permanently deletes the post with the given id from the trash. The confirm query parameter
+// must be true.
+func (pc *PostController) Purge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+	if r.URL.Query().Get("confirm") != "true" {
+		NewAPIError(&APIError{false, "Purging a post cannot be undone, set confirm=true to purge it", http.StatusBadRequest}, w)
+		return
+	}
+	_, err = pc.PostRepository.FindDeletedByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find post in trash", http.StatusNotFound}, w)
+		return
+	}
+	err = pc.PostRepository.Purge(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not purge post", http.StatusInternalServerError}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Post purged", Data: id}, w, http.StatusOK)
+}
+
+// GetLock returns who has the post with the given id open in the editor. Data is empty if nobody
+// has it open.
+func (pc *PostController) GetLock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	lock, err := pc.getEditLock(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not check edit lock", http.StatusInternalServerError}, w)
+		return
+	}
+	if lock == nil {
+		NewAPIResponse(&APIResponse{Success: true, Message: "Post is not being edited"}, w, http.StatusOK)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: lock}, w, http.StatusOK)
+}
+
+// Lock marks the post with the given id as open in the editor by the user. Editors should call it
+// again before the lock expires to keep it. Returns 409 with the lock if someone else has it open.
+func (pc *PostController) Lock(w http.ResponseWriter, r *http.Request) {
+	uid, err := services.UserIDFromContext(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+	post, err := pc.PostRepository.FindByIDAdmin(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find post", http.StatusNotFound}, w)
+		return
+	}
+	if !pc.canEdit(r.Context(), uid, post) {
+		NewAPIError(&APIError{false, "Only the post's authors or an admin can edit it", http.StatusForbidden}, w)
+		return
+	}
+	user, err := pc.UserRepository.FindByID(r.Context(), uid)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find user", http.StatusNotFound}, w)
+		return
+	}
+
+	current, err := pc.getEditLock(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not check edit lock", http.StatusInternalServerError}, w)
+		return
+	}
+	if current != nil && current.User.ID != uid {
+		NewAPIResponse(&APIResponse{Success: false, Message: "Post is being edited by " + current.User.Name, Data: current}, w, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	lock := &models.EditLock{
+		PostID:    id,
+		User:      user.Summary(),
+		LockedAt:  now,
+		ExpiresAt: now.Add(editLockTTL),
+	}
+	// Refreshing keeps the time the editor was opened
+	if current != nil {
+		lock.LockedAt = current.LockedAt
+	}
+	jLock, err := json.Marshal(lock)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not lock post", http.StatusInternalServerError}, w)
+		return
+	}
+
+	key := util.EditLockKeyPrefix + strconv.Itoa(id)
+	if current == nil {
+		// Someone else may have opened the post since it was checked
+		locked, err := pc.App.Redis.SetNX(key, jLock, editLockTTL).Result()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Could not lock post", "err", err)
+			NewAPIError(&APIError{false, "Could not lock post", http.StatusInternalServerError}, w)
+			return
+		}
+		if !locked {
+			NewAPIError(&APIError{false, "Post was just opened by someone else", http.StatusConflict}, w)
+			return
+		}
+	} else if err := pc.App.Redis.Set(key, jLock, editLockTTL).Err(); err != nil {
+		slog.ErrorContext(r.Context(), "Could not lock post", "err", err)
+		NewAPIError(&APIError{false, "Could not lock post", http.StatusInternalServerError}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: lock}, w, http.StatusOK)
+}
+
+// Unlock releases the user's edit lock on the post with the given id. Admins can release anyone's
+// lock.
+func (pc *PostController) Unlock(w http.ResponseWriter, r *http.Request) {
+	uid, err := services.UserIDFromContext(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	lock, err := pc.getEditLock(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not check edit lock", http.StatusInternalServerError}, w)
+		return
+	}
+	if lock == nil {
+		NewAPIResponse(&APIResponse{Success: true, Message: "Post is not being edited"}, w, http.StatusOK)
+		return
+	}
+	if lock.User.ID != uid {
+		user, err := pc.UserRepository.FindByID(r.Context(), uid)
+		if err != nil || !user.IsAdmin() {
+			NewAPIError(&APIError{false, "Only the editor or an admin can release the lock", http.StatusForbidden}, w)
+			return
+		}
+	}
+
+	err = pc.App.Redis.Del(util.EditLockKeyPrefix + strconv.Itoa(id)).Err()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Could not release lock", "err", err)
+		NewAPIError(&APIError{false, "Could not release lock", http.StatusInternalServerError}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Lock released", Data: id}, w, http.StatusOK)
+}
+
 // Search for posts using title and tags. Will not return nil data if search is successful.
 func (pc *PostController) Search(w http.ResponseWriter, r *http.Request) {
 
@@ -775,10 +1176,10 @@ func (pc *PostController) Search(w http.ResponseWriter, r *http.Request) {
 
 	tags := q["tags"]
 	if len(tags) == 0 {
-		log.Println("No tags slice")
+		slog.DebugContext(r.Context(), "No tags slice")
 	}
 
-	results, err := pc.PostRepository.SearchQuery(title, tags)
+	results, err := pc.PostRepository.SearchQuery(r.Context(), title, tags)
 
 	if err != nil {
 		NewAPIError(&APIError{false, "Failed to search", http.StatusBadRequest}, w)
@@ -797,157 +1198,541 @@ func (pc *PostController) Search(w http.ResponseWriter, r *http.Request) {
 
 // False -> pagination not in cache
 // True -> pagination in cache
-func (pc *PostController) checkCache(key string) (bool, []byte) {
+func (pc *PostController) checkCache(ctx context.Context, key string) (bool, []byte) {
 
-	val := pc.App.Redis.HGet("page-hash", key)
-	if val.Val() == "" {
-		log.Println("[INFO] Key " + key + " not found in cache")
+	val, err := pc.App.Redis.HGet(util.PageCacheKey, key).Result()
+	if err != nil && err != redis.Nil {
+		slog.WarnContext(ctx, "Failed to check page cache", "err", err)
+		return false, []byte("")
+	}
+	if err == redis.Nil || val == "" {
+		metrics.CacheLookup("page", false)
+		slog.DebugContext(ctx, "Cache miss", "key", key)
 		return false, []byte("")
-	} else if val.Err() != nil {
-		panic(val.Err())
 	}
-	log.Println("[INFO] Key " + key + " found in cache")
-	return true, []byte(val.Val())
+	metrics.CacheLookup("page", true)
+	slog.DebugContext(ctx, "Cache hit", "key", key)
+	return true, []byte(val)
 }
 
 // Returns if given slug is in slug cache
-func (pc *PostController) checkSlugCache(slug string) (bool, []byte) {
+func (pc *PostController) checkSlugCache(ctx context.Context, slug string) (bool, []byte) {
 
-	val, err := pc.App.Redis.Get(slug).Result()
+	val, err := pc.App.Redis.Get(util.SlugCacheKey(slug)).Result()
 	if err != nil && err != redis.Nil {
-		log.Println(err)
-		log.Println("[WARN] Failed to check slug cache")
+		slog.WarnContext(ctx, "Failed to check slug cache", "err", err)
 		return false, []byte("")
 	}
 	if err == redis.Nil || val == "" {
-		log.Println("[INFO] Slug " + slug + " not found in cache")
+		metrics.CacheLookup("slug", false)
+		slog.DebugContext(ctx, "Cache miss", "slug", slug)
 		return false, []byte("")
 	}
-	log.Println("[INFO] Slug " + slug + " found in cache")
+	metrics.CacheLookup("slug", true)
+	slog.DebugContext(ctx, "Cache hit", "slug", slug)
 	return true, []byte(val)
 }
 
 // Returns if given ID is in id cache
-func (pc *PostController) checkIDCache(id int) (bool, []byte) {
+func (pc *PostController) checkIDCache(ctx context.Context, id int) (bool, []byte) {
 
-	val := pc.App.Redis.HGet("ID-hash", string(id))
-	if val.Val() == "" {
-		log.Println("[INFO] Key " + string(id) + " not found in ID cache")
+	val, err := pc.App.Redis.HGet(util.IDCacheKey, strconv.Itoa(id)).Result()
+	if err != nil && err != redis.Nil {
+		slog.WarnContext(ctx, "Failed to check ID cache", "err", err)
 		return false, []byte("")
-	} else if val.Err() != nil {
-		panic(val.Err())
 	}
-	log.Println("[INFO] Key " + string(id) + " found in ID cache")
-	return true, []byte(val.Val())
+	if err == redis.Nil || val == "" {
+		metrics.CacheLookup("id", false)
+		slog.DebugContext(ctx, "Cache miss", "cache", "ID", "id", id)
+		return false, []byte("")
+	}
+	metrics.CacheLookup("id", true)
+	slog.DebugContext(ctx, "Cache hit", "cache", "ID", "id", id)
+	return true, []byte(val)
 }
 
 // Returns if given category is in category cache
-func (pc *PostController) checkCategoryCache(category string, key string) (bool, []byte) {
+func (pc *PostController) checkCategoryCache(ctx context.Context, category string, key string) (bool, []byte) {
 
-	val := pc.App.Redis.HGet(category, key)
-	if val.Val() == "" {
-		log.Println("[INFO] Key " + key + " not found in cache")
+	val, err := pc.App.Redis.HGet(category, key).Result()
+	if err != nil && err != redis.Nil {
+		slog.WarnContext(ctx, "Failed to check category cache", "err", err)
+		return false, []byte("")
+	}
+	if err == redis.Nil || val == "" {
+		metrics.CacheLookup("category", false)
+		slog.DebugContext(ctx, "Cache miss", "key", key)
 		return false, []byte("")
-	} else if val.Err() != nil {
-		log.Println(val.Err())
 	}
-	log.Println("[INFO] Key " + key + " found in cache")
-	return true, []byte(val.Val())
+	metrics.CacheLookup("category", true)
+	slog.DebugContext(ctx, "Cache hit", "key", key)
+	return true, []byte(val)
 }
 
 // False -> pagination not in admin cache
 // True -> pagination in admin cache
-func (pc *PostController) checkAdminCache(key string) (bool, []byte) {
+func (pc *PostController) checkAdminCache(ctx context.Context, key string) (bool, []byte) {
 
-	val := pc.App.Redis.HGet("admin-page-hash", key)
-	if val.Val() == "" {
-		log.Println("[INFO] Key " + key + " not found in admin hash")
+	val, err := pc.App.Redis.HGet(util.AdminPageCacheKey, key).Result()
+	if err != nil && err != redis.Nil {
+		slog.WarnContext(ctx, "Failed to check admin pagination cache", "err", err)
+		return false, []byte("")
+	}
+	if err == redis.Nil || val == "" {
+		metrics.CacheLookup("admin-page", false)
+		slog.DebugContext(ctx, "Cache miss", "cache", "admin", "key", key)
 		return false, []byte("")
-	} else if val.Err() != nil {
-		panic(val.Err())
 	}
-	log.Println("[INFO] Key " + key + " found in admin hash")
-	return true, []byte(val.Val())
+	metrics.CacheLookup("admin-page", true)
+	slog.DebugContext(ctx, "Cache hit", "cache", "admin", "key", key)
+	return true, []byte(val)
 }
 
 // Returns if given slug is in admin slug cache
-func (pc *PostController) checkAdminSlugCache(slug string) (bool, []byte) {
+func (pc *PostController) checkAdminSlugCache(ctx context.Context, slug string) (bool, []byte) {
 
-	val := pc.App.Redis.HGet("admin-slug-hash", slug)
-	if val.Val() == "" {
-		log.Println("[INFO] Key " + slug + " not found in admin hash")
+	val, err := pc.App.Redis.HGet(util.AdminSlugCacheKey, slug).Result()
+	if err != nil && err != redis.Nil {
+		slog.WarnContext(ctx, "Failed to check admin slug cache", "err", err)
+		return false, []byte("")
+	}
+	if err == redis.Nil || val == "" {
+		metrics.CacheLookup("admin-slug", false)
+		slog.DebugContext(ctx, "Cache miss", "cache", "admin", "slug", slug)
 		return false, []byte("")
-	} else if val.Err() != nil {
-		panic(val.Err())
 	}
-	log.Println("[INFO] Key " + slug + " found in admin hash")
-	return true, []byte(val.Val())
+	metrics.CacheLookup("admin-slug", true)
+	slog.DebugContext(ctx, "Cache hit", "cache", "admin", "slug", slug)
+	return true, []byte(val)
 }
 
 // Flushes pagination cache
-func (pc *PostController) flushCache() {
-	err := pc.App.Redis.Del("page-hash")
+func (pc *PostController) flushCache(ctx context.Context) {
+	err := pc.App.Redis.Del(util.PageCacheKey)
 	if err.Err() != nil {
-		log.Println(err.Err())
+		slog.WarnContext(ctx, "Failed to flush pagination hash", "err", err.Err())
 	}
-	log.Println("[INFO] Flushed pagination hash")
+	slog.DebugContext(ctx, "Flushed pagination hash")
 	return
 }
 
 // Flushes slug cache
-func (pc *PostController) flushSlugCache(slug string) {
-	err := pc.App.Redis.Del(slug)
+func (pc *PostController) flushSlugCache(ctx context.Context, slug string) {
+	err := pc.App.Redis.Del(util.SlugCacheKey(slug))
 	if err.Err() != nil {
-		log.Println(err.Err())
-		log.Println("[WARN] Failed to flush slug cache")
+		slog.WarnContext(ctx, "Failed to flush slug cache", "err", err.Err())
 		return
 	}
-	log.Println("[INFO] Flushed tags slug cache")
+	slog.DebugContext(ctx, "Flushed tags slug cache")
 	return
 }
 
 // Flushes ID cache
-func (pc *PostController) flushIDCache() {
-	err := pc.App.Redis.Del("ID-hash")
+func (pc *PostController) flushIDCache(ctx context.Context) {
+	err := pc.App.Redis.Del(util.IDCacheKey)
 	if err.Err() != nil {
-		log.Println(err.Err())
+		slog.WarnContext(ctx, "Failed to flush ID hash", "err", err.Err())
 	}
-	log.Println("[INFO] Flushed ID hash")
+	slog.DebugContext(ctx, "Flushed ID hash")
 	return
 }
 
 // Flushes tags (category) cache
-func (pc *PostController) flushTagsCache(tags []string) {
+func (pc *PostController) flushTagsCache(ctx context.Context, tags []string) {
 	for _, tag := range tags {
 		err := pc.App.Redis.Del(tag)
 		if err.Err() != nil {
-			log.Println(err.Err())
+			slog.WarnContext(ctx, "Failed to flush tags pagination hash", "tag", tag, "err", err.Err())
 		}
 	}
-	log.Println("[INFO] Flushed tags pagination hash")
+	slog.DebugContext(ctx, "Flushed tags pagination hash")
 	return
 }
 
 // Flushes admin pagination cache
-func (pc *PostController) flushAdminCache() {
-	err := pc.App.Redis.Del("admin-page-hash")
+func (pc *PostController) flushAdminCache(ctx context.Context) {
+	err := pc.App.Redis.Del(util.AdminPageCacheKey)
 	if err.Err() != nil {
-		log.Println(err.Err())
+		slog.WarnContext(ctx, "Failed to flush admin pagination hash", "err", err.Err())
 	}
-	log.Println("[INFO] Flushed admin pagination hash")
+	slog.DebugContext(ctx, "Flushed admin pagination hash")
 	return
 }
 
 // Flushes admin slug cache
-func (pc *PostController) flushAdminSlugCache(slug string) {
-	err := pc.App.Redis.Del("admin-slug-hash", slug)
+func (pc *PostController) flushAdminSlugCache(ctx context.Context, slug string) {
+	err := pc.App.Redis.Del(util.AdminSlugCacheKey, util.SlugCacheKey(slug))
 	if err.Err() != nil {
-		log.Println(err.Err())
+		slog.WarnContext(ctx, "Failed to flush admin slug cache", "err", err.Err())
 	}
-	log.Println("[INFO] Flushed admin slug cache")
+	slog.DebugContext(ctx, "Flushed admin slug cache")
 	return
 }
 
+// Flushes every cache the posts appear in with a single command. tags are tags added to or removed
+// from the posts.
+func (pc *PostController) flushBulkCache(ctx context.Context, posts []*models.Post, tags []string) {
+	keys := append(util.PostCacheKeys(), util.MenusCacheKey)
+	keys = append(keys, tags...)
+	series := make(map[int]bool)
+	for _, post := range posts {
+		keys = append(keys, util.SlugCacheKey(post.Slug))
+		keys = append(keys, post.Tags...)
+
+		nav, err := pc.SeriesRepository.FindNavByPostID(ctx, post.ID)
+		if err != nil || nav == nil || series[nav.ID] {
+			continue
+		}
+		series[nav.ID] = true
+		slugs, err := pc.SeriesRepository.GetPartSlugs(ctx, nav.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to get series slugs to flush")
+			continue
+		}
+		keys = append(keys, util.SlugCacheKeys(slugs)...)
+	}
+
+	res := pc.App.Redis.Del(keys...)
+	if res.Err() != nil {
+		slog.WarnContext(ctx, "Failed to flush bulk cache", "err", res.Err())
+		return
+	}
+	slog.DebugContext(ctx, "Flushed bulk cache")
+	return
+}
+
+// Flushes the slug cache of every part in the series the post belongs to, since their
+// previous/next links include the post's title and slug
+func (pc *PostController) flushSeriesCache(ctx context.Context, postID int) {
+	nav, err := pc.SeriesRepository.FindNavByPostID(ctx, postID)
+	if err != nil || nav == nil {
+		return
+	}
+	slugs, err := pc.SeriesRepository.GetPartSlugs(ctx, nav.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to flush series cache")
+		return
+	}
+	for _, slug := range slugs {
+		pc.flushSlugCache(ctx, slug)
+	}
+	slog.DebugContext(ctx, "Flushed series slug cache")
+	return
+}
+
+// Reads the optional slug and slugPinned keys. Only admins can set them; postID is -1 for a new post.
+// Returns the custom slug (empty if not given), whether the slug is pinned, and an error if they are invalid.
+func (pc *PostController) getCustomSlug(ctx context.Context, j *JSONData, uid string, postID int, currentlyPinned bool) (string, bool, *APIError) {
+	customSlug, slugErr := j.GetString("slug")
+	if slugErr != nil {
+		customSlug = ""
+	}
+	pinned, pinnedErr := j.GetBool("slugPinned")
+
+	if customSlug == "" && pinnedErr != nil {
+		// Nothing requested, keep the current pin state
+		return "", currentlyPinned, nil
+	}
+
+	user, err := pc.UserRepository.FindByID(ctx, uid)
+	if err != nil || !user.IsAdmin() {
+		return "", false, &APIError{false, "Admin required to set a custom slug", http.StatusForbidden}
+	}
+
+	if customSlug == "" {
+		return "", pinned, nil
+	}
+
+	if !util.IsSlug(customSlug) {
+		return "", false, &APIError{false, "Slug may only contain letters, numbers, dashes and slashes", http.StatusBadRequest}
+	}
+	if !util.CheckSlug(customSlug) {
+		return "", false, &APIError{false, "The slug is reserved by another route", http.StatusBadRequest}
+	}
+
+	owner, err := pc.PostRepository.FindSlugOwner(ctx, customSlug)
+	if err == nil && owner != postID {
+		return "", false, &APIError{false, "The slug is already in use", http.StatusBadRequest}
+	}
+
+	return customSlug, true, nil
+}
+
+// Reads the optional excerpt key. A non-empty excerpt overrides the generated one until an empty
+// excerpt is sent. Returns the excerpt, whether it is custom, and an error if it is invalid.
+func getExcerpt(j *JSONData, rendered *util.RenderedMarkdown, current string, currentlyCustom bool) (string, bool, *APIError) {
+	excerpt, err := j.GetString("excerpt")
+	if err != nil {
+		if currentlyCustom {
+			return current, true, nil
+		}
+		return rendered.Excerpt, false, nil
+	}
+
+	excerpt = strings.TrimSpace(excerpt)
+	if excerpt == "" {
+		return rendered.Excerpt, false, nil
+	}
+	if utf8.RuneCountInString(excerpt) > 2*util.ExcerptLength {
+		return "", false, &APIError{false, "Excerpt is too long", http.StatusBadRequest}
+	}
+
+	return excerpt, true, nil
+}
+
+// Returns the num query value, or the default if not given, and false if it was invalid and an
+// error response was sent
+func getPerPage(num string, defaultPerPage int, settings *models.SiteSettings, w http.ResponseWriter) (int, bool) {
+	if num == "" {
+		return defaultPerPage, true
+	}
+	perPage, err := strconv.Atoi(num)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid num type", http.StatusBadRequest}, w)
+		return 0, false
+	}
+	if perPage < settings.MinPostsPerPage || perPage > settings.MaxPostsPerPage {
+		NewAPIError(&APIError{false, fmt.Sprintf("Query string num is not within bounds [%v, %v]", settings.MinPostsPerPage, settings.MaxPostsPerPage), http.StatusBadRequest}, w)
+		return 0, false
+	}
+	return perPage, true
+}
+
+// Returns the position a keyset page starts after and the query value the page is cached by. The
+// cursor query value is the cursor of the previous page. Older clients send maxID instead, the
+// minID of the previous page or -1 for the first page. Without either the first page is returned
+// unless required is set. Returns false if an error response was sent.
+func getPageCursor(ctx context.Context, pr repositories.PostRepository, q url.Values, required bool, w http.ResponseWriter) (models.PostCursor, string, bool) {
+	if cursorString := q.Get("cursor"); cursorString != "" {
+		cursor, err := models.ParsePostCursor(cursorString)
+		if err != nil {
+			NewAPIError(&APIError{false, "Invalid cursor", http.StatusBadRequest}, w)
+			return models.PostCursor{}, "", false
+		}
+		return cursor, cursorString, true
+	}
+
+	maxIDString := q.Get("maxID")
+	if maxIDString == "" && !required {
+		maxIDString = "-1"
+	}
+	maxID, err := strconv.Atoi(maxIDString)
+	if err != nil {
+		NewAPIError(&APIError{false, "Cursor or max ID is required (or -1 if first page)", http.StatusBadRequest}, w)
+		return models.PostCursor{}, "", false
+	}
+	if maxID == -1 {
+		return models.PostCursor{}, maxIDString, true
+	}
+	// Posts are ordered by creation time, so the ID alone isn't a position
+	cursor, err := pr.FindCursor(ctx, maxID)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find the post of max ID", http.StatusBadRequest}, w)
+		return models.PostCursor{}, "", false
+	}
+	return cursor, maxIDString, true
+}
+
+// Returns the YYYY/MM/ prefix of a generated slug
+func slugDatePrefix(t time.Time) string {
+	return fmt.Sprintf("%04d/%02d/", t.Year(), int(t.Month()))
+}
+
+// Sends a permanent redirect to the post's current slug. The body also includes the slug for
+// clients that don't follow redirects.
+func redirect(w http.ResponseWriter, r *http.Request, slug string) {
+	location := strings.TrimSuffix(r.URL.Path, mux.Vars(r)["slug"]) + slug
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+
+	data := struct {
+		Redirect string `json:"redirect"`
+	}{
+		slug,
+	}
+
+	w.Header().Set("Location", location)
+	NewAPIResponse(&APIResponse{Success: true, Message: "Post has moved", Data: data}, w, http.StatusMovedPermanently)
+}
+
+// Embeds the author summary and the contributors in the post. If replaceID is set, the author ID is
+// replaced by the author's name as older clients expect.
+func setAuthor(ctx context.Context, ur repositories.UserRepository, pr repositories.PostRepository, post *models.Post, replaceID bool) {
+	setAuthors(ctx, ur, pr, []*models.Post{post}, replaceID)
+}
+
+// Embeds the author summaries and the contributors in the posts, with one query for all the
+// contributors and one for all the authors, like the GraphQL loader.
+func setAuthors(ctx context.Context, ur repositories.UserRepository, pr repositories.PostRepository, posts []*models.Post, replaceID bool) {
+	if len(posts) == 0 {
+		return
+	}
+	ids := make([]int, len(posts))
+	authorIDs := make([]string, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+		authorIDs[i] = post.AuthorID
+	}
+
+	contributors, err := pr.GetAuthorsByPosts(ctx, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get contributors of posts")
+	}
+	users, err := ur.FindByIDs(ctx, authorIDs)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get authors of posts")
+	}
+	authors := make(map[string]*models.User, len(users))
+	for _, user := range users {
+		authors[user.ID.String()] = user
+	}
+
+	for _, post := range posts {
+		post.Authors = contributors[post.ID]
+		author, ok := authors[post.AuthorID]
+		if !ok {
+			if replaceID {
+				post.AuthorID = "Unknown"
+			}
+			continue
+		}
+		post.Author = author.Summary()
+
+		// Posts written before contributors were added only have their primary author
+		if len(post.Authors) == 0 {
+			post.Authors = []*models.PostAuthor{{AuthorSummary: *post.Author, Role: models.RoleAuthor}}
+		}
+
+		if replaceID {
+			post.AuthorID = author.Name
+		}
+	}
+}
+
+// Sets the ETag header of a post response to the post's version
+func setPostETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// Reads the version an update is based on from the If-Match header, or the version key if there is
+// no header. Returns false if an error response was sent.
+func getVersion(r *http.Request, j *JSONData, w http.ResponseWriter) (int, bool) {
+	if match := r.Header.Get("If-Match"); match != "" {
+		version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(match, "W/"), `"`))
+		if err != nil {
+			NewAPIError(&APIError{false, "If-Match must be the post's ETag", http.StatusBadRequest}, w)
+			return 0, false
+		}
+		return version, true
+	}
+
+	version, err := j.GetInt("version")
+	if err != nil {
+		NewAPIError(&APIError{false, "The post's version is required, send it as If-Match or version", http.StatusPreconditionRequired}, w)
+		return 0, false
+	}
+	return version, true
+}
+
+// Sends a conflict response with the current copy of the post
+func (pc *PostController) sendConflict(ctx context.Context, current *models.Post, w http.ResponseWriter) {
+	setAuthor(ctx, pc.UserRepository, pc.PostRepository, current, false)
+	setPostETag(w, current.Version)
+	NewAPIResponse(&APIResponse{Success: false, Message: "Post was changed by someone else", Data: current}, w, http.StatusConflict)
+}
+
+// Returns the edit lock of the post, or nil if nobody has it open
+func (pc *PostController) getEditLock(ctx context.Context, id int) (*models.EditLock, error) {
+	val, err := pc.App.Redis.Get(util.EditLockKeyPrefix + strconv.Itoa(id)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get edit lock", "post", id, "err", err)
+		return nil, err
+	}
+
+	lock := new(models.EditLock)
+	if err := json.Unmarshal([]byte(val), lock); err != nil {
+		slog.ErrorContext(ctx, "Failed to read edit lock", "post", id, "err", err)
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Returns true if the user is an admin or one of the post's contributors
+func (pc *PostController) canEdit(ctx context.Context, uid string, post *models.Post) bool {
+	if post.AuthorID == uid {
+		return true
+	}
+	user, err := pc.UserRepository.FindByID(ctx, uid)
+	if err == nil && user.IsAdmin() {
+		return true
+	}
+	return pc.PostRepository.IsAuthor(ctx, post.ID, uid)
+}
+
+// Returns the ID of the first contributor with the author role
+func primaryAuthorID(authors []*models.PostAuthor) string {
+	for _, author := range authors {
+		if author.Role == models.RoleAuthor {
+			return author.ID
+		}
+	}
+	return ""
+}
+
+// Reads the optional authors key, a list of {id, role} contributors in order. Returns the
+// contributors, whether they were given, and an error if they are invalid.
+func (pc *PostController) getAuthors(ctx context.Context, j *JSONData) ([]*models.PostAuthor, bool, *APIError) {
+	value, ok := j.data["authors"]
+	if !ok {
+		return nil, false, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 || len(list) > maxPostAuthors {
+		return nil, false, &APIError{false, "Authors must be a list of 1 to 10 contributors", http.StatusBadRequest}
+	}
+
+	authors := make([]*models.PostAuthor, 0, len(list))
+	seen := make(map[string]bool, len(list))
+	hasAuthor := false
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false, &APIError{false, "Contributors must have an id and role", http.StatusBadRequest}
+		}
+		id, _ := entry["id"].(string)
+		role, _ := entry["role"].(string)
+		if role == "" {
+			role = models.RoleAuthor
+		}
+		if role != models.RoleAuthor && role != models.RoleEditor && role != models.RoleIllustrator {
+			return nil, false, &APIError{false, "Contributor role must be author, editor or illustrator", http.StatusBadRequest}
+		}
+
+		user, err := pc.UserRepository.FindByID(ctx, id)
+		if err != nil {
+			return nil, false, &APIError{false, "Could not find contributor " + id, http.StatusBadRequest}
+		}
+		if seen[user.ID.String()] {
+			return nil, false, &APIError{false, "Contributor " + id + " is listed more than once", http.StatusBadRequest}
+		}
+		seen[user.ID.String()] = true
+		hasAuthor = hasAuthor || role == models.RoleAuthor
+
+		authors = append(authors, &models.PostAuthor{AuthorSummary: *user.Summary(), Role: role})
+	}
+
+	if !hasAuthor {
+		return nil, false, &APIError{false, "At least one contributor must have the author role", http.StatusBadRequest}
+	}
+
+	return authors, true, nil
+}
+
 // Removes any duplicate tags
 func rmDuplicateTags(tags []string) []string {
 	// Remove any duplicate tags by using them as a key in a map
@@ -968,18 +1753,7 @@ func rmDuplicateTags(tags []string) []string {
 // Returns true if tags contains no keywords, false otherwise
 func checkTags(tags []string) bool {
 	for _, tag := range tags {
-		// Check if page-hash
-		if tag == "page-hash" {
-			return false
-		}
-		// Check if slug
-		matched, err := regexp.Match(`^\d{4}[\/]\d{2}[\/].`, []byte(tag))
-		if err != nil {
-			log.Println(err)
-			log.Println("[WARN] Failed to check tags using regexp")
-			return false
-		}
-		if matched {
+		if !util.CheckTag(tag) {
 			return false
 		}
 	}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/gorilla/mux"
+)
+
+// SeriesController stores the App config and repositories
+type SeriesController struct {
+	*app.App
+	repositories.SeriesRepository
+	repositories.PostRepository
+}
+
+// NewSeriesController creates a new series controller
+func NewSeriesController(a *app.App, sr repositories.SeriesRepository, pr repositories.PostRepository) *SeriesController {
+	return &SeriesController{a, sr, pr}
+}
+
+// GetAll returns the list of all series (without parts)
+func (sc *SeriesController) GetAll(w http.ResponseWriter, r *http.Request) {
+	series, err := sc.SeriesRepository.GetAll(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch series", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if series == nil {
+		series = []*models.Series{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: series}, w, http.StatusOK)
+}
+
+// GetBySlug returns the series with the given slug and its public parts in order
+func (sc *SeriesController) GetBySlug(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	slug := vars["slug"]
+
+	series, err := sc.SeriesRepository.FindBySlug(r.Context(), slug)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find series", http.StatusNotFound}, w)
+		return
+	}
+
+	series.Parts, err = sc.SeriesRepository.GetParts(r.Context(), series.ID, false)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch series parts", http.StatusInternalServerError}, w)
+		return
+	}
+	if series.Parts == nil {
+		series.Parts = []*models.SeriesPart{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: series}, w, http.StatusOK)
+}
+
+// GetByIDAdmin returns the series with the given ID and all of its parts (including hidden)
+func (sc *SeriesController) GetByIDAdmin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	series, err := sc.SeriesRepository.FindByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find series", http.StatusNotFound}, w)
+		return
+	}
+
+	series.Parts, err = sc.SeriesRepository.GetParts(r.Context(), series.ID, true)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch series parts", http.StatusInternalServerError}, w)
+		return
+	}
+	if series.Parts == nil {
+		series.Parts = []*models.SeriesPart{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: series}, w, http.StatusOK)
+}
+
+// Create creates a new series and returns its details
+func (sc *SeriesController) Create(w http.ResponseWriter, r *http.Request) {
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	title, err := j.GetString("title")
+	if err != nil {
+		NewAPIError(&APIError{false, "Title is required", http.StatusBadRequest}, w)
+		return
+	}
+
+	slug := util.GenerateSlug(title)
+	if len(slug) == 0 {
+		NewAPIError(&APIError{false, "Title is invalid", http.StatusBadRequest}, w)
+		return
+	}
+
+	if sc.SeriesRepository.Exists(r.Context(), slug) {
+		NewAPIError(&APIError{false, "A series with this title already exists", http.StatusBadRequest}, w)
+		return
+	}
+
+	description, err := j.GetString("description")
+	if err != nil {
+		description = ""
+	}
+
+	postIDs, err := j.GetIntArray("posts")
+	if err != nil {
+		postIDs = []int{}
+	}
+
+	if !sc.checkParts(r.Context(), -1, postIDs, w) {
+		return
+	}
+
+	series := &models.Series{
+		Title:       title,
+		Slug:        slug,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+
+	err = sc.SeriesRepository.Create(r.Context(), series, postIDs)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not create series", http.StatusBadRequest}, w)
+		return
+	}
+
+	series.Parts, _ = sc.SeriesRepository.GetParts(r.Context(), series.ID, true)
+	sc.flushPartsCache(r.Context(), series.Parts)
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Series created", Data: series}, w, http.StatusOK)
+}
+
+// Update updates the series with the given id and returns its new details
+func (sc *SeriesController) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	series, err := sc.SeriesRepository.FindByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find series", http.StatusNotFound}, w)
+		return
+	}
+
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	title, err := j.GetString("title")
+	if err != nil {
+		NewAPIError(&APIError{false, "Title is required", http.StatusBadRequest}, w)
+		return
+	}
+
+	slug := util.GenerateSlug(title)
+	if len(slug) == 0 {
+		NewAPIError(&APIError{false, "Title is invalid", http.StatusBadRequest}, w)
+		return
+	}
+
+	if slug != series.Slug && sc.SeriesRepository.Exists(r.Context(), slug) {
+		NewAPIError(&APIError{false, "A series with this title already exists", http.StatusBadRequest}, w)
+		return
+	}
+
+	description, err := j.GetString("description")
+	if err != nil {
+		description = series.Description
+	}
+
+	oldParts, err := sc.SeriesRepository.GetParts(r.Context(), series.ID, true)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch series parts", http.StatusInternalServerError}, w)
+		return
+	}
+
+	// The parts are only replaced when the posts key is given
+	postIDs, err := j.GetIntArray("posts")
+	if err != nil {
+		postIDs = nil
+	} else if postIDs == nil {
+		postIDs = []int{}
+	}
+	if postIDs != nil && !sc.checkParts(r.Context(), series.ID, postIDs, w) {
+		return
+	}
+
+	now := time.Now()
+	series.Title = title
+	series.Slug = slug
+	series.Description = description
+	series.UpdatedAt = &now
+
+	err = sc.SeriesRepository.Update(r.Context(), series, postIDs)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not update series", http.StatusBadRequest}, w)
+		return
+	}
+
+	series.Parts, _ = sc.SeriesRepository.GetParts(r.Context(), series.ID, true)
+	sc.flushPartsCache(r.Context(), oldParts)
+	sc.flushPartsCache(r.Context(), series.Parts)
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Series updated", Data: series}, w, http.StatusOK)
+}
+
+// Delete deletes the series with the given id. The posts in the series are kept.
+func (sc *SeriesController) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	_, err = sc.SeriesRepository.FindByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find series to delete", http.StatusNotFound}, w)
+		return
+	}
+
+	parts, err := sc.SeriesRepository.GetParts(r.Context(), id, true)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch series parts", http.StatusInternalServerError}, w)
+		return
+	}
+
+	err = sc.SeriesRepository.Delete(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not delete series", http.StatusInternalServerError}, w)
+		return
+	}
+	sc.flushPartsCache(r.Context(), parts)
+
+	NewAPIResponse(&APIResponse{Success: true, Data: id}, w, http.StatusOK)
+}
+
+// Returns true if every post ID exists, is only listed once and isn't part of another series,
+// otherwise sends an error response. seriesID is -1 for a new series.
+func (sc *SeriesController) checkParts(ctx context.Context, seriesID int, postIDs []int, w http.ResponseWriter) bool {
+	seen := make(map[int]bool, len(postIDs))
+	for _, postID := range postIDs {
+		if seen[postID] {
+			NewAPIError(&APIError{false, "Post " + strconv.Itoa(postID) + " is listed more than once", http.StatusBadRequest}, w)
+			return false
+		}
+		seen[postID] = true
+
+		if _, err := sc.PostRepository.FindByIDAdmin(ctx, postID); err != nil {
+			NewAPIError(&APIError{false, "Could not find post " + strconv.Itoa(postID), http.StatusBadRequest}, w)
+			return false
+		}
+
+		nav, err := sc.SeriesRepository.FindNavByPostID(ctx, postID)
+		if err != nil {
+			NewAPIError(&APIError{false, "Could not fetch series of post " + strconv.Itoa(postID), http.StatusInternalServerError}, w)
+			return false
+		}
+		if nav != nil && nav.ID != seriesID {
+			NewAPIError(&APIError{false, "Post " + strconv.Itoa(postID) + " is already part of the series " + nav.Title, http.StatusConflict}, w)
+			return false
+		}
+	}
+	return true
+}
+
+// Flushes the cached posts of the given parts since their series navigation changed
+func (sc *SeriesController) flushPartsCache(ctx context.Context, parts []*models.SeriesPart) {
+	for _, part := range parts {
+		err := sc.App.Redis.Del(util.SlugCacheKey(part.Slug))
+		if err.Err() != nil {
+			slog.WarnContext(ctx, "Failed to flush series parts cache", "slug", part.Slug, "err", err.Err())
+		}
+	}
+	err := sc.App.Redis.Del(util.IDCacheKey)
+	if err.Err() != nil {
+		slog.WarnContext(ctx, "Failed to flush series parts cache", "err", err.Err())
+	}
+	slog.DebugContext(ctx, "Flushed series parts cache")
+	return
+}
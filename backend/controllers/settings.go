@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/util"
+)
+
+// SettingsController stores the App config and repositories
+type SettingsController struct {
+	*app.App
+	repositories.TagRepository
+}
+
+// NewSettingsController creates a new site settings controller
+func NewSettingsController(a *app.App, tr repositories.TagRepository) *SettingsController {
+	return &SettingsController{a, tr}
+}
+
+// GetPublic returns the site settings that anyone can read
+func (sc *SettingsController) GetPublic(w http.ResponseWriter, r *http.Request) {
+	settings := sc.App.Settings.Get()
+
+	NewAPIResponse(&APIResponse{Success: true, Data: settings.Public()}, w, http.StatusOK)
+}
+
+// GetAdmin returns all site settings
+func (sc *SettingsController) GetAdmin(w http.ResponseWriter, r *http.Request) {
+	settings := sc.App.Settings.Get()
+
+	NewAPIResponse(&APIResponse{Success: true, Data: &settings}, w, http.StatusOK)
+}
+
+// Update changes the given site settings and returns all of them. Settings that are not given
+// keep their value.
+func (sc *SettingsController) Update(w http.ResponseWriter, r *http.Request) {
+	var changes map[string]json.RawMessage
+	err := json.NewDecoder(r.Body).Decode(&changes)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	old := sc.App.Settings.Get()
+
+	settings, err := sc.App.Settings.Apply(changes)
+	if err != nil {
+		NewAPIError(&APIError{false, err.Error(), http.StatusBadRequest}, w)
+		return
+	}
+
+	err = sc.App.Settings.Save(r.Context(), settings)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not save settings", http.StatusInternalServerError}, w)
+		return
+	}
+
+	// Only pages of the default size are cached
+	if settings.PostsPerPage != old.PostsPerPage || settings.MaxPostsPerPage != old.MaxPostsPerPage {
+		sc.flushPageCache(r.Context())
+	}
+
+	slog.InfoContext(r.Context(), "Updated site settings")
+	NewAPIResponse(&APIResponse{Success: true, Message: "Settings updated", Data: settings}, w, http.StatusOK)
+}
+
+// Flushes the pagination hashes, including the per-tag hashes
+func (sc *SettingsController) flushPageCache(ctx context.Context) {
+	keys := []string{util.PageCacheKey, util.AdminPageCacheKey}
+	tags, err := sc.TagRepository.GetAll(ctx, true)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get tags to flush")
+	}
+	for _, tag := range tags {
+		keys = append(keys, tag.Name)
+	}
+
+	res := sc.App.Redis.Del(keys...)
+	if res.Err() != nil {
+		slog.WarnContext(ctx, "Failed to flush pagination cache", "err", res.Err())
+		return
+	}
+	slog.DebugContext(ctx, "Flushed pagination cache")
+	return
+}
@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/gorilla/mux"
+)
+
+// TagController stores the App config and repositories
+type TagController struct {
+	*app.App
+	repositories.TagRepository
+	repositories.MenuRepository
+}
+
+// NewTagController creates a new tag controller
+func NewTagController(a *app.App, tr repositories.TagRepository, mr repositories.MenuRepository) *TagController {
+	return &TagController{a, tr, mr}
+}
+
+// GetAll returns the list of all tags with their public post counts
+func (tc *TagController) GetAll(w http.ResponseWriter, r *http.Request) {
+	tags, err := tc.TagRepository.GetAll(r.Context(), false)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch tags", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: tags}, w, http.StatusOK)
+}
+
+// GetAllAdmin returns the list of all tags with post counts including hidden posts
+func (tc *TagController) GetAllAdmin(w http.ResponseWriter, r *http.Request) {
+	tags, err := tc.TagRepository.GetAll(r.Context(), true)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch tags", http.StatusBadRequest}, w)
+		return
+	}
+
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: tags}, w, http.StatusOK)
+}
+
+// GetByName returns the tag with the given name
+func (tc *TagController) GetByName(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["tag"]
+
+	tag, err := tc.TagRepository.FindByName(r.Context(), name, false)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find tag", http.StatusNotFound}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: tag}, w, http.StatusOK)
+}
+
+// Update creates or updates the metadata of the tag with the given name
+func (tc *TagController) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["tag"]
+
+	if !checkTags([]string{name}) {
+		NewAPIError(&APIError{false, "Contains bad tag", http.StatusBadRequest}, w)
+		return
+	}
+
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	displayName, err := j.GetString("displayName")
+	if err != nil {
+		displayName = ""
+	}
+
+	description, err := j.GetString("description")
+	if err != nil {
+		description = ""
+	}
+
+	color, err := j.GetString("color")
+	if err != nil {
+		color = ""
+	}
+	if color != "" && !util.IsHexColor(color) {
+		NewAPIError(&APIError{false, "Color must be a hex color such as #ff8800", http.StatusBadRequest}, w)
+		return
+	}
+
+	imgURL, err := j.GetString("coverImageUrl")
+	if err != nil {
+		imgURL = ""
+	}
+
+	now := time.Now()
+	tag := &models.Tag{
+		Name:          name,
+		DisplayName:   displayName,
+		Description:   description,
+		Color:         color,
+		CoverImageURL: imgURL,
+		UpdatedAt:     &now,
+	}
+
+	err = tc.TagRepository.Save(r.Context(), tag)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not update tag", http.StatusBadRequest}, w)
+		return
+	}
+
+	tag, err = tc.TagRepository.FindByName(r.Context(), name, true)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find tag", http.StatusNotFound}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Tag updated", Data: tag}, w, http.StatusOK)
+}
+
+// Delete deletes the metadata of the tag with the given name. Posts keep the tag.
+func (tc *TagController) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["tag"]
+
+	err := tc.TagRepository.Delete(r.Context(), name)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not delete tag", http.StatusInternalServerError}, w)
+		return
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: name}, w, http.StatusOK)
+}
+
+// Rename renames a tag on every post. If the new name is already in use, the tags are merged.
+func (tc *TagController) Rename(w http.ResponseWriter, r *http.Request) {
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	from, err := j.GetString("from")
+	if err != nil || from == "" {
+		NewAPIError(&APIError{false, "Tag to rename is required", http.StatusBadRequest}, w)
+		return
+	}
+
+	to, err := j.GetString("to")
+	if err != nil || to == "" {
+		NewAPIError(&APIError{false, "New tag name is required", http.StatusBadRequest}, w)
+		return
+	}
+
+	tc.merge(r.Context(), []string{from}, to, w)
+}
+
+// Merge replaces several tags with a single tag on every post
+func (tc *TagController) Merge(w http.ResponseWriter, r *http.Request) {
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	from, err := j.GetStringArray("from")
+	if err != nil || len(from) == 0 {
+		NewAPIError(&APIError{false, "Tags to merge are required", http.StatusBadRequest}, w)
+		return
+	}
+
+	to, err := j.GetString("to")
+	if err != nil || to == "" {
+		NewAPIError(&APIError{false, "Tag to merge into is required", http.StatusBadRequest}, w)
+		return
+	}
+
+	tc.merge(r.Context(), from, to, w)
+}
+
+// Validates and runs a rename/merge, then flushes every cache that could contain the old tags
+func (tc *TagController) merge(ctx context.Context, from []string, to string, w http.ResponseWriter) {
+	if !checkTags([]string{to}) {
+		NewAPIError(&APIError{false, "Contains bad tag", http.StatusBadRequest}, w)
+		return
+	}
+
+	sources := make([]string, 0, len(from))
+	for _, tag := range rmDuplicateTags(from) {
+		if tag != to {
+			sources = append(sources, tag)
+		}
+	}
+	if len(sources) == 0 {
+		NewAPIError(&APIError{false, "Cannot merge a tag into itself", http.StatusBadRequest}, w)
+		return
+	}
+
+	slugs, err := tc.TagRepository.Merge(ctx, sources, to)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not rename tag", http.StatusInternalServerError}, w)
+		return
+	}
+
+	tc.retargetMenus(ctx, sources, to)
+	tc.flushCache(ctx, append(sources, to), slugs)
+
+	data := struct {
+		From  []string `json:"from"`
+		To    string   `json:"to"`
+		Posts int      `json:"posts"`
+	}{
+		sources,
+		to,
+		len(slugs),
+	}
+
+	slog.InfoContext(ctx, "Merged tags", "sources", sources, "to", to, "posts", len(slugs))
+	NewAPIResponse(&APIResponse{Success: true, Message: "Tags renamed", Data: data}, w, http.StatusOK)
+}
+
+// Flushes the per-tag pagination hashes and the post caches that contain the changed posts
+func (tc *TagController) flushCache(ctx context.Context, tags []string, slugs []string) {
+	keys := append(util.PostCacheKeys(), tags...)
+	keys = append(keys, util.SlugCacheKeys(slugs)...)
+	keys = append(keys, util.MenusCacheKey)
+	err := tc.App.Redis.Del(keys...)
+	if err.Err() != nil {
+		slog.WarnContext(ctx, "Failed to flush tags cache", "err", err.Err())
+		return
+	}
+	slog.DebugContext(ctx, "Flushed tags cache")
+	return
+}
+
+// Points the menu items that link to the merged tags to the tag they were merged into
+func (tc *TagController) retargetMenus(ctx context.Context, sources []string, to string) {
+	merged := make(map[string]bool, len(sources))
+	for _, tag := range sources {
+		merged[tag] = true
+	}
+
+	menus, err := tc.MenuRepository.GetAll(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get menus to update merged tags")
+		return
+	}
+	for _, menu := range menus {
+		if !retargetMenuItems(menu.Items, merged, to) {
+			continue
+		}
+		if err := tc.MenuRepository.Save(ctx, menu); err != nil {
+			slog.WarnContext(ctx, "Failed to update merged tags in menu", "menu", menu.Name)
+		}
+	}
+}
+
+// Replaces the targets of the tag items in merged with to. Returns true if any item changed.
+func retargetMenuItems(items []*models.MenuItem, merged map[string]bool, to string) bool {
+	changed := false
+	for _, item := range items {
+		if item.Type == models.MenuItemTag && merged[item.Target] {
+			item.Target = to
+			changed = true
+		}
+		if retargetMenuItems(item.Children, merged, to) {
+			changed = true
+		}
+	}
+	return changed
+}
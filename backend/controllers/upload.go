@@ -6,11 +6,12 @@ import (
 	"image/png"
 	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/alanqchen/Bear-Post/backend/metrics"
 	"github.com/alanqchen/Bear-Post/backend/util"
 )
 
@@ -89,7 +90,7 @@ func (uc *UploadController) UploadImage(w http.ResponseWriter, r *http.Request)
 	fileName := now.Format("2006-01-02_15-04-05") + "_" + util.GetMD5Hash(now.String())
 
 	if strings.ContainsAny(fileName, `\ | /`) {
-		log.Println("[WARN] Upload generated bad file name:", fileName)
+		slog.WarnContext(r.Context(), "Upload generated bad file name", "file", fileName)
 		NewAPIError(&APIError{false, "Generated bad file name", http.StatusInternalServerError}, w)
 		return
 	}
@@ -100,7 +101,7 @@ func (uc *UploadController) UploadImage(w http.ResponseWriter, r *http.Request)
 	if ext == ".webp" {
 		err = ioutil.WriteFile("./public/images/webp/"+fileName+ext, Buf.Bytes(), 0644)
 		if err != nil {
-			log.Println(err)
+			slog.ErrorContext(r.Context(), "Could not write webp image", "err", err)
 			NewAPIError(&APIError{false, "Could not write webp image", http.StatusInternalServerError}, w)
 			return
 		}
@@ -144,13 +145,14 @@ func (uc *UploadController) UploadImage(w http.ResponseWriter, r *http.Request)
 
 		err = ioutil.WriteFile("./public/images/original/"+fileName+ext, Buf.Bytes(), 0644)
 		if err != nil {
-			log.Println(err)
+			slog.ErrorContext(r.Context(), "Could not write original image", "err", err)
 			NewAPIError(&APIError{false, "Could not write original image", http.StatusInternalServerError}, w)
 			return
 		}
 
 	}
 
+	metrics.UploadBytes("image", Buf.Len())
 	Buf.Reset()
 
 	// TODO: Check if wildcard routes are possible - .webp should go to /public/images/webp while
@@ -210,18 +212,19 @@ func (uc *UploadController) UploadVideo(w http.ResponseWriter, r *http.Request)
 	fileName := now.Format("2006-01-02_15-04-05") + "_" + util.GetMD5Hash(now.String())
 
 	if strings.ContainsAny(fileName, `\ | /`) {
-		log.Println("[WARN] Upload generated bad file name:", fileName)
+		slog.WarnContext(r.Context(), "Upload generated bad file name", "file", fileName)
 		NewAPIError(&APIError{false, "Generated bad file name", http.StatusInternalServerError}, w)
 		return
 	}
 
 	err = ioutil.WriteFile("./public/videos/"+fileName+ext, Buf.Bytes(), 0644)
 	if err != nil {
-		log.Println(err)
+		slog.ErrorContext(r.Context(), "Could not write file to disk", "err", err)
 		NewAPIError(&APIError{false, "Could not write file to disk", http.StatusInternalServerError}, w)
 		return
 	}
 
+	metrics.UploadBytes("video", Buf.Len())
 	Buf.Reset()
 
 	// TODO: Remove hardcoded url
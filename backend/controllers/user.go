@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/alanqchen/Bear-Post/backend/app"
 	"github.com/alanqchen/Bear-Post/backend/models"
@@ -30,6 +34,11 @@ type PasswordResetController struct { // Same as Auth
 }
 */
 
+var (
+	socialNetworkRegex = regexp.MustCompile(`^[a-z0-9\-]{1,32}$`)
+	socialHandleRegex  = regexp.MustCompile(`^@?[a-zA-Z0-9_.\-]{1,64}$`)
+)
+
 // NewUserController creates a new user controller
 func NewUserController(a *app.App, ur repositories.UserRepository, pr repositories.PostRepository) *UserController {
 	return &UserController{a, ur, pr}
@@ -37,7 +46,6 @@ func NewUserController(a *app.App, ur repositories.UserRepository, pr repositori
 
 // HelloWorld is the response used on pings
 func (uc *UserController) HelloWorld(w http.ResponseWriter, r *http.Request) {
-	log.Println(r.Context().Value("userId"))
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	fmt.Fprint(w, "Hey! You're not supposed to be here! (The API is online though)")
 }
@@ -107,7 +115,7 @@ func (uc *UserController) Create(w http.ResponseWriter, r *http.Request) {
 		NewAPIError(&APIError{false, "You must provide a valid email address", http.StatusBadRequest}, w)
 		return
 	} else if !usedDefault {
-		exists := uc.UserRepository.Exists(email)
+		exists := uc.UserRepository.Exists(r.Context(), email)
 		if exists {
 			NewAPIError(&APIError{false, "The email address is already in use", http.StatusBadRequest}, w)
 			return
@@ -119,7 +127,7 @@ func (uc *UserController) Create(w http.ResponseWriter, r *http.Request) {
 		NewAPIError(&APIError{false, "Username is required", http.StatusBadRequest}, w)
 		return
 	}
-	exists := uc.UserRepository.ExistsUsername(username)
+	exists := uc.UserRepository.ExistsUsername(r.Context(), username)
 	if exists {
 		NewAPIError(&APIError{false, "The username is already in use", http.StatusBadRequest}, w)
 		return
@@ -156,7 +164,7 @@ func (uc *UserController) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	u.SetPassword(pw)
 
-	err = uc.UserRepository.Create(u)
+	err = uc.UserRepository.Create(r.Context(), u)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not create user", http.StatusBadRequest}, w)
 		return
@@ -197,7 +205,7 @@ func (uc *UserController) CreateFirstAdmin(w http.ResponseWriter, r *http.Reques
 		NewAPIError(&APIError{false, "You must provide a valid email address", http.StatusBadRequest}, w)
 		return
 	} else if !usedDefault {
-		exists := uc.UserRepository.Exists(email)
+		exists := uc.UserRepository.Exists(r.Context(), email)
 		if exists {
 			NewAPIError(&APIError{false, "The email address is already in use", http.StatusBadRequest}, w)
 			return
@@ -209,7 +217,7 @@ func (uc *UserController) CreateFirstAdmin(w http.ResponseWriter, r *http.Reques
 		NewAPIError(&APIError{false, "Username is required", http.StatusBadRequest}, w)
 		return
 	}
-	exists := uc.UserRepository.ExistsUsername(username)
+	exists := uc.UserRepository.ExistsUsername(r.Context(), username)
 	if exists {
 		NewAPIError(&APIError{false, "The username is already in use", http.StatusBadRequest}, w)
 		return
@@ -241,14 +249,14 @@ func (uc *UserController) CreateFirstAdmin(w http.ResponseWriter, r *http.Reques
 	}
 	u.SetPassword(pw)
 
-	success, err := uc.UserRepository.CreateFirstAdmin(u)
+	success, err := uc.UserRepository.CreateFirstAdmin(r.Context(), u)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not create admin user", http.StatusBadRequest}, w)
 		return
 	}
 
 	if success == false {
-		log.Printf("[BAD CREATE FIRST ADMIN] There is already admin name: %v username: %v password: %v", name, email, pw)
+		slog.WarnContext(r.Context(), "Tried to create a first admin when there already is one", "username", username)
 		NewAPIError(&APIError{false, "There is already an admin user", http.StatusBadRequest}, w)
 		return
 	}
@@ -256,13 +264,13 @@ func (uc *UserController) CreateFirstAdmin(w http.ResponseWriter, r *http.Reques
 	// This shouldn't be needed since this is server-side (closed automatically)
 	//defer r.Body.Close()
 
-	log.Println("[AUTH] First admin user created")
+	slog.InfoContext(r.Context(), "First admin user created", "username", username)
 	NewAPIResponse(&APIResponse{Success: true, Message: "Admin user created"}, w, http.StatusOK)
 }
 
 // GetAll returns the list of all users (no usernames)
 func (uc *UserController) GetAll(w http.ResponseWriter, r *http.Request) {
-	users, err := uc.UserRepository.GetAll()
+	users, err := uc.UserRepository.GetAll(r.Context())
 	if err != nil {
 		// something went wrong
 		NewAPIError(&APIError{false, "Could not fetch users", http.StatusBadRequest}, w)
@@ -274,7 +282,7 @@ func (uc *UserController) GetAll(w http.ResponseWriter, r *http.Request) {
 
 // GetAllDetailed returns the list of all users with all details
 func (uc *UserController) GetAllDetailed(w http.ResponseWriter, r *http.Request) {
-	users, err := uc.UserRepository.GetAllDetailed()
+	users, err := uc.UserRepository.GetAllDetailed(r.Context())
 	if err != nil {
 		// something went wrong
 		NewAPIError(&APIError{false, "Could not fetch users", http.StatusBadRequest}, w)
@@ -293,7 +301,7 @@ func (uc *UserController) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uc.UserRepository.FindByID(id)
+	user, err := uc.UserRepository.FindByID(r.Context(), id)
 	if err != nil {
 		// user was not found
 		NewAPIError(&APIError{false, "Could not find user", http.StatusNotFound}, w)
@@ -312,7 +320,7 @@ func (uc *UserController) GetByIDDetailed(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	user, err := uc.UserRepository.FindByIDDetailed(id)
+	user, err := uc.UserRepository.FindByIDDetailed(r.Context(), id)
 	if err != nil {
 		// user was not found
 		NewAPIError(&APIError{false, "Could not find user", http.StatusNotFound}, w)
@@ -337,7 +345,7 @@ func (uc *UserController) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uc.UserRepository.FindByIDDetailed(uid)
+	user, err := uc.UserRepository.FindByIDDetailed(r.Context(), uid)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not find user", http.StatusBadRequest}, w)
 		return
@@ -359,7 +367,7 @@ func (uc *UserController) Update(w http.ResponseWriter, r *http.Request) {
 		NewAPIError(&APIError{false, "You must provide a valid email address", http.StatusBadRequest}, w)
 		return
 	} else if !usedDefault {
-		exists := uc.UserRepository.Exists(email)
+		exists := uc.UserRepository.Exists(r.Context(), email)
 		if exists {
 			NewAPIError(&APIError{false, "The email address is already in use", http.StatusBadRequest}, w)
 			return
@@ -387,7 +395,7 @@ func (uc *UserController) Update(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		user.SetPassword(newpw)
-		log.Println("[AUTH] Changing password for user", user.Username)
+		slog.InfoContext(r.Context(), "Changing password", "username", user.Username)
 	}
 
 	tempTime := time.Now()
@@ -401,7 +409,7 @@ func (uc *UserController) Update(w http.ResponseWriter, r *http.Request) {
 
 	if user.Admin && !admin {
 		multipleAdmins := false
-		users, err := uc.UserRepository.GetAllDetailed()
+		users, err := uc.UserRepository.GetAllDetailed(r.Context())
 		if err != nil {
 			NewAPIError(&APIError{false, "Failed to perform only admin check", http.StatusInternalServerError}, w)
 			return
@@ -419,7 +427,7 @@ func (uc *UserController) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Admin = admin
 
-	err = uc.UserRepository.Update(user)
+	err = uc.UserRepository.Update(r.Context(), user)
 	if err != nil {
 		NewAPIError(&APIError{false, "Could not update user", http.StatusBadRequest}, w)
 		return
@@ -433,7 +441,104 @@ func (uc *UserController) Update(w http.ResponseWriter, r *http.Request) {
 	NewAPIResponse(&APIResponse{Success: true, Data: authUser}, w, http.StatusOK)
 }
 
-// Delete deletes the given uid user
+// UpdateProfile updates the public profile of the current user. Profile fields that are not
+// given keep their value.
+func (uc *UserController) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	uid, err := services.UserIDFromContext(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Something went wrong", http.StatusInternalServerError}, w)
+		return
+	}
+
+	user, err := uc.UserRepository.FindByIDDetailed(r.Context(), uid)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find user", http.StatusNotFound}, w)
+		return
+	}
+
+	j, err := GetJSON(r.Body)
+	if err != nil {
+		NewAPIError(&APIError{false, "Invalid request", http.StatusBadRequest}, w)
+		return
+	}
+
+	if bio, err := j.GetString("bio"); err == nil {
+		if utf8.RuneCountInString(bio) > 1000 {
+			NewAPIError(&APIError{false, "Bio must not be more than 1000 characters", http.StatusBadRequest}, w)
+			return
+		}
+		user.Bio = strings.TrimSpace(bio)
+	}
+
+	// Avatars are uploaded through the images upload route first
+	if avatarURL, err := j.GetString("avatarUrl"); err == nil {
+		if avatarURL != "" && !strings.HasPrefix(avatarURL, "/assets/images/") && !util.IsHTTPURL(avatarURL) {
+			NewAPIError(&APIError{false, "Avatar must be an uploaded image or a http(s) URL", http.StatusBadRequest}, w)
+			return
+		}
+		user.AvatarURL = avatarURL
+	}
+
+	if website, err := j.GetString("website"); err == nil {
+		if website != "" && !util.IsHTTPURL(website) {
+			NewAPIError(&APIError{false, "Website must be a http(s) URL", http.StatusBadRequest}, w)
+			return
+		}
+		user.Website = website
+	}
+
+	if _, ok := j.data["socialHandles"]; ok {
+		handles, ok := j.data["socialHandles"].(map[string]interface{})
+		if !ok {
+			NewAPIError(&APIError{false, "Social handles must be an object", http.StatusBadRequest}, w)
+			return
+		}
+		user.SocialHandles = make(map[string]string, len(handles))
+		for network, value := range handles {
+			handle, ok := value.(string)
+			if !ok || !socialNetworkRegex.MatchString(network) || !socialHandleRegex.MatchString(handle) {
+				NewAPIError(&APIError{false, "Social handles may only contain letters, numbers, dots, dashes and underscores", http.StatusBadRequest}, w)
+				return
+			}
+			user.SocialHandles[network] = strings.TrimPrefix(handle, "@")
+		}
+	}
+	if user.SocialHandles == nil {
+		user.SocialHandles = map[string]string{}
+	}
+
+	tempTime := time.Now()
+	user.UpdatedAt = &tempTime
+
+	err = uc.UserRepository.UpdateProfile(r.Context(), user)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not update profile", http.StatusBadRequest}, w)
+		return
+	}
+	uc.flushAuthorCache(r.Context(), uid)
+
+	NewAPIResponse(&APIResponse{Success: true, Message: "Profile updated", Data: user}, w, http.StatusOK)
+}
+
+// Flushes the post caches that embed the author's summary
+func (uc *UserController) flushAuthorCache(ctx context.Context, uid string) {
+	slugs, tags, err := uc.PostRepository.GetSlugsAndTagsByAuthor(ctx, uid)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to flush author cache")
+		return
+	}
+	keys := append(util.PostCacheKeys(), tags...)
+	keys = append(keys, util.SlugCacheKeys(slugs)...)
+	res := uc.App.Redis.Del(keys...)
+	if res.Err() != nil {
+		slog.WarnContext(ctx, "Failed to flush author cache", "err", res.Err())
+		return
+	}
+	slog.DebugContext(ctx, "Flushed author cache")
+	return
+}
+
+// Delete moves the given uid user to the trash
 func (uc *UserController) Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -442,7 +547,7 @@ func (uc *UserController) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uc.UserRepository.FindByIDDetailed(id)
+	user, err := uc.UserRepository.FindByIDDetailed(r.Context(), id)
 	if err != nil {
 		// user was not found
 		NewAPIError(&APIError{false, "Could not find user", http.StatusNotFound}, w)
@@ -452,7 +557,7 @@ func (uc *UserController) Delete(w http.ResponseWriter, r *http.Request) {
 	// Check that the only admin isn't being deleted
 	if user.Admin {
 		multipleAdmins := false
-		users, err := uc.UserRepository.GetAll()
+		users, err := uc.UserRepository.GetAll(r.Context())
 		if err != nil {
 			NewAPIError(&APIError{false, "Failed to perform only admin check", http.StatusInternalServerError}, w)
 			return
@@ -469,17 +574,85 @@ func (uc *UserController) Delete(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = uc.UserRepository.Delete(id)
+	err = uc.UserRepository.Delete(r.Context(), id)
 	if err != nil {
 		// user was not found
 		NewAPIError(&APIError{false, "Failed to delete user", http.StatusInternalServerError}, w)
 		return
 	}
 
-	log.Println("[DELETE USER SUCCESS] - username:", user.Username)
+	// Their posts no longer embed their summary
+	uc.flushAuthorCache(r.Context(), id)
+
+	slog.InfoContext(r.Context(), "Deleted user", "username", user.Username)
 	NewAPIResponse(&APIResponse{Success: true, Data: user}, w, http.StatusOK)
 }
 
+// GetTrash returns the users in the trash, most recently deleted first
+func (uc *UserController) GetTrash(w http.ResponseWriter, r *http.Request) {
+	users, err := uc.UserRepository.GetTrash(r.Context())
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not fetch trash", http.StatusBadRequest}, w)
+		return
+	}
+
+	// If result is nil, set to empty array
+	if users == nil {
+		users = []*models.User{}
+	}
+
+	NewAPIResponse(&APIResponse{Success: true, Data: users}, w, http.StatusOK)
+}
+
+// Restore takes the given uid user out of the trash
+func (uc *UserController) Restore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	user, err := uc.UserRepository.FindDeletedByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find user in trash", http.StatusNotFound}, w)
+		return
+	}
+
+	err = uc.UserRepository.Restore(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not restore user", http.StatusInternalServerError}, w)
+		return
+	}
+	uc.flushAuthorCache(r.Context(), id)
+
+	slog.InfoContext(r.Context(), "Restored user", "username", user.Username)
+	NewAPIResponse(&APIResponse{Success: true, Message: "User restored", Data: id}, w, http.StatusOK)
+}
+
+// Purge permanently deletes the given uid user from the trash. The confirm query parameter must be
+// true.
+func (uc *UserController) Purge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if r.URL.Query().Get("confirm") != "true" {
+		NewAPIError(&APIError{false, "Purging a user cannot be undone, set confirm=true to purge them", http.StatusBadRequest}, w)
+		return
+	}
+
+	user, err := uc.UserRepository.FindDeletedByID(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not find user in trash", http.StatusNotFound}, w)
+		return
+	}
+
+	err = uc.UserRepository.Purge(r.Context(), id)
+	if err != nil {
+		NewAPIError(&APIError{false, "Could not purge user", http.StatusInternalServerError}, w)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Purged user", "username", user.Username)
+	NewAPIResponse(&APIResponse{Success: true, Message: "User purged", Data: id}, w, http.StatusOK)
+}
+
 // Password reset functionality - Might look into this more later
 /*
 func (prc *PasswordResetController) ResetPasswordRequest(w http.ResponseWriter, r *http.Request) {
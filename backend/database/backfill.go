@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/jackc/pgx/v4"
+)
+
+// Renders the bodies of the posts saved before bodies were rendered on save, so reads never have to
+func backfillRenderedBodies(ctx context.Context, tx pgx.Tx) error {
+	type legacyPost struct {
+		id   int
+		body string
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id, body FROM post_schema.post WHERE body_html = '' AND body <> ''")
+	if err != nil {
+		return err
+	}
+	var posts []legacyPost
+	for rows.Next() {
+		var p legacyPost
+		if err := rows.Scan(&p.id, &p.body); err != nil {
+			rows.Close()
+			return err
+		}
+		posts = append(posts, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		rendered, err := util.RenderMarkdown(p.body)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to render body of post", "post", p.id, "err", err)
+			continue
+		}
+		_, err = tx.Exec(ctx,
+			"UPDATE post_schema.post SET body_html=$2, toc=$3, excerpt=$4, word_count=$5, reading_time=$6 WHERE id=$1",
+			p.id, rendered.HTML, rendered.TOC, rendered.Excerpt, rendered.WordCount, rendered.ReadingTime,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if len(posts) > 0 {
+		slog.InfoContext(ctx, "Rendered bodies of posts", "posts", len(posts))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Migrations are the changes to the schema since the first release, named
+// <version>_<description>.sql. init.sql creates the latest schema and records the migrations it
+// includes. They are applied in order and must not be edited once released, add a new one instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Arbitrary key of the advisory lock that keeps API instances starting together from migrating at
+// the same time
+const migrationLockKey = 0x62656172
+
+// Fill the columns added by a migration with values computed in Go, keyed by the migration's version
+var backfills = map[int]func(ctx context.Context, tx pgx.Tx) error{
+	5: backfillRenderedBodies,
+}
+
+// Migration is a versioned change to the schema
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// Runs in the migration's transaction after the SQL, nil for most migrations
+	Backfill func(ctx context.Context, tx pgx.Tx) error
+}
+
+// Migrations returns the embedded migrations sorted by version
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no version: %w", entry.Name(), err)
+		}
+		sql, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{version, name, string(sql), backfills[version]})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestMigration returns the version of the newest migration, the schema version the API needs
+func LatestMigration() int {
+	migrations, err := Migrations()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate applies the migrations newer than the version stored in the database. Each migration runs
+// in its own transaction with the version it stores, so a failed migration can be fixed and retried.
+func Migrate(ctx context.Context, db *Postgres) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `create table if not exists post_schema.schema_migration
+		(
+			version integer not null primary key,
+			name text not null,
+			applied_at timestamptz default now() not null
+		)`)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		applied, err := applyMigration(ctx, db, migration)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+		if applied {
+			slog.InfoContext(ctx, "Applied migration", "migration", migration.Name)
+		}
+	}
+	return nil
+}
+
+// Applies the migration unless another instance already did. Returns true if it was applied.
+func applyMigration(ctx context.Context, db *Postgres, migration Migration) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockKey)
+	if err != nil {
+		return false, err
+	}
+	var applied bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM post_schema.schema_migration WHERE version=$1)", migration.Version).Scan(&applied)
+	if err != nil || applied {
+		return false, err
+	}
+
+	if _, err = tx.Exec(ctx, migration.SQL); err != nil {
+		return false, err
+	}
+	if migration.Backfill != nil {
+		if err = migration.Backfill(ctx, tx); err != nil {
+			return false, err
+		}
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO post_schema.schema_migration (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
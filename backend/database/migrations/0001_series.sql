@@ -0,0 +1,44 @@
+create table post_schema.series
+(
+	id integer not null,
+	title text not null,
+	slug text not null,
+	description text default '' not null,
+	created_at timestamptz not null,
+	updated_at timestamptz default null
+);
+
+create unique index series_id_uindex
+	on post_schema.series (id);
+
+create unique index series_slug_uindex
+	on post_schema.series (slug);
+
+alter table post_schema.series
+	add constraint series_pk
+		primary key (id);
+
+create sequence post_schema.series_id_seq;
+
+alter table post_schema.series alter column id set default nextval('post_schema.series_id_seq');
+
+alter sequence post_schema.series_id_seq owned by post_schema.series.id;
+
+create table post_schema.series_post
+(
+	series_id integer not null
+		references post_schema.series (id) on delete cascade,
+	post_id integer not null
+		references post_schema.post (id) on delete cascade,
+	position integer not null
+);
+
+create unique index series_post_post_id_uindex
+	on post_schema.series_post (post_id);
+
+create unique index series_post_position_uindex
+	on post_schema.series_post (series_id, position);
+
+alter table post_schema.series_post
+	add constraint series_post_pk
+		primary key (series_id, post_id);
@@ -0,0 +1,16 @@
+create table post_schema.tag
+(
+	name text not null,
+	display_name text default '' not null,
+	description text default '' not null,
+	color text default '' not null,
+	cover_image_url text default '' not null,
+	updated_at timestamptz default null
+);
+
+create unique index tag_name_uindex
+	on post_schema.tag (name);
+
+alter table post_schema.tag
+	add constraint tag_pk
+		primary key (name);
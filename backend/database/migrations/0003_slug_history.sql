@@ -0,0 +1,20 @@
+alter table post_schema.post
+	add column slug_pinned boolean default false not null;
+
+create table post_schema.post_slug_history
+(
+	slug text not null,
+	post_id integer not null
+		references post_schema.post (id) on delete cascade,
+	created_at timestamptz not null
+);
+
+create unique index post_slug_history_slug_uindex
+	on post_schema.post_slug_history (slug);
+
+create index post_slug_history_post_id_index
+	on post_schema.post_slug_history (post_id);
+
+alter table post_schema.post_slug_history
+	add constraint post_slug_history_pk
+		primary key (slug);
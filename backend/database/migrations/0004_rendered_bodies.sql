@@ -0,0 +1,3 @@
+alter table post_schema.post
+	add column body_html text default '' not null,
+	add column toc jsonb default '[]' not null;
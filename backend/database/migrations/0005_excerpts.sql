@@ -0,0 +1,5 @@
+alter table post_schema.post
+	add column excerpt text default '' not null,
+	add column custom_excerpt boolean default false not null,
+	add column word_count integer default 0 not null,
+	add column reading_time integer default 0 not null;
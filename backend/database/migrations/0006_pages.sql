@@ -0,0 +1,36 @@
+create table post_schema.page
+(
+	id integer not null,
+	title text not null,
+	slug text not null,
+	body text default '' not null,
+	body_html text default '' not null,
+	toc jsonb default '[]' not null,
+	parent_id integer default null
+		references post_schema.page (id) on delete set null,
+	sort_order integer default 0 not null,
+	template text default '' not null,
+	hidden boolean default true not null,
+	authorid uuid not null,
+	created_at timestamptz not null,
+	updated_at timestamptz default null
+);
+
+create unique index page_id_uindex
+	on post_schema.page (id);
+
+create unique index page_slug_uindex
+	on post_schema.page (slug);
+
+create index page_parent_id_index
+	on post_schema.page (parent_id);
+
+alter table post_schema.page
+	add constraint page_pk
+		primary key (id);
+
+create sequence post_schema.page_id_seq;
+
+alter table post_schema.page alter column id set default nextval('post_schema.page_id_seq');
+
+alter sequence post_schema.page_id_seq owned by post_schema.page.id;
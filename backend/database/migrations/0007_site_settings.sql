@@ -0,0 +1,13 @@
+create table post_schema.site_settings
+(
+	key text not null,
+	value jsonb not null,
+	updated_at timestamptz not null
+);
+
+create unique index site_settings_key_uindex
+	on post_schema.site_settings (key);
+
+alter table post_schema.site_settings
+	add constraint site_settings_pk
+		primary key (key);
@@ -0,0 +1,13 @@
+create table post_schema.menu
+(
+	name text not null,
+	items jsonb default '[]' not null,
+	updated_at timestamptz default null
+);
+
+create unique index menu_name_uindex
+	on post_schema.menu (name);
+
+alter table post_schema.menu
+	add constraint menu_pk
+		primary key (name);
@@ -0,0 +1,5 @@
+alter table user_schema."user"
+	add column bio text default '' not null,
+	add column avatar_url text default '' not null,
+	add column website text default '' not null,
+	add column social_handles jsonb default '{}' not null;
@@ -0,0 +1,19 @@
+create table post_schema.post_author
+(
+	post_id integer not null
+		references post_schema.post (id) on delete cascade,
+	user_id uuid not null
+		references user_schema."user" (id) on delete cascade,
+	role text default 'author' not null,
+	position integer not null
+);
+
+create unique index post_author_position_uindex
+	on post_schema.post_author (post_id, position);
+
+create index post_author_user_id_index
+	on post_schema.post_author (user_id);
+
+alter table post_schema.post_author
+	add constraint post_author_pk
+		primary key (post_id, user_id);
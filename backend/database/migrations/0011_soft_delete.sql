@@ -0,0 +1,5 @@
+alter table post_schema.post
+	add column deleted_at timestamptz default null;
+
+alter table user_schema."user"
+	add column deleted_at timestamptz default null;
@@ -0,0 +1,2 @@
+alter table post_schema.post
+	add column version integer default 1 not null;
@@ -3,7 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -21,12 +21,12 @@ func NewPostgres(dbConfig config.PostgreSQLConfig) (*Postgres, error) {
 	databaseDSN := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s", dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.Database)
 	connConfig, err := pgxpool.ParseConfig(databaseDSN)
 	if err != nil {
-		log.Printf("[FATAL] Unable to parse to DSN: %v\n", err)
+		slog.Error("Unable to parse to DSN", "err", err)
 		os.Exit(1)
 	}
 	conn, err := pgxpool.ConnectConfig(context.Background(), connConfig)
 	if err != nil {
-		log.Printf("[FATAL] Unable to connect to database: %v\n", err)
+		slog.Error("Unable to connect to database", "err", err)
 		os.Exit(1)
 	}
 
@@ -1,8 +1,7 @@
 package database
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/alanqchen/Bear-Post/backend/config"
 	"github.com/go-redis/redis"
@@ -21,12 +20,11 @@ func NewRedis(dbConfig config.RedisConfig) (*Redis, error) {
 		Password: dbConfig.Password,
 		DB:       0,
 	})
-	log.Println("Pinging redis...")
+	slog.Debug("Pinging redis...")
 	_, err := client.Ping().Result()
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
-	log.Println("Ping Successful")
+	slog.Debug("Ping Successful")
 	return &Redis{client}, err
 }
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const complexityCtxKey contextKey = "graph-complexity"
+
+// The cost of the fields a request resolved so far
+type complexity struct {
+	mu   sync.Mutex
+	cost int
+}
+
+// Returns a copy of the context that counts the cost of a new request
+func withComplexity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, complexityCtxKey, &complexity{})
+}
+
+// Adds the cost of a field that is about to be resolved. Every field that queries the database
+// costs 1 and a list costs 1 more for each item it can return, the page size of connections.
+// Returns an error instead once the request would cost more than MaxComplexity, so the field isn't
+// resolved.
+func addComplexity(ctx context.Context, cost int) error {
+	c, ok := ctx.Value(complexityCtxKey).(*complexity)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cost+cost > MaxComplexity {
+		return fmt.Errorf("Query complexity is over the limit of %v", MaxComplexity)
+	}
+	c.cost += cost
+	return nil
+}
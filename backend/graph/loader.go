@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+)
+
+type contextKey string
+
+const loaderCtxKey contextKey = "graph-loader"
+
+// Caches what is loaded once for a whole request
+type loader struct {
+	tagsOnce sync.Once
+	tags     map[string]*models.Tag
+	tagsErr  error
+}
+
+// Returns a copy of the context with a new request loader
+func withLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loaderCtxKey, &loader{})
+}
+
+// Returns the loader of the request, or a new one if the context has none
+func loaderFromContext(ctx context.Context) *loader {
+	if l, ok := ctx.Value(loaderCtxKey).(*loader); ok {
+		return l
+	}
+	return &loader{}
+}
+
+// Returns the public tags by name, loading all of them with a single query the first time
+func (l *loader) getTags(ctx context.Context, r *Resolver) (map[string]*models.Tag, error) {
+	l.tagsOnce.Do(func() {
+		tags, err := r.tags.GetAll(ctx, false)
+		if err != nil {
+			l.tagsErr = err
+			return
+		}
+		l.tags = make(map[string]*models.Tag, len(tags))
+		for _, tag := range tags {
+			l.tags[tag.Name] = tag
+		}
+	})
+	return l.tags, l.tagsErr
+}
+
+// A page of posts whose contributors are loaded together the first time one of them needs them,
+// so a connection costs two queries instead of two per post
+type postBatch struct {
+	r       *Resolver
+	posts   []*models.Post
+	once    sync.Once
+	authors map[int][]*models.PostAuthor
+	users   map[string]*models.User
+	err     error
+}
+
+func newPostBatch(r *Resolver, posts []*models.Post) *postBatch {
+	return &postBatch{r: r, posts: posts}
+}
+
+// Loads the contributors of every post in the batch and their users
+func (b *postBatch) load(ctx context.Context) error {
+	b.once.Do(func() {
+		ids := make([]int, len(b.posts))
+		for i, post := range b.posts {
+			ids[i] = post.ID
+		}
+		b.authors, b.err = b.r.posts.GetAuthorsByPosts(ctx, ids)
+		if b.err != nil {
+			slog.WarnContext(ctx, "Failed to load contributors of posts", "err", b.err)
+			return
+		}
+
+		// Posts written before contributors were added only have their primary author
+		seen := map[string]bool{}
+		var userIDs []string
+		add := func(id string) {
+			if !seen[id] {
+				seen[id] = true
+				userIDs = append(userIDs, id)
+			}
+		}
+		for _, post := range b.posts {
+			add(post.AuthorID)
+			for _, author := range b.authors[post.ID] {
+				add(author.ID)
+			}
+		}
+
+		users, err := b.r.users.FindByIDs(ctx, userIDs)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to load authors of posts", "err", err)
+			b.err = err
+			return
+		}
+		b.users = make(map[string]*models.User, len(users))
+		for _, user := range users {
+			b.users[user.ID.String()] = user
+		}
+	})
+	return b.err
+}
+
+// Returns the primary author of the post, or nil if they were deleted
+func (b *postBatch) author(ctx context.Context, post *models.Post) (*models.User, error) {
+	if err := b.load(ctx); err != nil {
+		return nil, err
+	}
+	return b.users[post.AuthorID], nil
+}
+
+// Returns the contributors of the post that still exist
+func (b *postBatch) contributors(ctx context.Context, post *models.Post) ([]*contributorResolver, error) {
+	if err := b.load(ctx); err != nil {
+		return nil, err
+	}
+
+	authors := b.authors[post.ID]
+	if len(authors) == 0 {
+		authors = []*models.PostAuthor{{AuthorSummary: models.AuthorSummary{ID: post.AuthorID}, Role: models.RoleAuthor}}
+	}
+
+	contributors := make([]*contributorResolver, 0, len(authors))
+	for _, author := range authors {
+		if user, ok := b.users[author.ID]; ok {
+			contributors = append(contributors, &contributorResolver{&userResolver{b.r, user}, author.Role})
+		}
+	}
+	return contributors, nil
+}
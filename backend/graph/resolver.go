@@ -0,0 +1,620 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostMutator creates and updates posts with the same validation as the posts endpoints.
+// The input has the keys of the endpoints' JSON body.
+type PostMutator interface {
+	CreatePost(ctx context.Context, input map[string]interface{}) (*models.Post, error)
+	UpdatePost(ctx context.Context, id int, version int, input map[string]interface{}) (*models.Post, error)
+}
+
+// Resolver is the root resolver of the schema
+type Resolver struct {
+	posts    repositories.PostRepository
+	users    repositories.UserRepository
+	tags     repositories.TagRepository
+	settings *services.SettingsService
+	mutator  PostMutator
+}
+
+// Post returns a public post by its id or slug
+func (r *Resolver) Post(ctx context.Context, args struct {
+	ID   *graphql.ID
+	Slug *string
+}) (*postResolver, error) {
+	if err := addComplexity(ctx, 1); err != nil {
+		return nil, err
+	}
+	var post *models.Post
+	var err error
+	switch {
+	case args.ID != nil:
+		id, convErr := strconv.Atoi(string(*args.ID))
+		if convErr != nil {
+			return nil, nil
+		}
+		post, err = r.posts.FindByID(ctx, id)
+	case args.Slug != nil:
+		post, err = r.posts.FindBySlug(ctx, *args.Slug)
+	default:
+		return nil, errors.New("id or slug is required")
+	}
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not fetch post", "err", err)
+		return nil, errors.New("Could not fetch post")
+	}
+	return &postResolver{post, newPostBatch(r, []*models.Post{post})}, nil
+}
+
+// Posts returns a page of public posts
+func (r *Resolver) Posts(ctx context.Context, args struct {
+	First *int32
+	After *string
+	Tag   *string
+}) (*connectionResolver, error) {
+	var tags []string
+	count := r.posts.GetPublicPostCount
+	if args.Tag != nil {
+		tag := *args.Tag
+		tags = []string{tag}
+		count = func(ctx context.Context) (int, error) { return r.tagPostCount(ctx, tag) }
+	}
+	return r.paginate(ctx, args.First, args.After, func(after models.PostCursor, perPage int) ([]*models.Post, int, error) {
+		return r.posts.Paginate(ctx, after, perPage, tags)
+	}, count)
+}
+
+// User returns a user by their id or username
+func (r *Resolver) User(ctx context.Context, args struct {
+	ID       *graphql.ID
+	Username *string
+}) (*userResolver, error) {
+	if err := addComplexity(ctx, 1); err != nil {
+		return nil, err
+	}
+	var user *models.User
+	var err error
+	switch {
+	case args.ID != nil:
+		user, err = r.users.FindByID(ctx, string(*args.ID))
+		if err != nil {
+			return nil, nil
+		}
+	case args.Username != nil:
+		user, err = r.users.FindByUsername(ctx, *args.Username)
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not fetch user", "err", err)
+			return nil, errors.New("Could not fetch user")
+		}
+		if user.Username == "" {
+			return nil, nil
+		}
+	default:
+		return nil, errors.New("id or username is required")
+	}
+	return &userResolver{r, user}, nil
+}
+
+// Users returns all users
+func (r *Resolver) Users(ctx context.Context) ([]*userResolver, error) {
+	users, err := r.users.GetAll(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not fetch users", "err", err)
+		return nil, errors.New("Could not fetch users")
+	}
+	if err := addComplexity(ctx, 1+len(users)); err != nil {
+		return nil, err
+	}
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		resolvers[i] = &userResolver{r, user}
+	}
+	return resolvers, nil
+}
+
+// Tag returns a tag of a public post
+func (r *Resolver) Tag(ctx context.Context, args struct{ Name string }) (*tagResolver, error) {
+	if err := addComplexity(ctx, 1); err != nil {
+		return nil, err
+	}
+	tag, err := r.tags.FindByName(ctx, args.Name, false)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not fetch tag", "err", err)
+		return nil, errors.New("Could not fetch tag")
+	}
+	return &tagResolver{r, tag}, nil
+}
+
+// Tags returns the tags of public posts ordered by their post count
+func (r *Resolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	tags, err := r.tags.GetAll(ctx, false)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not fetch tags", "err", err)
+		return nil, errors.New("Could not fetch tags")
+	}
+	if err := addComplexity(ctx, 1+len(tags)); err != nil {
+		return nil, err
+	}
+	resolvers := make([]*tagResolver, len(tags))
+	for i, tag := range tags {
+		resolvers[i] = &tagResolver{r, tag}
+	}
+	return resolvers, nil
+}
+
+// CreatePost creates a post as the current user
+func (r *Resolver) CreatePost(ctx context.Context, args struct{ Input postInput }) (*postResolver, error) {
+	if _, err := services.UserIDFromContext(ctx); err != nil {
+		return nil, errors.New("Authentication required")
+	}
+	post, err := r.mutator.CreatePost(ctx, args.Input.values())
+	if err != nil {
+		return nil, err
+	}
+	return &postResolver{post, newPostBatch(r, []*models.Post{post})}, nil
+}
+
+// UpdatePost updates a post the current user can edit
+func (r *Resolver) UpdatePost(ctx context.Context, args struct {
+	ID      graphql.ID
+	Version int32
+	Input   postInput
+}) (*postResolver, error) {
+	if _, err := services.UserIDFromContext(ctx); err != nil {
+		return nil, errors.New("Authentication required")
+	}
+	id, err := strconv.Atoi(string(args.ID))
+	if err != nil {
+		return nil, errors.New("Invalid post id")
+	}
+	post, err := r.mutator.UpdatePost(ctx, id, int(args.Version), args.Input.values())
+	if err != nil {
+		return nil, err
+	}
+	return &postResolver{post, newPostBatch(r, []*models.Post{post})}, nil
+}
+
+// Returns the number of public posts with the tag
+func (r *Resolver) tagPostCount(ctx context.Context, name string) (int, error) {
+	tag, err := r.tags.FindByName(ctx, name, false)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return tag.PostCount, nil
+}
+
+// Builds a connection from a keyset page. One more post than requested is fetched to know if there is a next page.
+func (r *Resolver) paginate(ctx context.Context, first *int32, after *string, page func(after models.PostCursor, perPage int) ([]*models.Post, int, error), count func(context.Context) (int, error)) (*connectionResolver, error) {
+	settings := r.settings.Get()
+	perPage := settings.PostsPerPage
+	if first != nil {
+		perPage = int(*first)
+		if perPage < settings.MinPostsPerPage || perPage > settings.MaxPostsPerPage {
+			return nil, fmt.Errorf("first is not within bounds [%v, %v]", settings.MinPostsPerPage, settings.MaxPostsPerPage)
+		}
+	}
+
+	if err := addComplexity(ctx, 1+perPage); err != nil {
+		return nil, err
+	}
+
+	var cursor models.PostCursor
+	if after != nil {
+		var err error
+		cursor, err = decodeCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	posts, _, err := page(cursor, perPage+1)
+	if err != nil && err != pgx.ErrNoRows {
+		slog.ErrorContext(ctx, "Could not fetch posts", "err", err)
+		return nil, errors.New("Could not fetch posts")
+	}
+
+	hasNextPage := len(posts) > perPage
+	if hasNextPage {
+		posts = posts[:perPage]
+	}
+	return &connectionResolver{posts, newPostBatch(r, posts), hasNextPage, count}, nil
+}
+
+// Cursors are opaque to clients but are the creation time and ID of the post
+func encodeCursor(post *models.Post) string {
+	return base64.StdEncoding.EncodeToString([]byte("post:" + post.Cursor().String()))
+}
+
+func decodeCursor(cursor string) (models.PostCursor, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), "post:") {
+		return models.PostCursor{}, errors.New("Invalid cursor")
+	}
+	c, err := models.ParsePostCursor(strings.TrimPrefix(string(b), "post:"))
+	if err != nil {
+		return models.PostCursor{}, errors.New("Invalid cursor")
+	}
+	return c, nil
+}
+
+type postInput struct {
+	Title         string
+	Subtitle      string
+	Body          string
+	Tags          []string
+	Hidden        *bool
+	FeatureImgURL *string
+	Excerpt       *string
+	Slug          *string
+	SlugPinned    *bool
+	Authors       *[]*contributorInput
+}
+
+type contributorInput struct {
+	ID   graphql.ID
+	Role *string
+}
+
+// Returns the input as the JSON body of the posts endpoints
+func (in *postInput) values() map[string]interface{} {
+	tags := make([]interface{}, len(in.Tags))
+	for i, tag := range in.Tags {
+		tags[i] = tag
+	}
+	values := map[string]interface{}{
+		"title":    in.Title,
+		"subtitle": in.Subtitle,
+		"body":     in.Body,
+		"tags":     tags,
+	}
+	if in.Hidden != nil {
+		values["hidden"] = *in.Hidden
+	}
+	if in.FeatureImgURL != nil {
+		values["featureImgUrl"] = *in.FeatureImgURL
+	}
+	if in.Excerpt != nil {
+		values["excerpt"] = *in.Excerpt
+	}
+	if in.Slug != nil {
+		values["slug"] = *in.Slug
+	}
+	if in.SlugPinned != nil {
+		values["slugPinned"] = *in.SlugPinned
+	}
+	if in.Authors != nil {
+		authors := make([]interface{}, len(*in.Authors))
+		for i, author := range *in.Authors {
+			entry := map[string]interface{}{"id": string(author.ID)}
+			if author.Role != nil {
+				entry["role"] = *author.Role
+			}
+			authors[i] = entry
+		}
+		values["authors"] = authors
+	}
+	return values
+}
+
+type postResolver struct {
+	p     *models.Post
+	batch *postBatch
+}
+
+func (r *postResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(r.p.ID))
+}
+
+func (r *postResolver) Title() string {
+	return r.p.Title
+}
+
+func (r *postResolver) Subtitle() string {
+	return r.p.Subtitle
+}
+
+func (r *postResolver) Slug() string {
+	return r.p.Slug
+}
+
+func (r *postResolver) Body() *string {
+	return optionalString(r.p.Body)
+}
+
+func (r *postResolver) BodyHTML() *string {
+	return optionalString(r.p.BodyHTML)
+}
+
+func (r *postResolver) Excerpt() string {
+	return r.p.Excerpt
+}
+
+func (r *postResolver) WordCount() int32 {
+	return int32(r.p.WordCount)
+}
+
+func (r *postResolver) ReadingTime() int32 {
+	return int32(r.p.ReadingTime)
+}
+
+func (r *postResolver) Views() int32 {
+	return int32(r.p.Views)
+}
+
+func (r *postResolver) FeatureImgURL() string {
+	return r.p.FeatureImgURL
+}
+
+func (r *postResolver) Hidden() bool {
+	return r.p.Hidden
+}
+
+func (r *postResolver) Version() int32 {
+	return int32(r.p.Version)
+}
+
+func (r *postResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.p.CreatedAt}
+}
+
+func (r *postResolver) UpdatedAt() *graphql.Time {
+	if value, _ := r.p.UpdatedAt.Value(); value == nil {
+		return nil
+	}
+	return &graphql.Time{Time: r.p.UpdatedAt.Time}
+}
+
+func (r *postResolver) Author(ctx context.Context) (*userResolver, error) {
+	user, err := r.batch.author(ctx, r.p)
+	if err != nil {
+		return nil, errors.New("Could not fetch author")
+	}
+	if user == nil {
+		return nil, nil
+	}
+	return &userResolver{r.batch.r, user}, nil
+}
+
+func (r *postResolver) Contributors(ctx context.Context) ([]*contributorResolver, error) {
+	contributors, err := r.batch.contributors(ctx, r.p)
+	if err != nil {
+		return nil, errors.New("Could not fetch contributors")
+	}
+	return contributors, nil
+}
+
+func (r *postResolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	tags, err := loaderFromContext(ctx).getTags(ctx, r.batch.r)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not fetch tags", "err", err)
+		return nil, errors.New("Could not fetch tags")
+	}
+	resolvers := make([]*tagResolver, len(r.p.Tags))
+	for i, name := range r.p.Tags {
+		tag, ok := tags[name]
+		if !ok {
+			// Only used by hidden posts
+			tag = &models.Tag{Name: name, DisplayName: name}
+		}
+		resolvers[i] = &tagResolver{r.batch.r, tag}
+	}
+	return resolvers, nil
+}
+
+type contributorResolver struct {
+	user *userResolver
+	role string
+}
+
+func (r *contributorResolver) User() *userResolver {
+	return r.user
+}
+
+func (r *contributorResolver) Role() string {
+	return r.role
+}
+
+// Only resolves the public fields of a user
+type userResolver struct {
+	r *Resolver
+	u *models.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(r.u.ID.String())
+}
+
+func (r *userResolver) Name() string {
+	return r.u.Name
+}
+
+func (r *userResolver) Username() string {
+	return r.u.Username
+}
+
+func (r *userResolver) Bio() string {
+	return r.u.Bio
+}
+
+func (r *userResolver) AvatarURL() string {
+	return r.u.AvatarURL
+}
+
+func (r *userResolver) Website() string {
+	return r.u.Website
+}
+
+func (r *userResolver) SocialHandles() []*socialHandleResolver {
+	networks := make([]string, 0, len(r.u.SocialHandles))
+	for network := range r.u.SocialHandles {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+
+	handles := make([]*socialHandleResolver, len(networks))
+	for i, network := range networks {
+		handles[i] = &socialHandleResolver{network, r.u.SocialHandles[network]}
+	}
+	return handles
+}
+
+func (r *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.u.CreatedAt}
+}
+
+func (r *userResolver) Posts(ctx context.Context, args struct {
+	First *int32
+	After *string
+}) (*connectionResolver, error) {
+	id := r.u.ID.String()
+	return r.r.paginate(ctx, args.First, args.After, func(after models.PostCursor, perPage int) ([]*models.Post, int, error) {
+		return r.r.posts.PaginateByAuthor(ctx, after, perPage, id)
+	}, func(ctx context.Context) (int, error) {
+		return r.r.posts.GetAuthorPostCount(ctx, id)
+	})
+}
+
+type socialHandleResolver struct {
+	network string
+	handle  string
+}
+
+func (r *socialHandleResolver) Network() string {
+	return r.network
+}
+
+func (r *socialHandleResolver) Handle() string {
+	return r.handle
+}
+
+type tagResolver struct {
+	r *Resolver
+	t *models.Tag
+}
+
+func (r *tagResolver) Name() string {
+	return r.t.Name
+}
+
+func (r *tagResolver) DisplayName() string {
+	return r.t.DisplayName
+}
+
+func (r *tagResolver) Description() string {
+	return r.t.Description
+}
+
+func (r *tagResolver) Color() string {
+	return r.t.Color
+}
+
+func (r *tagResolver) CoverImageURL() string {
+	return r.t.CoverImageURL
+}
+
+func (r *tagResolver) PostCount() int32 {
+	return int32(r.t.PostCount)
+}
+
+func (r *tagResolver) Posts(ctx context.Context, args struct {
+	First *int32
+	After *string
+}) (*connectionResolver, error) {
+	tags := []string{r.t.Name}
+	return r.r.paginate(ctx, args.First, args.After, func(after models.PostCursor, perPage int) ([]*models.Post, int, error) {
+		return r.r.posts.Paginate(ctx, after, perPage, tags)
+	}, func(context.Context) (int, error) {
+		return r.t.PostCount, nil
+	})
+}
+
+type connectionResolver struct {
+	posts       []*models.Post
+	batch       *postBatch
+	hasNextPage bool
+	count       func(context.Context) (int, error)
+}
+
+func (r *connectionResolver) Edges() []*edgeResolver {
+	edges := make([]*edgeResolver, len(r.posts))
+	for i, post := range r.posts {
+		edges[i] = &edgeResolver{&postResolver{post, r.batch}}
+	}
+	return edges
+}
+
+func (r *connectionResolver) PageInfo() *pageInfoResolver {
+	var endCursor *string
+	if len(r.posts) > 0 {
+		cursor := encodeCursor(r.posts[len(r.posts)-1])
+		endCursor = &cursor
+	}
+	return &pageInfoResolver{r.hasNextPage, endCursor}
+}
+
+func (r *connectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	total, err := r.count(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not count posts", "err", err)
+		return 0, errors.New("Could not count posts")
+	}
+	return int32(total), nil
+}
+
+type edgeResolver struct {
+	node *postResolver
+}
+
+func (r *edgeResolver) Cursor() string {
+	return encodeCursor(r.node.p)
+}
+
+func (r *edgeResolver) Node() *postResolver {
+	return r.node
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.hasNextPage
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+// Returns nil for empty strings
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
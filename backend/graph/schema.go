@@ -0,0 +1,165 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// MaxDepth is the deepest a query may nest its selections
+const MaxDepth = 10
+
+// MaxComplexity is the most a query may cost, see addComplexity
+const MaxComplexity = 1000
+
+// Schema is the GraphQL schema over the public posts, users and tags
+type Schema struct {
+	schema *graphql.Schema
+}
+
+// Request is a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewSchema parses the schema with resolvers backed by the given repositories.
+// Mutations are done by the mutator so they are validated like the REST endpoints.
+func NewSchema(pr repositories.PostRepository, ur repositories.UserRepository, tr repositories.TagRepository, settings *services.SettingsService, mutator PostMutator) (*Schema, error) {
+	resolver := &Resolver{pr, ur, tr, settings, mutator}
+	schema, err := graphql.ParseSchema(schemaString, resolver, graphql.MaxDepth(MaxDepth), graphql.UseStringDescriptions())
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema}, nil
+}
+
+// Exec executes the request. The fields resolved once it is too complex return an error instead.
+func (s *Schema) Exec(ctx context.Context, req *Request) *graphql.Response {
+	return s.schema.Exec(withComplexity(withLoader(ctx)), req.Query, req.OperationName, req.Variables)
+}
+
+const schemaString = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+"A point in time formatted as RFC 3339"
+scalar Time
+
+type Query {
+	"A public post by its id or slug"
+	post(id: ID, slug: String): Post
+	"Public posts, newest first, optionally with the given tag"
+	posts(first: Int, after: String, tag: String): PostConnection!
+	"A user by their id or username"
+	user(id: ID, username: String): User
+	users: [User!]!
+	"A tag of a public post"
+	tag(name: String!): Tag
+	"Tags ordered by their number of posts"
+	tags: [Tag!]!
+}
+
+"Mutations need a bearer token and are validated like the posts endpoints"
+type Mutation {
+	createPost(input: PostInput!): Post!
+	"Updates the post if it is still at the given version"
+	updatePost(id: ID!, version: Int!, input: PostInput!): Post!
+}
+
+input PostInput {
+	title: String!
+	subtitle: String!
+	body: String!
+	tags: [String!]!
+	hidden: Boolean
+	featureImgUrl: String
+	excerpt: String
+	"Admins can set a custom slug"
+	slug: String
+	slugPinned: Boolean
+	authors: [ContributorInput!]
+}
+
+input ContributorInput {
+	id: ID!
+	"author, editor or illustrator"
+	role: String
+}
+
+type Post {
+	id: ID!
+	title: String!
+	subtitle: String!
+	slug: String!
+	"Only returned for a single post"
+	body: String
+	"Only returned for a single post"
+	bodyHtml: String
+	excerpt: String!
+	wordCount: Int!
+	readingTime: Int!
+	views: Int!
+	featureImgUrl: String!
+	hidden: Boolean!
+	version: Int!
+	createdAt: Time!
+	updatedAt: Time
+	author: User
+	contributors: [Contributor!]!
+	tags: [Tag!]!
+}
+
+type Contributor {
+	user: User!
+	role: String!
+}
+
+type User {
+	id: ID!
+	name: String!
+	username: String!
+	bio: String!
+	avatarUrl: String!
+	website: String!
+	socialHandles: [SocialHandle!]!
+	createdAt: Time!
+	posts(first: Int, after: String): PostConnection!
+}
+
+type SocialHandle {
+	network: String!
+	handle: String!
+}
+
+type Tag {
+	name: String!
+	displayName: String!
+	description: String!
+	color: String!
+	coverImageUrl: String!
+	postCount: Int!
+	posts(first: Int, after: String): PostConnection!
+}
+
+type PostConnection {
+	edges: [PostEdge!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type PostEdge {
+	cursor: String!
+	node: Post!
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+`
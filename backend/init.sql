@@ -14,7 +14,16 @@ create table post_schema.post
 	authorid uuid not null,
 	feature_image_url text default '/assets/images/default-image.png' not null,
 	subtitle text default '' not null,
-	views integer default 0 not null
+	views integer default 0 not null,
+	slug_pinned boolean default false not null,
+	body_html text default '' not null,
+	toc jsonb default '[]' not null,
+	excerpt text default '' not null,
+	custom_excerpt boolean default false not null,
+	word_count integer default 0 not null,
+	reading_time integer default 0 not null,
+	deleted_at timestamptz default null,
+	version integer default 1 not null
 );
 
 create unique index post_id_uindex
@@ -42,7 +51,12 @@ create table user_schema."user"
 	admin boolean default false not null,
 	created_at timestamptz not null,
 	updated_at timestamptz default null,
-	username text not null
+	username text not null,
+	bio text default '' not null,
+	avatar_url text default '' not null,
+	website text default '' not null,
+	social_handles jsonb default '{}' not null,
+	deleted_at timestamptz default null
 );
 
 create unique index user_id_uindex
@@ -54,3 +68,194 @@ create unique index user_username_uindex
 alter table user_schema."user"
 	add constraint user_pk
 		primary key (id);
+
+create table post_schema.series
+(
+	id integer not null,
+	title text not null,
+	slug text not null,
+	description text default '' not null,
+	created_at timestamptz not null,
+	updated_at timestamptz default null
+);
+
+create unique index series_id_uindex
+	on post_schema.series (id);
+
+create unique index series_slug_uindex
+	on post_schema.series (slug);
+
+alter table post_schema.series
+	add constraint series_pk
+		primary key (id);
+
+create sequence post_schema.series_id_seq;
+
+alter table post_schema.series alter column id set default nextval('post_schema.series_id_seq');
+
+alter sequence post_schema.series_id_seq owned by post_schema.series.id;
+
+create table post_schema.series_post
+(
+	series_id integer not null
+		references post_schema.series (id) on delete cascade,
+	post_id integer not null
+		references post_schema.post (id) on delete cascade,
+	position integer not null
+);
+
+create unique index series_post_post_id_uindex
+	on post_schema.series_post (post_id);
+
+create unique index series_post_position_uindex
+	on post_schema.series_post (series_id, position);
+
+alter table post_schema.series_post
+	add constraint series_post_pk
+		primary key (series_id, post_id);
+
+create table post_schema.tag
+(
+	name text not null,
+	display_name text default '' not null,
+	description text default '' not null,
+	color text default '' not null,
+	cover_image_url text default '' not null,
+	updated_at timestamptz default null
+);
+
+create unique index tag_name_uindex
+	on post_schema.tag (name);
+
+alter table post_schema.tag
+	add constraint tag_pk
+		primary key (name);
+
+create table post_schema.post_slug_history
+(
+	slug text not null,
+	post_id integer not null
+		references post_schema.post (id) on delete cascade,
+	created_at timestamptz not null
+);
+
+create unique index post_slug_history_slug_uindex
+	on post_schema.post_slug_history (slug);
+
+create index post_slug_history_post_id_index
+	on post_schema.post_slug_history (post_id);
+
+alter table post_schema.post_slug_history
+	add constraint post_slug_history_pk
+		primary key (slug);
+
+create table post_schema.page
+(
+	id integer not null,
+	title text not null,
+	slug text not null,
+	body text default '' not null,
+	body_html text default '' not null,
+	toc jsonb default '[]' not null,
+	parent_id integer default null
+		references post_schema.page (id) on delete set null,
+	sort_order integer default 0 not null,
+	template text default '' not null,
+	hidden boolean default true not null,
+	authorid uuid not null,
+	created_at timestamptz not null,
+	updated_at timestamptz default null
+);
+
+create unique index page_id_uindex
+	on post_schema.page (id);
+
+create unique index page_slug_uindex
+	on post_schema.page (slug);
+
+create index page_parent_id_index
+	on post_schema.page (parent_id);
+
+alter table post_schema.page
+	add constraint page_pk
+		primary key (id);
+
+create sequence post_schema.page_id_seq;
+
+alter table post_schema.page alter column id set default nextval('post_schema.page_id_seq');
+
+alter sequence post_schema.page_id_seq owned by post_schema.page.id;
+
+create table post_schema.site_settings
+(
+	key text not null,
+	value jsonb not null,
+	updated_at timestamptz not null
+);
+
+create unique index site_settings_key_uindex
+	on post_schema.site_settings (key);
+
+alter table post_schema.site_settings
+	add constraint site_settings_pk
+		primary key (key);
+
+create table post_schema.menu
+(
+	name text not null,
+	items jsonb default '[]' not null,
+	updated_at timestamptz default null
+);
+
+create unique index menu_name_uindex
+	on post_schema.menu (name);
+
+alter table post_schema.menu
+	add constraint menu_pk
+		primary key (name);
+
+create table post_schema.post_author
+(
+	post_id integer not null
+		references post_schema.post (id) on delete cascade,
+	user_id uuid not null
+		references user_schema."user" (id) on delete cascade,
+	role text default 'author' not null,
+	position integer not null
+);
+
+create unique index post_author_position_uindex
+	on post_schema.post_author (post_id, position);
+
+create index post_author_user_id_index
+	on post_schema.post_author (user_id);
+
+alter table post_schema.post_author
+	add constraint post_author_pk
+		primary key (post_id, user_id);
+
+create table post_schema.schema_migration
+(
+	version integer not null,
+	name text not null,
+	applied_at timestamptz default now() not null
+);
+
+alter table post_schema.schema_migration
+	add constraint schema_migration_pk
+		primary key (version);
+
+-- Migrations the schema above already includes
+insert into post_schema.schema_migration (version, name) values
+	(1, '0001_series'),
+	(2, '0002_tags'),
+	(3, '0003_slug_history'),
+	(4, '0004_rendered_bodies'),
+	(5, '0005_excerpts'),
+	(6, '0006_pages'),
+	(7, '0007_site_settings'),
+	(8, '0008_menus'),
+	(9, '0009_author_profiles'),
+	(10, '0010_post_authors'),
+	(11, '0011_soft_delete'),
+	(12, '0012_post_versions');
@@ -1,11 +1,14 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"os"
 
 	"github.com/alanqchen/Bear-Post/backend/app"
 	"github.com/alanqchen/Bear-Post/backend/config"
+	"github.com/alanqchen/Bear-Post/backend/logging"
 	"github.com/alanqchen/Bear-Post/backend/routes"
 )
 
@@ -24,36 +27,51 @@ import (
  */
 
 func main() {
-	log.Println("Starting up API...")
-	var cfg config.Config
-	var err error
-	log.Println("Looking for a config file")
-
-	if _, err := os.Stat("config/app-custom.json"); !os.IsNotExist(err) {
-		log.Println("Using config/app-custom.json")
-		cfg, err = config.New("config/app-custom.json")
-	} else if _, err := os.Stat("config/app.json"); !os.IsNotExist(err) {
-		log.Println("Using config/app.json")
-		cfg, err = config.New("config/app.json")
-	} else if _, err := os.Stat("../app.json"); !os.IsNotExist(err) {
-		log.Println("Using ../app.json")
-		cfg, err = config.New("../app.json")
-	} else if _, err := os.Stat("config/app-docker.json"); !os.IsNotExist(err) {
-		log.Println("Using config/app-docker.json")
-		cfg, err = config.New("config/app-docker.json")
-	} else {
-		log.Fatal("[FATAL] Failed to find config/app-custom.json or config/app.json or config/app-docker or ../app.json")
-	}
-	if err != nil {
-		log.Fatal("Failed to create config:", err)
+	cfg, args := loadConfig()
+
+	// Subcommands run against the database and exit without starting the API
+	if len(args) > 0 {
+		os.Exit(runCommand(cfg, args[0], args[1:]))
 	}
 
-	log.Println("Creating api")
+	slog.Info("Starting up API...")
+	slog.Info("Creating api")
 
 	app := app.New(cfg)
-	defer app.Database.Close()
-	log.Println("Creating routes")
+	slog.Info("Creating routes")
 	router := routes.NewRouter(app)
-	log.Println("Running api...")
+	slog.Info("Running api...")
 	app.Run(router)
 }
+
+// Loads the config from the defaults, the config file, the environment and the flags, checks it
+// and sets up logging with it. Returns the config and the arguments after the flags.
+func loadConfig() (config.Config, []string) {
+	cfg, path, args, err := config.Load(os.Args[1:])
+	var invalid *config.ValidationError
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if errors.As(err, &invalid) {
+		logging.Fatal("Invalid config", "problems", invalid.Problems)
+	} else if err != nil {
+		logging.Fatal("Failed to load config", "err", err)
+	}
+
+	// config print shows the problems itself
+	if len(args) == 0 || args[0] != "config" {
+		if err := cfg.Validate(); err != nil {
+			logging.Fatal("Invalid config", "problems", err.(*config.ValidationError).Problems)
+		}
+	}
+
+	if err := logging.Setup(cfg.Log); err != nil {
+		logging.Fatal("Invalid log config", "err", err)
+	}
+	if path != "" {
+		slog.Info("Loaded config", "file", path)
+	} else {
+		slog.Info("Loaded config without a file", "searched", config.SearchPaths)
+	}
+
+	return cfg, args
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bearpost"
+
+// Route label of requests that didn't match a route
+const unmatchedRoute = "unmatched"
+
+// Registry holds the metrics of the API and the Go runtime
+var Registry = prometheus.NewRegistry()
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Requests served by route template, method and status.",
+	}, []string{"route", "method", "status"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve requests by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Redis cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+	uploadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes of uploaded media saved by kind (image or video).",
+	}, []string{"kind"})
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result (success or failure).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		requests,
+		requestDuration,
+		cacheLookups,
+		uploadBytes,
+		logins,
+	)
+}
+
+// Methods used as a label as is, others are counted as OTHER
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// ObserveRequest counts a served request and its duration. The route is the template of the
+// matched route, or "" if none matched.
+func ObserveRequest(route string, method string, status int, duration time.Duration) {
+	if route == "" {
+		route = unmatchedRoute
+	}
+	if !knownMethods[method] {
+		method = "OTHER"
+	}
+	code := strconv.Itoa(status)
+	requests.WithLabelValues(route, method, code).Inc()
+	requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// CacheLookup counts a lookup in the named cache
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// UploadBytes counts the bytes of a saved upload of the given kind
+func UploadBytes(kind string, n int) {
+	uploadBytes.WithLabelValues(kind).Add(float64(n))
+}
+
+// Login counts a login attempt
+func Login(success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	logins.WithLabelValues(result).Inc()
+}
+
+// RegisterPools adds the stats of the Postgres and Redis connection pools, read on each scrape
+func RegisterPools(db *database.Postgres, redis *database.Redis) error {
+	return Registry.Register(&poolCollector{db, redis})
+}
+
+// Handler serves the metrics in the Prometheus text format. If token isn't empty, requests need
+// it as a bearer token.
+func Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pgConns = prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", "connections"),
+		"Connections of the Postgres pool by state (acquired, idle or constructing).", []string{"state"}, nil)
+	pgMaxConns = prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", "max_connections"),
+		"Maximum size of the Postgres pool.", nil, nil)
+	pgAcquires = prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", "acquires_total"),
+		"Connections acquired from the Postgres pool.", nil, nil)
+	pgEmptyAcquires = prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", "empty_acquires_total"),
+		"Acquires that waited for a connection because the Postgres pool was empty.", nil, nil)
+	pgCanceledAcquires = prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", "canceled_acquires_total"),
+		"Acquires from the Postgres pool that were canceled.", nil, nil)
+	pgAcquireSeconds = prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", "acquire_seconds_total"),
+		"Total time spent acquiring connections from the Postgres pool.", nil, nil)
+
+	redisConns = prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "connections"),
+		"Connections of the Redis pool by state (total or idle).", []string{"state"}, nil)
+	redisHits = prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "hits_total"),
+		"Times a free connection was found in the Redis pool.", nil, nil)
+	redisMisses = prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "misses_total"),
+		"Times a free connection was not found in the Redis pool.", nil, nil)
+	redisTimeouts = prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "timeouts_total"),
+		"Times waiting for a Redis pool connection timed out.", nil, nil)
+	redisStaleConns = prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", "stale_connections_total"),
+		"Stale connections removed from the Redis pool.", nil, nil)
+)
+
+// Collects the stats of the connection pools
+type poolCollector struct {
+	db    *database.Postgres
+	redis *database.Redis
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{pgConns, pgMaxConns, pgAcquires, pgEmptyAcquires, pgCanceledAcquires,
+		pgAcquireSeconds, redisConns, redisHits, redisMisses, redisTimeouts, redisStaleConns} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.db != nil {
+		stat := c.db.Stat()
+		ch <- prometheus.MustNewConstMetric(pgConns, prometheus.GaugeValue, float64(stat.AcquiredConns()), "acquired")
+		ch <- prometheus.MustNewConstMetric(pgConns, prometheus.GaugeValue, float64(stat.IdleConns()), "idle")
+		ch <- prometheus.MustNewConstMetric(pgConns, prometheus.GaugeValue, float64(stat.ConstructingConns()), "constructing")
+		ch <- prometheus.MustNewConstMetric(pgMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+		ch <- prometheus.MustNewConstMetric(pgAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+		ch <- prometheus.MustNewConstMetric(pgEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+		ch <- prometheus.MustNewConstMetric(pgCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+		ch <- prometheus.MustNewConstMetric(pgAcquireSeconds, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	}
+
+	if c.redis != nil {
+		stats := c.redis.PoolStats()
+		ch <- prometheus.MustNewConstMetric(redisConns, prometheus.GaugeValue, float64(stats.TotalConns), "total")
+		ch <- prometheus.MustNewConstMetric(redisConns, prometheus.GaugeValue, float64(stats.IdleConns), "idle")
+		ch <- prometheus.MustNewConstMetric(redisHits, prometheus.CounterValue, float64(stats.Hits))
+		ch <- prometheus.MustNewConstMetric(redisMisses, prometheus.CounterValue, float64(stats.Misses))
+		ch <- prometheus.MustNewConstMetric(redisTimeouts, prometheus.CounterValue, float64(stats.Timeouts))
+		ch <- prometheus.MustNewConstMetric(redisStaleConns, prometheus.CounterValue, float64(stats.StaleConns))
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 
@@ -24,7 +24,7 @@ func RequireAuthentication(a *app.App, next http.HandlerFunc, admin bool) http.H
 		t, err := request.ParseFromRequest(r, request.AuthorizationHeaderExtractor,
 			func(token *jwt.Token) (interface{}, error) {
 				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					log.Println("[BAD AUTH] Unexpected signing method:", token.Header["alg"])
+					slog.WarnContext(r.Context(), "Authentication failed", "reason", "Unexpected signing method", "alg", token.Header["alg"])
 					return nil, fmt.Errorf("[ERROR] Unexpected signing method: %v", token.Header["alg"])
 				}
 
@@ -33,11 +33,11 @@ func RequireAuthentication(a *app.App, next http.HandlerFunc, admin bool) http.H
 
 		if err != nil {
 			if err == request.ErrNoTokenInRequest {
-				log.Println("[BAD AUTH] Missing token")
+				slog.WarnContext(r.Context(), "Authentication failed", "reason", "Missing token")
 				controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Missing token", Status: http.StatusUnauthorized}, w)
 				return
 			}
-			log.Println("[BAD AUTH] Invalid token")
+			slog.WarnContext(r.Context(), "Authentication failed", "reason", "Invalid token")
 			controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Invalid token", Status: http.StatusUnauthorized}, w)
 			return
 		}
@@ -45,19 +45,19 @@ func RequireAuthentication(a *app.App, next http.HandlerFunc, admin bool) http.H
 		if claims, ok := t.Claims.(jwt.MapClaims); ok && t.Valid {
 			jti, ok := claims["jti"].(string)
 			if !ok {
-				log.Println("[BAD AUTH] Bad jti type")
+				slog.WarnContext(r.Context(), "Authentication failed", "reason", "Bad jti type")
 				controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Bad jti type", Status: http.StatusBadRequest}, w)
 				return
 			}
 			tokenHash, ok := claims["tokenHash"].(string)
 			if !ok {
-				log.Println("[BAD AUTH] Bad token hash type")
+				slog.WarnContext(r.Context(), "Authentication failed", "reason", "Bad token hash type")
 				controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Bad token hash type", Status: http.StatusBadRequest}, w)
 				return
 			}
 			val, err := a.Redis.Get(tokenHash + "." + jti).Result()
 			if err != nil || val == "" {
-				log.Println("[BAD AUTH] Invalid token v2")
+				slog.WarnContext(r.Context(), "Authentication failed", "reason", "Invalid token v2")
 				controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Invalid token", Status: http.StatusUnauthorized}, w)
 				return
 			}
@@ -72,7 +72,7 @@ func RequireAuthentication(a *app.App, next http.HandlerFunc, admin bool) http.H
 			ctx := services.ContextWithUser(r.Context(), user)*/
 			uid, ok := claims["id"].(string)
 			if !ok {
-				log.Println("[BAD AUTH] Bad id type")
+				slog.WarnContext(r.Context(), "Authentication failed", "reason", "Bad id type")
 				controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Bad id type", Status: http.StatusBadRequest}, w)
 				return
 			}
@@ -96,7 +96,7 @@ func RequireAuthentication(a *app.App, next http.HandlerFunc, admin bool) http.H
 			// Check if the user's token has admin true
 			isAdmin := claims["admin"].(bool)
 			if !isAdmin {
-				log.Println("[BAD AUTH] Not an admin - uid:", uid)
+				slog.WarnContext(r.Context(), "Authentication failed", "reason", "Not an admin", "uid", uid)
 				controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Admin required", Status: http.StatusForbidden}, w)
 				return
 			}
@@ -111,7 +111,7 @@ func RequireRefreshToken(a *app.App, next http.HandlerFunc) http.HandlerFunc {
 
 		publicKeyFile, err := os.Open(a.Config.JWT.PublicKey)
 		if err != nil {
-			log.Println(err)
+			slog.ErrorContext(r.Context(), "Failed to verify token", "err", err)
 			controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Failed to verify token", Status: http.StatusInternalServerError}, w)
 			return
 		}
@@ -123,7 +123,7 @@ func RequireRefreshToken(a *app.App, next http.HandlerFunc) http.HandlerFunc {
 		buffer := bufio.NewReader(publicKeyFile)
 		_, err = buffer.Read(pembytes)
 		if err != nil {
-			log.Println(err)
+			slog.ErrorContext(r.Context(), "Failed to verify token", "err", err)
 			controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Failed to verify token", Status: http.StatusInternalServerError}, w)
 			return
 		}
@@ -135,7 +135,7 @@ func RequireRefreshToken(a *app.App, next http.HandlerFunc) http.HandlerFunc {
 		publicKeyImported, err := x509.ParsePKIXPublicKey(data.Bytes)
 
 		if err != nil {
-			log.Println(err)
+			slog.ErrorContext(r.Context(), "Failed to verify token", "err", err)
 			controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Failed to verify token", Status: http.StatusInternalServerError}, w)
 			return
 		}
@@ -143,7 +143,7 @@ func RequireRefreshToken(a *app.App, next http.HandlerFunc) http.HandlerFunc {
 		rsaPub, ok := publicKeyImported.(*rsa.PublicKey)
 
 		if !ok {
-			log.Println("Failed to import public key")
+			slog.ErrorContext(r.Context(), "Failed to import public key")
 			controllers.NewAPIError(&controllers.APIError{Success: false, Message: "Failed to verify token", Status: http.StatusInternalServerError}, w)
 			return
 		}
@@ -194,3 +194,16 @@ func RequireRefreshToken(a *app.App, next http.HandlerFunc) http.HandlerFunc {
 		}
 	}
 }
+
+// OptionalAuthentication is the middleware function for routes that can be used with or without a bearer token.
+// Requests with a token are authenticated like RequireAuthentication.
+func OptionalAuthentication(a *app.App, next http.HandlerFunc) http.HandlerFunc {
+	authenticated := RequireAuthentication(a, next, false)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next(w, r)
+			return
+		}
+		authenticated(w, r)
+	}
+}
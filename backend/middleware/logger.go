@@ -1,23 +1,87 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"regexp"
 	"time"
 
+	"github.com/alanqchen/Bear-Post/backend/logging"
+	"github.com/alanqchen/Bear-Post/backend/metrics"
 	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
 )
 
-// Logger is the middleware function for logging requests
+// RequestIDHeader is the header of the ID of a request, kept if the client sends one
+const RequestIDHeader = "X-Request-ID"
+
+// Incoming request IDs are only kept if they can't break the log lines
+var requestIDRegex = regexp.MustCompile(`^[a-zA-Z0-9\-_.:]{1,128}$`)
+
+// Logger is the middleware function for logging requests. It gives each request an ID, sent back
+// in the X-Request-ID header and added to the lines logged with the request's context, and counts
+// the request in the metrics of its route.
 func Logger(next http.HandlerFunc) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		start := time.Now()
-		next(res, req)
-		ip := util.GetIP(req)
-		if ip != "" {
-			log.Printf("[%s] [%v] %q %v\n", req.Method, ip, req.URL.String(), time.Since(start))
-		} else {
-			log.Printf("[%s] [IP unknown] %q %v\n", req.Method, req.URL.String(), time.Since(start))
+
+		id := req.Header.Get(RequestIDHeader)
+		if !requestIDRegex.MatchString(id) {
+			id = uuid.Must(uuid.NewV4()).String()
 		}
+		res.Header().Set(RequestIDHeader, id)
+		ctx := logging.ContextWithRequestID(req.Context(), id)
+
+		w := &responseRecorder{ResponseWriter: res, status: http.StatusOK}
+		next(w, req.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if w.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		duration := time.Since(start)
+		route := ""
+		if current := mux.CurrentRoute(req); current != nil {
+			route, _ = current.GetPathTemplate()
+		}
+		metrics.ObserveRequest(route, req.Method, w.status, duration)
+
+		slog.Log(ctx, level, "Request",
+			"method", req.Method,
+			"url", req.URL.String(),
+			"ip", util.GetIP(req),
+			"status", w.status,
+			"size", w.size,
+			"durationMs", float64(duration.Microseconds())/1000,
+		)
 	}
 }
+
+// Records the status code and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the original writer
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
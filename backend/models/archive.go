@@ -0,0 +1,14 @@
+package models
+
+// ArchiveYear stores the number of posts published in a year broken down by month
+type ArchiveYear struct {
+	Year   int             `json:"year"`
+	Count  int             `json:"count"`
+	Months []*ArchiveMonth `json:"months"`
+}
+
+// ArchiveMonth stores the number of posts published in a month
+type ArchiveMonth struct {
+	Month int `json:"month"`
+	Count int `json:"count"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// AuthorSummary stores the author details embedded in a post
+type AuthorSummary struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatarUrl"`
+}
+
+// Roles of a contributor to a post
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleIllustrator = "illustrator"
+)
+
+// PostAuthor stores a contributor to a post and their role
+type PostAuthor struct {
+	AuthorSummary
+	Role string `json:"role"`
+}
+
+// AuthorProfile stores the public profile of an author
+type AuthorProfile struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+	Profile
+	PostCount int `json:"postCount"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Actions of a bulk post operation
+const (
+	BulkHide       = "hide"
+	BulkPublish    = "publish"
+	BulkAddTags    = "addTags"
+	BulkRemoveTags = "removeTags"
+	BulkSetAuthor  = "setAuthor"
+	BulkDelete     = "delete"
+)
+
+// PostFilter selects the posts of a bulk operation. Fields that are not set match every post.
+type PostFilter struct {
+	IDs      []int      `json:"ids,omitempty"`
+	Tag      string     `json:"tag,omitempty"`
+	AuthorID string     `json:"author,omitempty"`
+	From     *time.Time `json:"from,omitempty"`
+	To       *time.Time `json:"to,omitempty"`
+	Hidden   *bool      `json:"hidden,omitempty"`
+}
+
+// IsEmpty returns true if the filter matches every post
+func (f *PostFilter) IsEmpty() bool {
+	return f.IDs == nil && f.Tag == "" && f.AuthorID == "" && f.From == nil && f.To == nil && f.Hidden == nil
+}
+
+// BulkAction stores the action of a bulk post operation and its argument
+type BulkAction struct {
+	Action   string   `json:"action"`
+	Tags     []string `json:"tags,omitempty"`
+	AuthorID string   `json:"author,omitempty"`
+}
+
+// BulkResult stores the posts a bulk operation changed, or would change in a dry run
+type BulkResult struct {
+	Action string  `json:"action"`
+	DryRun bool    `json:"dryRun"`
+	Count  int     `json:"count"`
+	Posts  []*Post `json:"posts"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// ExportFormatVersion is the version of the export archive layout
+const ExportFormatVersion = 1
+
+// PostFrontMatter stores the YAML front matter of a post in an export archive
+type PostFrontMatter struct {
+	Title        string     `yaml:"title"`
+	Subtitle     string     `yaml:"subtitle,omitempty"`
+	Slug         string     `yaml:"slug"`
+	Tags         []string   `yaml:"tags"`
+	Hidden       bool       `yaml:"hidden"`
+	CreatedAt    time.Time  `yaml:"createdAt"`
+	UpdatedAt    *time.Time `yaml:"updatedAt,omitempty"`
+	Author       string     `yaml:"author,omitempty"`
+	FeatureImage string     `yaml:"featureImage,omitempty"`
+	Excerpt      string     `yaml:"excerpt,omitempty"`
+
+	// Every contributor in order, including the author
+	Contributors []*ExportedContributor `yaml:"contributors,omitempty"`
+}
+
+// ExportedContributor stores a contributor to a post in its front matter
+type ExportedContributor struct {
+	Username string `yaml:"username"`
+	Role     string `yaml:"role"`
+}
+
+// ExportManifest stores the contents of an export archive
+type ExportManifest struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Posts      []*ExportedPost `json:"posts"`
+	Users      string          `json:"users"`
+	Media      []string        `json:"media"`
+	Missing    []string        `json:"missingMedia"`
+}
+
+// ExportedPost stores where a post is in an export archive
+type ExportedPost struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	File string `json:"file"`
+}
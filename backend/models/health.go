@@ -0,0 +1,22 @@
+package models
+
+// Statuses of the API and of the dependencies it checks
+const (
+	HealthOK       = "ok"
+	HealthFail     = "fail"
+	HealthStarting = "starting"
+	HealthStopping = "stopping"
+)
+
+// HealthCheck stores the result of checking a dependency
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness stores if the API can serve requests, with the checks of its dependencies by name
+type Readiness struct {
+	Status string                  `json:"status"`
+	Checks map[string]*HealthCheck `json:"checks,omitempty"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// Formats the importer reads
+const (
+	ImportWXR      = "wxr"
+	ImportGhost    = "ghost"
+	ImportMarkdown = "markdown"
+)
+
+// Statuses of an imported post
+const (
+	ImportCreated     = "created"
+	ImportWouldCreate = "wouldCreate"
+	ImportSkipped     = "skipped"
+	ImportFailed      = "failed"
+)
+
+// ImportAuthor stores the author of an imported post as given by the source
+type ImportAuthor struct {
+	Username string
+	Name     string
+	Email    string
+}
+
+// ImportContributor stores a contributor to an imported post and their role
+type ImportContributor struct {
+	Author *ImportAuthor
+	Role   string
+}
+
+// ImportPost stores a post read from an import source before it's saved
+type ImportPost struct {
+	Title        string
+	Subtitle     string
+	Slug         string
+	Body         string
+	Excerpt      string
+	Tags         []string
+	Hidden       bool
+	CreatedAt    time.Time
+	Author       *ImportAuthor
+	FeatureImage string
+	// Directory or archive path that relative media paths are resolved against
+	MediaBase string
+	// Every contributor in order, including the author. Empty if the source only has the author.
+	Contributors []*ImportContributor
+}
+
+// ImportResult stores what happened to a single imported post
+type ImportResult struct {
+	ID     int    `json:"id,omitempty"`
+	Title  string `json:"title"`
+	Slug   string `json:"slug"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ImportReport stores the outcome of an import, or what would happen in a dry run
+type ImportReport struct {
+	Format   string          `json:"format"`
+	DryRun   bool            `json:"dryRun"`
+	Created  int             `json:"created"`
+	Skipped  int             `json:"skipped"`
+	Failed   int             `json:"failed"`
+	Posts    []*ImportResult `json:"posts"`
+	Users    []string        `json:"users"`
+	Tags     []string        `json:"tags"`
+	Media    []string        `json:"media"`
+	Warnings []string        `json:"warnings"`
+}
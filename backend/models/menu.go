@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Types of content a menu item can link to
+const (
+	MenuItemPost = "post"
+	MenuItemPage = "page"
+	MenuItemTag  = "tag"
+	MenuItemURL  = "url"
+)
+
+// Menu stores a named navigation menu such as the header or footer
+type Menu struct {
+	Name      string      `json:"name"`
+	Items     []*MenuItem `json:"items"`
+	UpdatedAt *time.Time  `json:"updatedAt"`
+}
+
+// MenuItem stores a link in a menu. Target is the slug of a post or page, the name of a tag or a URL.
+type MenuItem struct {
+	Label    string      `json:"label"`
+	Type     string      `json:"type"`
+	Target   string      `json:"target"`
+	Children []*MenuItem `json:"children,omitempty"`
+}
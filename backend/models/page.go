@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Page stores the data of a static page. Pages have top-level slugs and are not listed with posts.
+type Page struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Slug      string     `json:"slug"`
+	Body      string     `json:"body,omitempty"`
+	BodyHTML  string     `json:"bodyHtml,omitempty"`
+	TOC       []TOCEntry `json:"toc,omitempty"`
+	ParentID  *int       `json:"parentId"`
+	SortOrder int        `json:"sortOrder"`
+	Template  string     `json:"template"`
+	Hidden    bool       `json:"hidden"`
+	AuthorID  string     `json:"authorid"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+	Children  []*Page    `json:"children,omitempty"`
+}
@@ -2,6 +2,9 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgtype"
@@ -12,7 +15,13 @@ type Post struct {
 	ID            int                `json:"id"`
 	Title         string             `json:"title"`
 	Slug          string             `json:"slug"`
-	Body          string             `json:"body"`
+	Body          string             `json:"body,omitempty"`
+	BodyHTML      string             `json:"bodyHtml,omitempty"`
+	TOC           []TOCEntry         `json:"toc,omitempty"`
+	Excerpt       string             `json:"excerpt"`
+	CustomExcerpt bool               `json:"customExcerpt"`
+	WordCount     int                `json:"wordCount"`
+	ReadingTime   int                `json:"readingTime"`
 	CreatedAt     time.Time          `json:"createdAt"`
 	UpdatedAt     pgtype.Timestamptz `json:"updatedAt"`
 	Tags          []string           `json:"tags"`
@@ -21,6 +30,12 @@ type Post struct {
 	FeatureImgURL string             `json:"featureImgUrl"`
 	Subtitle      string             `json:"subtitle"`
 	Views         int                `json:"views"`
+	SlugPinned    bool               `json:"slugPinned"`
+	Version       int                `json:"version"`
+	Series        *SeriesNav         `json:"series,omitempty"`
+	Author        *AuthorSummary     `json:"author,omitempty"`
+	Authors       []*PostAuthor      `json:"authors,omitempty"`
+	DeletedAt     *time.Time         `json:"deletedAt,omitempty"`
 }
 
 // MarshalJSON marshals post data
@@ -31,7 +46,13 @@ func (p *Post) MarshalJSON() ([]byte, error) {
 			ID            int                 `json:"id"`
 			Title         string              `json:"title"`
 			Slug          string              `json:"slug"`
-			Body          string              `json:"body"`
+			Body          string              `json:"body,omitempty"`
+			BodyHTML      string              `json:"bodyHtml,omitempty"`
+			TOC           []TOCEntry          `json:"toc,omitempty"`
+			Excerpt       string              `json:"excerpt"`
+			CustomExcerpt bool                `json:"customExcerpt"`
+			WordCount     int                 `json:"wordCount"`
+			ReadingTime   int                 `json:"readingTime"`
 			CreatedAt     time.Time           `json:"createdAt"`
 			UpdatedAt     *pgtype.Timestamptz `json:"updatedAt"`
 			Tags          []string            `json:"tags"`
@@ -40,21 +61,81 @@ func (p *Post) MarshalJSON() ([]byte, error) {
 			FeatureImgURL string              `json:"featureImgUrl"`
 			Subtitle      string              `json:"subtitle"`
 			Views         int                 `json:"views"`
-		}{p.ID, p.Title, p.Slug, p.Body, p.CreatedAt, nil, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views})
+			SlugPinned    bool                `json:"slugPinned"`
+			Version       int                 `json:"version"`
+			Series        *SeriesNav          `json:"series,omitempty"`
+			Author        *AuthorSummary      `json:"author,omitempty"`
+			Authors       []*PostAuthor       `json:"authors,omitempty"`
+			DeletedAt     *time.Time          `json:"deletedAt,omitempty"`
+		}{p.ID, p.Title, p.Slug, p.Body, p.BodyHTML, p.TOC, p.Excerpt, p.CustomExcerpt, p.WordCount, p.ReadingTime, p.CreatedAt, nil, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views, p.SlugPinned, p.Version, p.Series, p.Author, p.Authors, p.DeletedAt})
 	}
 
 	return json.Marshal(struct {
-		ID            int       `json:"id"`
-		Title         string    `json:"title"`
-		Slug          string    `json:"slug"`
-		Body          string    `json:"body"`
-		CreatedAt     time.Time `json:"createdAt"`
-		UpdatedAt     time.Time `json:"updatedAt"`
-		Tags          []string  `json:"tags"`
-		Hidden        bool      `json:"hidden"`
-		AuthorID      string    `json:"authorid"`
-		FeatureImgURL string    `json:"featureImgUrl"`
-		Subtitle      string    `json:"subtitle"`
-		Views         int       `json:"views"`
-	}{p.ID, p.Title, p.Slug, p.Body, p.CreatedAt, p.UpdatedAt.Time, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views})
+		ID            int            `json:"id"`
+		Title         string         `json:"title"`
+		Slug          string         `json:"slug"`
+		Body          string         `json:"body,omitempty"`
+		BodyHTML      string         `json:"bodyHtml,omitempty"`
+		TOC           []TOCEntry     `json:"toc,omitempty"`
+		Excerpt       string         `json:"excerpt"`
+		CustomExcerpt bool           `json:"customExcerpt"`
+		WordCount     int            `json:"wordCount"`
+		ReadingTime   int            `json:"readingTime"`
+		CreatedAt     time.Time      `json:"createdAt"`
+		UpdatedAt     time.Time      `json:"updatedAt"`
+		Tags          []string       `json:"tags"`
+		Hidden        bool           `json:"hidden"`
+		AuthorID      string         `json:"authorid"`
+		FeatureImgURL string         `json:"featureImgUrl"`
+		Subtitle      string         `json:"subtitle"`
+		Views         int            `json:"views"`
+		SlugPinned    bool           `json:"slugPinned"`
+		Version       int            `json:"version"`
+		Series        *SeriesNav     `json:"series,omitempty"`
+		Author        *AuthorSummary `json:"author,omitempty"`
+		Authors       []*PostAuthor  `json:"authors,omitempty"`
+		DeletedAt     *time.Time     `json:"deletedAt,omitempty"`
+	}{p.ID, p.Title, p.Slug, p.Body, p.BodyHTML, p.TOC, p.Excerpt, p.CustomExcerpt, p.WordCount, p.ReadingTime, p.CreatedAt, p.UpdatedAt.Time, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views, p.SlugPinned, p.Version, p.Series, p.Author, p.Authors, p.DeletedAt})
+}
+
+// EditLock stores who has a post open in the editor. Locks are advisory and expire unless refreshed.
+type EditLock struct {
+	PostID    int            `json:"postId"`
+	User      *AuthorSummary `json:"user"`
+	LockedAt  time.Time      `json:"lockedAt"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+// PostCursor is the position of a post in the listings, which are ordered by creation time and
+// then by ID. The zero cursor is before the newest post.
+type PostCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// Cursor returns the position of the post in the listings
+func (p *Post) Cursor() PostCursor {
+	return PostCursor{p.CreatedAt, p.ID}
+}
+
+// String encodes the cursor as <creation time in microseconds>_<id>
+func (c PostCursor) String() string {
+	return strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + "_" + strconv.Itoa(c.ID)
+}
+
+// ParsePostCursor decodes a cursor encoded by String
+func ParsePostCursor(s string) (PostCursor, error) {
+	parts := strings.Split(s, "_")
+	if len(parts) != 2 {
+		return PostCursor{}, errors.New("invalid cursor")
+	}
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return PostCursor{}, errors.New("invalid cursor")
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil || id < 1 {
+		return PostCursor{}, errors.New("invalid cursor")
+	}
+	return PostCursor{time.UnixMicro(micros), id}, nil
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Series stores the data of a multi-part post collection
+type Series struct {
+	ID          int           `json:"id"`
+	Title       string        `json:"title"`
+	Slug        string        `json:"slug"`
+	Description string        `json:"description"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   *time.Time    `json:"updatedAt"`
+	Parts       []*SeriesPart `json:"parts,omitempty"`
+}
+
+// SeriesPart stores the summary of a post that is a member of a series
+type SeriesPart struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Subtitle string `json:"subtitle"`
+	Position int    `json:"position"`
+	Hidden   bool   `json:"hidden"`
+}
+
+// SeriesNav stores the series a post belongs to along with its neighbouring parts
+type SeriesNav struct {
+	ID       int         `json:"id"`
+	Title    string      `json:"title"`
+	Slug     string      `json:"slug"`
+	Position int         `json:"position"`
+	Total    int         `json:"total"`
+	Previous *SeriesPart `json:"previous"`
+	Next     *SeriesPart `json:"next"`
+}
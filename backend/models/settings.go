@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// SiteSettings stores the settings of the blog that can be changed while it is running.
+// Every JSON key is stored as a separate row in the site_settings table.
+type SiteSettings struct {
+	Title                  string            `json:"title"`
+	Description            string            `json:"description"`
+	DefaultFeatureImageURL string            `json:"defaultFeatureImageUrl"`
+	PostsPerPage           int               `json:"postsPerPage"`
+	MinPostsPerPage        int               `json:"minPostsPerPage"`
+	MaxPostsPerPage        int               `json:"maxPostsPerPage"`
+	SocialLinks            map[string]string `json:"socialLinks"`
+	AllowedOrigins         []string          `json:"allowedOrigins"`
+	UpdatedAt              *time.Time        `json:"updatedAt"`
+}
+
+// PublicSiteSettings stores the subset of the site settings that anyone can read
+type PublicSiteSettings struct {
+	Title                  string            `json:"title"`
+	Description            string            `json:"description"`
+	DefaultFeatureImageURL string            `json:"defaultFeatureImageUrl"`
+	PostsPerPage           int               `json:"postsPerPage"`
+	MinPostsPerPage        int               `json:"minPostsPerPage"`
+	MaxPostsPerPage        int               `json:"maxPostsPerPage"`
+	SocialLinks            map[string]string `json:"socialLinks"`
+}
+
+// Public returns the subset of the settings that anyone can read
+func (s *SiteSettings) Public() *PublicSiteSettings {
+	return &PublicSiteSettings{
+		s.Title,
+		s.Description,
+		s.DefaultFeatureImageURL,
+		s.PostsPerPage,
+		s.MinPostsPerPage,
+		s.MaxPostsPerPage,
+		s.SocialLinks,
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag stores the metadata of a tag along with the number of posts using it
+type Tag struct {
+	Name          string     `json:"name"`
+	DisplayName   string     `json:"displayName"`
+	Description   string     `json:"description"`
+	Color         string     `json:"color"`
+	CoverImageURL string     `json:"coverImageUrl"`
+	PostCount     int        `json:"postCount"`
+	UpdatedAt     *time.Time `json:"updatedAt"`
+}
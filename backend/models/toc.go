@@ -0,0 +1,8 @@
+package models
+
+// TOCEntry stores a heading of a rendered post body for its table of contents
+type TOCEntry struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
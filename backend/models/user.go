@@ -19,6 +19,16 @@ type User struct {
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt *time.Time `json:"updatedAt"`
 	Username  string     `json:"username"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	Profile
+}
+
+// Profile stores the public profile fields a user edits themselves
+type Profile struct {
+	Bio           string            `json:"bio"`
+	AvatarURL     string            `json:"avatarUrl"`
+	Website       string            `json:"website"`
+	SocialHandles map[string]string `json:"socialHandles"`
 }
 
 // AuthUser represents a user account for private visibility (used for login and update response)
@@ -39,7 +49,9 @@ func (u *User) MarshalJSON() ([]byte, error) {
 			CreatedAt time.Time  `json:"createdAt"`
 			UpdatedAt *time.Time `json:"updatedAt"`
 			Username  string     `json:"username"`
-		}{u.ID, u.Name, u.Email, u.CreatedAt, nil, u.Username})
+			DeletedAt *time.Time `json:"deletedAt,omitempty"`
+			Profile
+		}{u.ID, u.Name, u.Email, u.CreatedAt, nil, u.Username, u.DeletedAt, u.Profile})
 	}
 	return json.Marshal(struct {
 		ID        uuid.UUID  `json:"id"`
@@ -48,7 +60,9 @@ func (u *User) MarshalJSON() ([]byte, error) {
 		CreatedAt time.Time  `json:"createdAt"`
 		UpdatedAt *time.Time `json:"updatedAt"`
 		Username  string     `json:"username"`
-	}{u.ID, u.Name, u.Email, u.CreatedAt, u.UpdatedAt, u.Username})
+		DeletedAt *time.Time `json:"deletedAt,omitempty"`
+		Profile
+	}{u.ID, u.Name, u.Email, u.CreatedAt, u.UpdatedAt, u.Username, u.DeletedAt, u.Profile})
 }
 
 // MarshalJSON marshals a given user's information including role
@@ -64,7 +78,9 @@ func (u *AuthUser) MarshalJSON() ([]byte, error) {
 			CreatedAt time.Time  `json:"createdAt"`
 			UpdatedAt *time.Time `json:"updatedAt"`
 			Username  string     `json:"username"`
-		}{u.ID, u.Name, u.Email, u.Admin, u.CreatedAt, nil, u.Username})
+			DeletedAt *time.Time `json:"deletedAt,omitempty"`
+			Profile
+		}{u.ID, u.Name, u.Email, u.Admin, u.CreatedAt, nil, u.Username, u.DeletedAt, u.Profile})
 	}
 	return json.Marshal(struct {
 		ID        uuid.UUID  `json:"id"`
@@ -74,7 +90,9 @@ func (u *AuthUser) MarshalJSON() ([]byte, error) {
 		CreatedAt time.Time  `json:"createdAt"`
 		UpdatedAt *time.Time `json:"updatedAt"`
 		Username  string     `json:"username"`
-	}{u.ID, u.Name, u.Email, u.Admin, u.CreatedAt, u.UpdatedAt, u.Username})
+		DeletedAt *time.Time `json:"deletedAt,omitempty"`
+		Profile
+	}{u.ID, u.Name, u.Email, u.Admin, u.CreatedAt, u.UpdatedAt, u.Username, u.DeletedAt, u.Profile})
 }
 
 // SetPassword hashes and salts the given password and then sets it to the user
@@ -96,6 +114,11 @@ func (u *User) CheckPassword(password string) bool {
 	return true
 }
 
+// Summary returns the author summary embedded in posts
+func (u *User) Summary() *AuthorSummary {
+	return &AuthorSummary{u.ID.String(), u.Name, u.Username, u.AvatarURL}
+}
+
 // IsAdmin returns if the user is an admin
 func (u *User) IsAdmin() bool {
 	return u.Admin == true
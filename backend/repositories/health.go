@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+)
+
+// HealthRepository interface
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+type healthRepository struct {
+	*database.Postgres
+}
+
+// NewHealthRepository - creates a health repository instance
+func NewHealthRepository(db *database.Postgres) HealthRepository {
+	return &healthRepository{db}
+}
+
+// Ping acquires a connection from the pool and checks it
+func (hr *healthRepository) Ping(ctx context.Context) error {
+	return hr.Pool.Ping(ctx)
+}
+
+// SchemaVersion returns the version of the newest migration applied to the database, 0 if none were
+func (hr *healthRepository) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := hr.Pool.QueryRow(ctx, "SELECT coalesce(max(version), 0) FROM post_schema.schema_migration").Scan(&version)
+	return version, err
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Logs a failed query with the request of the context. Rows that weren't found aren't logged,
+// the callers handle them, usually by responding with 404.
+func logError(ctx context.Context, msg string, err error, args ...interface{}) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	slog.ErrorContext(ctx, msg, append(args, "err", err)...)
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/models"
+)
+
+// MenuRepository interface
+type MenuRepository interface {
+	GetAll(ctx context.Context) ([]*models.Menu, error)
+	FindByName(ctx context.Context, name string) (*models.Menu, error)
+	Save(ctx context.Context, m *models.Menu) error
+	Delete(ctx context.Context, name string) error
+}
+
+type menuRepository struct {
+	*database.Postgres
+}
+
+// NewMenuRepository - creates a menu repository instance
+func NewMenuRepository(db *database.Postgres) MenuRepository {
+	return &menuRepository{db}
+}
+
+// GetAll returns all menus ordered by name
+func (mr *menuRepository) GetAll(ctx context.Context) ([]*models.Menu, error) {
+	var menus []*models.Menu
+
+	rows, err := mr.Pool.Query(ctx, "SELECT name, items, updated_at FROM post_schema.menu ORDER BY name")
+	if err != nil {
+		logError(ctx, "Failed to list menus", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := new(models.Menu)
+		if err := rows.Scan(&m.Name, &m.Items, &m.UpdatedAt); err != nil {
+			logError(ctx, "Failed to list menus", err)
+			return nil, err
+		}
+		menus = append(menus, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list menus", err)
+		return nil, err
+	}
+
+	return menus, nil
+}
+
+// FindByName returns the menu with the given name
+func (mr *menuRepository) FindByName(ctx context.Context, name string) (*models.Menu, error) {
+	m := models.Menu{}
+	err := mr.Pool.QueryRow(ctx,
+		"SELECT name, items, updated_at FROM post_schema.menu WHERE name=$1", name,
+	).Scan(&m.Name, &m.Items, &m.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Save creates or replaces the menu
+func (mr *menuRepository) Save(ctx context.Context, m *models.Menu) error {
+	_, err := mr.Pool.Exec(ctx,
+		"INSERT INTO post_schema.menu (name, items, updated_at) VALUES ($1, $2, $3) ON CONFLICT (name) DO UPDATE SET items=$2, updated_at=$3",
+		m.Name, m.Items, m.UpdatedAt,
+	)
+	if err != nil {
+		logError(ctx, "Failed to save menu", err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the menu with the given name
+func (mr *menuRepository) Delete(ctx context.Context, name string) error {
+	_, err := mr.Pool.Exec(ctx, "DELETE FROM post_schema.menu WHERE name=$1", name)
+	if err != nil {
+		logError(ctx, "Failed to delete menu", err)
+		return err
+	}
+
+	return nil
+}
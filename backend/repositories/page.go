@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/models"
+)
+
+// PageRepository interface
+type PageRepository interface {
+	Create(ctx context.Context, p *models.Page) error
+	Update(ctx context.Context, p *models.Page) error
+	Delete(ctx context.Context, id int) error
+	GetAll(ctx context.Context, includeHidden bool) ([]*models.Page, error)
+	FindByID(ctx context.Context, id int) (*models.Page, error)
+	FindBySlug(ctx context.Context, slug string, includeHidden bool) (*models.Page, error)
+	Exists(ctx context.Context, slug string) bool
+	IsDescendant(ctx context.Context, id int, ancestorID int) (bool, error)
+	SearchQuery(ctx context.Context, title string) ([]*models.Page, error)
+}
+
+type pageRepository struct {
+	*database.Postgres
+}
+
+// Columns selected for a page, in the order of pageFields
+const pageColumns = "id, title, slug, body, body_html, toc, parent_id, sort_order, template, hidden, authorid, created_at, updated_at"
+
+// Returns the scan destinations of a page, in the order of pageColumns
+func pageFields(p *models.Page) []interface{} {
+	return []interface{}{
+		&p.ID, &p.Title, &p.Slug, &p.Body, &p.BodyHTML, &p.TOC, &p.ParentID, &p.SortOrder, &p.Template,
+		&p.Hidden, &p.AuthorID, &p.CreatedAt, &p.UpdatedAt,
+	}
+}
+
+// NewPageRepository - creates a page repository instance
+func NewPageRepository(db *database.Postgres) PageRepository {
+	return &pageRepository{db}
+}
+
+// Create creates a new page in the database
+func (pr *pageRepository) Create(ctx context.Context, p *models.Page) error {
+	var pID int
+	err := pr.Pool.QueryRow(ctx,
+		"INSERT INTO post_schema.page (title, slug, body, body_html, toc, parent_id, sort_order, template, hidden, authorid, created_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id",
+		p.Title, p.Slug, p.Body, p.BodyHTML, p.TOC, p.ParentID, p.SortOrder, p.Template, p.Hidden, p.AuthorID, p.CreatedAt.UTC(),
+	).Scan(&pID)
+	if err != nil {
+		logError(ctx, "Failed to insert page", err)
+		return err
+	}
+
+	p.ID = pID
+
+	return nil
+}
+
+// Update updates the page with the given ID in the database
+func (pr *pageRepository) Update(ctx context.Context, p *models.Page) error {
+	_, err := pr.Pool.Exec(ctx,
+		"UPDATE post_schema.page SET title=$1, slug=$2, body=$3, body_html=$4, toc=$5, parent_id=$6, sort_order=$7, template=$8, hidden=$9, updated_at=$10 "+
+			"WHERE id=$11",
+		p.Title, p.Slug, p.Body, p.BodyHTML, p.TOC, p.ParentID, p.SortOrder, p.Template, p.Hidden, p.UpdatedAt, p.ID,
+	)
+	if err != nil {
+		logError(ctx, "Failed to update page", err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the page with the given ID in the database. Its child pages become top-level pages.
+func (pr *pageRepository) Delete(ctx context.Context, id int) error {
+	_, err := pr.Pool.Exec(ctx, "DELETE FROM post_schema.page WHERE id=$1", id)
+	if err != nil {
+		logError(ctx, "Failed to delete page", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetAll returns all pages without their bodies, ordered by their sort order
+func (pr *pageRepository) GetAll(ctx context.Context, includeHidden bool) ([]*models.Page, error) {
+	var pages []*models.Page
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT "+pageColumns+" FROM post_schema.page WHERE $1 OR NOT hidden ORDER BY sort_order, title",
+		includeHidden,
+	)
+	if err != nil {
+		logError(ctx, "Failed to list pages", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := new(models.Page)
+		err := rows.Scan(pageFields(p)...)
+		if err != nil {
+			logError(ctx, "Failed to list pages", err)
+			return nil, err
+		}
+		p.Body = ""
+		p.BodyHTML = ""
+		p.TOC = nil
+		pages = append(pages, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list pages", err)
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// FindByID returns the page with the given ID, including hidden pages
+func (pr *pageRepository) FindByID(ctx context.Context, id int) (*models.Page, error) {
+	page := models.Page{}
+	err := pr.Pool.QueryRow(ctx, "SELECT "+pageColumns+" FROM post_schema.page WHERE id=$1", id).Scan(pageFields(&page)...)
+	if err != nil {
+		logError(ctx, "Failed to find page by id", err)
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// FindBySlug returns the page with the given slug
+func (pr *pageRepository) FindBySlug(ctx context.Context, slug string, includeHidden bool) (*models.Page, error) {
+	page := models.Page{}
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT "+pageColumns+" FROM post_schema.page WHERE slug=$1 AND ($2 OR NOT hidden)", slug, includeHidden,
+	).Scan(pageFields(&page)...)
+	if err != nil {
+		logError(ctx, "Failed to find page by slug", err)
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// Exists checks if a page with the slug already exists in the database
+func (pr *pageRepository) Exists(ctx context.Context, slug string) bool {
+	var exists bool
+	err := pr.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT id FROM post_schema.page WHERE slug=$1)", slug).Scan(&exists)
+	if err != nil {
+		logError(ctx, "Failed to check page slug", err)
+		return true
+	}
+
+	return exists
+}
+
+// IsDescendant returns true if the page with the given ID is the ancestor page or one of its descendants
+func (pr *pageRepository) IsDescendant(ctx context.Context, id int, ancestorID int) (bool, error) {
+	var isDescendant bool
+	err := pr.Pool.QueryRow(ctx,
+		"WITH RECURSIVE tree AS (SELECT id FROM post_schema.page WHERE id=$2 "+
+			"UNION SELECT p.id FROM post_schema.page p JOIN tree t ON p.parent_id = t.id) "+
+			"SELECT EXISTS (SELECT 1 FROM tree WHERE id=$1)",
+		id, ancestorID,
+	).Scan(&isDescendant)
+	if err != nil {
+		logError(ctx, "Failed to check page ancestors", err)
+		return false, err
+	}
+
+	return isDescendant, nil
+}
+
+// SearchQuery returns up to 5 public pages whose title contains the given title
+func (pr *pageRepository) SearchQuery(ctx context.Context, title string) ([]*models.Page, error) {
+	var pages []*models.Page
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT "+pageColumns+" FROM post_schema.page WHERE NOT hidden AND LOWER(title) LIKE LOWER('%' || $1 || '%') ORDER BY sort_order, title LIMIT 5",
+		title,
+	)
+	if err != nil {
+		logError(ctx, "Failed to search pages", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := new(models.Page)
+		err := rows.Scan(pageFields(p)...)
+		if err != nil {
+			logError(ctx, "Failed to search pages", err)
+			return nil, err
+		}
+		p.Body = ""
+		p.BodyHTML = ""
+		p.TOC = nil
+		pages = append(pages, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
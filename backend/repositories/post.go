@@ -2,8 +2,11 @@ package repositories
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alanqchen/Bear-Post/backend/database"
 	"github.com/alanqchen/Bear-Post/backend/models"
@@ -12,39 +15,75 @@ import (
 
 // PostRepository interface
 type PostRepository interface {
-	Create(p *models.Post) error
-	GetAll() ([]*models.Post, error)
-	FindByID(id int) (*models.Post, error)
-	FindByIDAdmin(id int) (*models.Post, error)
-	FindBySlug(slug string) (*models.Post, error)
-	FindBySlugAdmin(slug string) (*models.Post, error)
-	Exists(slug string) bool
-	Delete(id int) error
-	Update(p *models.Post) error
-	Paginate(maxID int, perPage int, tags []string) ([]*models.Post, int, error)
-	PaginateAdmin(maxID int, perPage int, tags []string) ([]*models.Post, int, error)
-	GetTotalPostCount() (int, error)
-	GetPublicPostCount() (int, error)
-	ResetSeq() error
-	GetLastID() (int, error)
-	GetLastIDAdmin() (int, error)
-	SearchQuery(string, []string) ([]*models.Post, error)
+	Create(ctx context.Context, p *models.Post) error
+	GetAll(ctx context.Context) ([]*models.Post, error)
+	GetBatch(ctx context.Context, afterID int, limit int) ([]*models.Post, error)
+	FindByID(ctx context.Context, id int) (*models.Post, error)
+	FindByIDAdmin(ctx context.Context, id int) (*models.Post, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Post, error)
+	FindBySlugAdmin(ctx context.Context, slug string) (*models.Post, error)
+	Exists(ctx context.Context, slug string) bool
+	FindSlugOwner(ctx context.Context, slug string) (int, error)
+	FindRedirect(ctx context.Context, slug string) (string, error)
+	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, p *models.Post) error
+	Paginate(ctx context.Context, after models.PostCursor, perPage int, tags []string) ([]*models.Post, int, error)
+	PaginateAdmin(ctx context.Context, after models.PostCursor, perPage int, tags []string) ([]*models.Post, int, error)
+	FindCursor(ctx context.Context, id int) (models.PostCursor, error)
+	GetTotalPostCount(ctx context.Context) (int, error)
+	GetPublicPostCount(ctx context.Context) (int, error)
+	ResetSeq(ctx context.Context) error
+	GetLastID(ctx context.Context) (int, error)
+	GetLastIDAdmin(ctx context.Context) (int, error)
+	SearchQuery(ctx context.Context, title string, tags []string) ([]*models.Post, error)
+	GetArchive(ctx context.Context) ([]*models.ArchiveYear, error)
+	GetArchivePostCount(ctx context.Context, year int, month int) (int, error)
+	PaginateArchive(ctx context.Context, after models.PostCursor, perPage int, year int, month int) ([]*models.Post, int, error)
+	GetAuthorPostCount(ctx context.Context, authorID string) (int, error)
+	PaginateByAuthor(ctx context.Context, after models.PostCursor, perPage int, authorID string) ([]*models.Post, int, error)
+	GetSlugsAndTagsByAuthor(ctx context.Context, authorID string) ([]string, []string, error)
+	GetAuthors(ctx context.Context, postID int) ([]*models.PostAuthor, error)
+	GetAuthorsByPosts(ctx context.Context, postIDs []int) (map[int][]*models.PostAuthor, error)
+	SetAuthors(ctx context.Context, postID int, authors []*models.PostAuthor) (int, error)
+	IsAuthor(ctx context.Context, postID int, userID string) bool
+	GetTrash(ctx context.Context) ([]*models.Post, error)
+	FindDeletedByID(ctx context.Context, id int) (*models.Post, error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	BulkUpdate(ctx context.Context, filter *models.PostFilter, action *models.BulkAction, dryRun bool) ([]*models.Post, error)
 }
 
+// ErrVersionConflict is returned by Update when the post was changed since the version being updated
+var ErrVersionConflict = errors.New("post was changed by someone else")
+
 type postRepository struct {
 	*database.Postgres
 }
 
+// Columns selected for a post, in the order of postFields
+const postColumns = "id, title, slug, body, created_at, updated_at, tags, hidden, authorid, feature_image_url, subtitle, views, slug_pinned, body_html, toc, " +
+	"excerpt, custom_excerpt, word_count, reading_time, version"
+
+// Returns the scan destinations of a post, in the order of postColumns
+func postFields(p *models.Post) []interface{} {
+	return []interface{}{
+		&p.ID, &p.Title, &p.Slug, &p.Body, &p.CreatedAt, &p.UpdatedAt, &p.Tags, &p.Hidden,
+		&p.AuthorID, &p.FeatureImgURL, &p.Subtitle, &p.Views, &p.SlugPinned, &p.BodyHTML, &p.TOC,
+		&p.Excerpt, &p.CustomExcerpt, &p.WordCount, &p.ReadingTime, &p.Version,
+	}
+}
+
 // NewPostRepository - creates a post repository instance
 func NewPostRepository(db *database.Postgres) PostRepository {
 	return &postRepository{db}
 }
 
 // Create creates a new post in the database
-func (pr *postRepository) Create(p *models.Post) error {
-	exists := pr.Exists(p.Slug)
+func (pr *postRepository) Create(ctx context.Context, p *models.Post) error {
+	exists := pr.Exists(ctx, p.Slug)
 	if exists {
-		err := pr.createWithSlugCount(p)
+		err := pr.createWithSlugCount(ctx, p)
 		if err != nil {
 			return err
 		}
@@ -62,13 +101,16 @@ func (pr *postRepository) Create(p *models.Post) error {
 	var pID int
 
 	err := pr.Pool.QueryRow(
-		context.Background(),
-		"INSERT INTO post_schema.post VALUES (default, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id",
-		p.Title, p.Slug, p.Body, p.CreatedAt.UTC(), nil, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views,
-	).Scan(&pID)
+		ctx,
+		"INSERT INTO post_schema.post (title, slug, body, created_at, updated_at, tags, hidden, authorid, feature_image_url, subtitle, views, slug_pinned, body_html, toc, "+
+			"excerpt, custom_excerpt, word_count, reading_time) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id, version",
+		p.Title, p.Slug, p.Body, p.CreatedAt.UTC(), nil, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views, p.SlugPinned, p.BodyHTML, p.TOC,
+		p.Excerpt, p.CustomExcerpt, p.WordCount, p.ReadingTime,
+	).Scan(&pID, &p.Version)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to insert post", err)
 		return err
 	}
 
@@ -77,23 +119,102 @@ func (pr *postRepository) Create(p *models.Post) error {
 	return nil
 }
 
-// Delete deletes the post with the given ID in the database
-func (pr *postRepository) Delete(id int) error {
-	row, err := pr.Pool.Query(context.Background(), "DELETE FROM post_schema.post WHERE id=$1", id)
-	defer row.Close()
+// Delete moves the post with the given ID to the trash
+func (pr *postRepository) Delete(ctx context.Context, id int) error {
+	_, err := pr.Pool.Exec(ctx, "UPDATE post_schema.post SET deleted_at=$1, version=version+1 WHERE id=$2 AND deleted_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		logError(ctx, "Failed to move post to trash", err)
+		return err
+	}
+	return nil
+}
+
+// GetTrash returns the posts in the trash without their bodies, most recently deleted first
+func (pr *postRepository) GetTrash(ctx context.Context) ([]*models.Post, error) {
+	var posts []*models.Post
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT "+postColumns+", deleted_at FROM post_schema.post WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC",
+	)
+	if err != nil {
+		logError(ctx, "Failed to list posts in trash", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := new(models.Post)
+		err := rows.Scan(append(postFields(p), &p.DeletedAt)...)
+		if err != nil {
+			logError(ctx, "Failed to list posts in trash", err)
+			return nil, err
+		}
+
+		toListing(p)
+
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list posts in trash", err)
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// FindDeletedByID returns the post with the given ID if it is in the trash
+func (pr *postRepository) FindDeletedByID(ctx context.Context, id int) (*models.Post, error) {
+	post := models.Post{}
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT "+postColumns+", deleted_at FROM post_schema.post WHERE deleted_at IS NOT NULL AND id = $1", id,
+	).Scan(append(postFields(&post), &post.DeletedAt)...)
+	if err != nil {
+		logError(ctx, "Failed to find post in trash", err)
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// Restore takes the post with the given ID out of the trash
+func (pr *postRepository) Restore(ctx context.Context, id int) error {
+	_, err := pr.Pool.Exec(ctx, "UPDATE post_schema.post SET deleted_at=NULL, version=version+1 WHERE id=$1", id)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to restore post", err)
 		return err
 	}
 	return nil
 }
 
-// Exists checks if a post with the slug already exists in the database
-func (pr *postRepository) Exists(slug string) bool {
+// Purge permanently deletes the post with the given ID if it is in the trash
+func (pr *postRepository) Purge(ctx context.Context, id int) error {
+	_, err := pr.Pool.Exec(ctx, "DELETE FROM post_schema.post WHERE id=$1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		logError(ctx, "Failed to purge post", err)
+		return err
+	}
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes the posts that were moved to the trash before the cutoff.
+// Returns the number of posts deleted.
+func (pr *postRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := pr.Pool.Exec(ctx, "DELETE FROM post_schema.post WHERE deleted_at < $1", cutoff.UTC())
+	if err != nil {
+		logError(ctx, "Failed to purge posts from trash", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Exists checks if a post with the slug already exists, or previously existed, in the database.
+// Posts in the trash keep their slug so they can be restored.
+func (pr *postRepository) Exists(ctx context.Context, slug string) bool {
 	var exists bool
-	err := pr.Pool.QueryRow(context.Background(), "SELECT EXISTS (SELECT id FROM post_schema.post WHERE slug=$1)", slug).Scan(&exists)
+	err := pr.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT id FROM post_schema.post WHERE slug=$1) OR EXISTS (SELECT post_id FROM post_schema.post_slug_history WHERE slug=$1)", slug).Scan(&exists)
 	if err != nil {
-		log.Printf("[POST REPO]: Exists err %v", err)
+		logError(ctx, "Failed to check post slug", err)
 		return true
 	}
 
@@ -101,12 +222,12 @@ func (pr *postRepository) Exists(slug string) bool {
 }
 
 // This is a private function to be used in cases where a slug already exists
-func (pr *postRepository) createWithSlugCount(p *models.Post) error {
+func (pr *postRepository) createWithSlugCount(ctx context.Context, p *models.Post) error {
 
 	var count int
-	err := pr.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM post_schema.post WHERE slug LIKE $1", p.Slug+"%").Scan(&count)
+	err := pr.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM post_schema.post WHERE slug LIKE $1", p.Slug+"%").Scan(&count)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to insert post with numbered slug", err)
 		return err
 	}
 	counter := strconv.Itoa(count + 1)
@@ -120,13 +241,16 @@ func (pr *postRepository) createWithSlugCount(p *models.Post) error {
 
 	var pID int
 	err = pr.Pool.QueryRow(
-		context.Background(),
-		"INSERT INTO post_schema.post VALUES (default, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id",
-		p.Title, p.Slug+"-"+counter, p.Body, p.CreatedAt.UTC(), nil, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views,
-	).Scan(&pID)
+		ctx,
+		"INSERT INTO post_schema.post (title, slug, body, created_at, updated_at, tags, hidden, authorid, feature_image_url, subtitle, views, slug_pinned, body_html, toc, "+
+			"excerpt, custom_excerpt, word_count, reading_time) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id, version",
+		p.Title, p.Slug+"-"+counter, p.Body, p.CreatedAt.UTC(), nil, p.Tags, p.Hidden, p.AuthorID, p.FeatureImgURL, p.Subtitle, p.Views, p.SlugPinned, p.BodyHTML, p.TOC,
+		p.Excerpt, p.CustomExcerpt, p.WordCount, p.ReadingTime,
+	).Scan(&pID, &p.Version)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to insert post with numbered slug", err)
 		return err
 	}
 
@@ -137,23 +261,21 @@ func (pr *postRepository) createWithSlugCount(p *models.Post) error {
 }
 
 // FindByID returns the post with the given ID. Returns nil if the post doesn't exist or it's hidden in the database
-func (pr *postRepository) FindByID(id int) (*models.Post, error) {
+func (pr *postRepository) FindByID(ctx context.Context, id int) (*models.Post, error) {
 	post := models.Post{}
 
-	err := pr.Pool.QueryRow(context.Background(),
-		"SELECT * FROM post_schema.post WHERE NOT hidden AND id = $1", id,
-	).Scan(&post.ID, &post.Title, &post.Slug, &post.Body, &post.CreatedAt, &post.UpdatedAt,
-		&post.Tags, &post.Hidden, &post.AuthorID, &post.FeatureImgURL, &post.Subtitle, &post.Views,
-	)
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND id = $1", id,
+	).Scan(postFields(&post)...)
 
 	if err != nil {
 		return nil, err
 	}
 	post.Views++
 	//pr.Conn.Prepare(context.Background(), "update-views-query", "UPDATE post_schema.post SET views=$1 WHERE id=$2")
-	_, err = pr.Pool.Exec(context.Background(), "UPDATE post_schema.post SET views=$1 WHERE id=$2", post.Views, post.ID)
+	_, err = pr.Pool.Exec(ctx, "UPDATE post_schema.post SET views=$1 WHERE id=$2", post.Views, post.ID)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to count post view", err)
 		return nil, err
 	}
 
@@ -161,13 +283,10 @@ func (pr *postRepository) FindByID(id int) (*models.Post, error) {
 }
 
 // FindByIDAdmin returns the post with the given ID (including hidden). Returns nil if the post doesn't exist
-func (pr *postRepository) FindByIDAdmin(id int) (*models.Post, error) {
+func (pr *postRepository) FindByIDAdmin(ctx context.Context, id int) (*models.Post, error) {
 	post := models.Post{}
 
-	err := pr.Pool.QueryRow(context.Background(), "SELECT * FROM post_schema.post WHERE id = $1", id).Scan(
-		&post.ID, &post.Title, &post.Slug, &post.Body, &post.CreatedAt, &post.UpdatedAt, &post.Tags,
-		&post.Hidden, &post.AuthorID, &post.FeatureImgURL, &post.Subtitle, &post.Views,
-	)
+	err := pr.Pool.QueryRow(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND id = $1", id).Scan(postFields(&post)...)
 
 	if err != nil {
 		return nil, err
@@ -177,12 +296,12 @@ func (pr *postRepository) FindByIDAdmin(id int) (*models.Post, error) {
 }
 
 // Update updates the post with the given ID in the database
-func (pr *postRepository) Update(p *models.Post) error {
-	exists := pr.Exists(p.Slug)
+func (pr *postRepository) Update(ctx context.Context, p *models.Post) error {
+	exists := pr.Exists(ctx, p.Slug)
 	// Check if this is a new slug
 	if !exists {
 		//
-		err := pr.updatePost(p)
+		err := pr.updatePost(ctx, p)
 		if err != nil {
 			return err
 		}
@@ -191,15 +310,14 @@ func (pr *postRepository) Update(p *models.Post) error {
 	}
 
 	// Post do exists
-	// Now we want to find out if the slug is the post we are updating
-	var postID int
-	err := pr.Pool.QueryRow(context.Background(), "SELECT id FROM post_schema.post WHERE slug LIKE $1", p.Slug).Scan(&postID)
+	// Now we want to find out if the slug is the post we are updating (currently or previously)
+	postID, err := pr.FindSlugOwner(ctx, p.Slug)
 	if err != nil && err != pgx.ErrNoRows {
 		return err
 	}
 
 	if p.ID == postID {
-		err := pr.updatePost(p)
+		err := pr.updatePost(ctx, p)
 		if err != nil {
 			return err
 		}
@@ -209,14 +327,14 @@ func (pr *postRepository) Update(p *models.Post) error {
 
 	// If its not the same post we append the next count number of that slug
 	var slugCount int
-	err = pr.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM post_schema.post where slug LIKE $1", "%"+p.Slug+"%").Scan(&slugCount)
+	err = pr.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM post_schema.post where slug LIKE $1", "%"+p.Slug+"%").Scan(&slugCount)
 	if err != nil {
 		return err
 	}
 	counter := strconv.Itoa(slugCount + 1)
 	p.Slug = p.Slug + "-" + counter
 
-	err = pr.updatePost(p)
+	err = pr.updatePost(ctx, p)
 	if err != nil {
 		return err
 	}
@@ -225,22 +343,100 @@ func (pr *postRepository) Update(p *models.Post) error {
 }
 
 // updatePost is separated since it's used in multiple conditions in Update
-func (pr *postRepository) updatePost(p *models.Post) error {
-	_, err := pr.Pool.Exec(context.Background(), "UPDATE post_schema.post SET title=$1, slug=$2, body=$3, updated_at=$4, tags=$5, hidden=$6, feature_image_url=$7, subtitle=$8 WHERE id=$9", p.Title, p.Slug, p.Body, p.UpdatedAt, p.Tags, p.Hidden, p.FeatureImgURL, p.Subtitle, p.ID)
+// If the slug changed, the previous slug is kept in the slug history so old links can be redirected.
+// Returns ErrVersionConflict if the stored version isn't the post's version, otherwise the version is incremented.
+func (pr *postRepository) updatePost(ctx context.Context, p *models.Post) error {
+	tx, err := pr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to update post", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldSlug string
+	var version int
+	err = tx.QueryRow(ctx, "SELECT slug, version FROM post_schema.post WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", p.ID).Scan(&oldSlug, &version)
+	if err != nil {
+		logError(ctx, "Failed to update post", err)
+		return err
+	}
+	if version != p.Version {
+		return ErrVersionConflict
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE post_schema.post SET title=$1, slug=$2, body=$3, updated_at=$4, tags=$5, hidden=$6, feature_image_url=$7, subtitle=$8, slug_pinned=$9, body_html=$10, toc=$11, "+
+		"excerpt=$12, custom_excerpt=$13, word_count=$14, reading_time=$15, version=version+1 WHERE id=$16",
+		p.Title, p.Slug, p.Body, p.UpdatedAt, p.Tags, p.Hidden, p.FeatureImgURL, p.Subtitle, p.SlugPinned, p.BodyHTML, p.TOC,
+		p.Excerpt, p.CustomExcerpt, p.WordCount, p.ReadingTime, p.ID,
+	)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to update post", err)
 		return err
 	}
 
+	if oldSlug != p.Slug {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO post_schema.post_slug_history (slug, post_id, created_at) VALUES ($1, $2, $3) "+
+				"ON CONFLICT (slug) DO UPDATE SET post_id=$2, created_at=$3",
+			oldSlug, p.ID, time.Now().UTC(),
+		)
+		if err != nil {
+			logError(ctx, "Failed to update post", err)
+			return err
+		}
+		// The current slug always wins over a previous one
+		_, err = tx.Exec(ctx, "DELETE FROM post_schema.post_slug_history WHERE slug=$1", p.Slug)
+		if err != nil {
+			logError(ctx, "Failed to update post", err)
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logError(ctx, "Failed to update post", err)
+		return err
+	}
+
+	p.Version++
+
 	return nil
 }
 
+// FindSlugOwner returns the ID of the post that currently uses or previously used the given slug
+func (pr *postRepository) FindSlugOwner(ctx context.Context, slug string) (int, error) {
+	var postID int
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT id FROM post_schema.post WHERE slug=$1 UNION ALL SELECT post_id FROM post_schema.post_slug_history WHERE slug=$1 LIMIT 1",
+		slug,
+	).Scan(&postID)
+	if err != nil {
+		return -1, err
+	}
+
+	return postID, nil
+}
+
+// FindRedirect returns the current slug of the non-hidden post that previously used the given slug
+func (pr *postRepository) FindRedirect(ctx context.Context, slug string) (string, error) {
+	var current string
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT p.slug FROM post_schema.post_slug_history h JOIN post_schema.post p ON p.id = h.post_id WHERE p.deleted_at IS NULL AND NOT p.hidden AND h.slug=$1",
+		slug,
+	).Scan(&current)
+	if err != nil {
+		return "", err
+	}
+
+	return current, nil
+}
+
 // GetTotalPostCount returns the number of posts (including hidden) in the database
-func (pr *postRepository) GetTotalPostCount() (int, error) {
+func (pr *postRepository) GetTotalPostCount(ctx context.Context) (int, error) {
 	var count int
-	err := pr.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM post_schema.post").Scan(&count)
+	err := pr.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM post_schema.post WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to count posts", err)
 		return -1, err
 	}
 
@@ -248,11 +444,11 @@ func (pr *postRepository) GetTotalPostCount() (int, error) {
 }
 
 // GetPublicPostCount returns the number of non-hidden posts in the database
-func (pr *postRepository) GetPublicPostCount() (int, error) {
+func (pr *postRepository) GetPublicPostCount(ctx context.Context) (int, error) {
 	var count int
-	err := pr.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM post_schema.post WHERE NOT hidden").Scan(&count)
+	err := pr.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden").Scan(&count)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to count public posts", err)
 		return -1, err
 	}
 
@@ -260,25 +456,22 @@ func (pr *postRepository) GetPublicPostCount() (int, error) {
 }
 
 // FindBySlug returns the post with the given slug in
-func (pr *postRepository) FindBySlug(slug string) (*models.Post, error) {
+func (pr *postRepository) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
 	post := models.Post{}
 
-	err := pr.Pool.QueryRow(context.Background(), "SELECT * FROM post_schema.post WHERE NOT hidden AND slug LIKE $1", slug).Scan(
-		&post.ID, &post.Title, &post.Slug, &post.Body, &post.CreatedAt, &post.UpdatedAt,
-		&post.Tags, &post.Hidden, &post.AuthorID, &post.FeatureImgURL, &post.Subtitle, &post.Views,
-	)
+	err := pr.Pool.QueryRow(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND slug LIKE $1", slug).Scan(postFields(&post)...)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find post by slug", err)
 		return nil, err
 	}
 
 	post.Views++
 
 	//pr.Conn.Prepare(context.Background(), "update-views-query", "UPDATE post_schema.post SET views=$1 WHERE slug LIKE $2")
-	_, err = pr.Pool.Exec(context.Background(), "UPDATE post_schema.post SET views=$1 WHERE slug LIKE $2", post.Views, slug)
+	_, err = pr.Pool.Exec(ctx, "UPDATE post_schema.post SET views=$1 WHERE deleted_at IS NULL AND slug LIKE $2", post.Views, slug)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find post by slug", err)
 		return nil, err
 	}
 
@@ -286,15 +479,12 @@ func (pr *postRepository) FindBySlug(slug string) (*models.Post, error) {
 }
 
 // Returns a single post matching the slug, including hidden posts. There should not be multiple posts with the same slug.
-func (pr *postRepository) FindBySlugAdmin(slug string) (*models.Post, error) {
+func (pr *postRepository) FindBySlugAdmin(ctx context.Context, slug string) (*models.Post, error) {
 	post := models.Post{}
-	err := pr.Pool.QueryRow(context.Background(), "SELECT * FROM post_schema.post WHERE slug LIKE $1", slug).Scan(
-		&post.ID, &post.Title, &post.Slug, &post.Body, &post.CreatedAt, &post.UpdatedAt,
-		&post.Tags, &post.Hidden, &post.AuthorID, &post.FeatureImgURL, &post.Subtitle, &post.Views,
-	)
+	err := pr.Pool.QueryRow(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND slug LIKE $1", slug).Scan(postFields(&post)...)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find post by slug", err)
 		return nil, err
 	}
 	// Don't increment view count
@@ -303,22 +493,52 @@ func (pr *postRepository) FindBySlugAdmin(slug string) (*models.Post, error) {
 	return &post, nil
 }
 
+// GetBatch returns up to limit posts (including hidden) with an ID greater than afterID, in order
+// of their IDs, so every post can be read without loading them all at once
+func (pr *postRepository) GetBatch(ctx context.Context, afterID int, limit int) ([]*models.Post, error) {
+	var posts []*models.Post
+
+	rows, err := pr.Pool.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND id > $1 ORDER BY id LIMIT $2", afterID, limit)
+	if err != nil {
+		logError(ctx, "Failed to list posts", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := new(models.Post)
+		err := rows.Scan(postFields(p)...)
+		if err != nil {
+			logError(ctx, "Failed to list posts", err)
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list posts", err)
+		return nil, err
+	}
+
+	return posts, nil
+}
+
 // GetAll returns all posts (including hidden)
-func (pr *postRepository) GetAll() ([]*models.Post, error) {
+func (pr *postRepository) GetAll(ctx context.Context) ([]*models.Post, error) {
 	var posts []*models.Post
 
-	rows, err := pr.Pool.Query(context.Background(), "SELECT * FROM post_schema.post")
+	rows, err := pr.Pool.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL")
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list posts", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		p := new(models.Post)
-		err := rows.Scan(&p.ID, &p.Title, &p.Slug, &p.Body, &p.CreatedAt, &p.UpdatedAt, &p.Tags, &p.Hidden, &p.AuthorID, &p.FeatureImgURL, &p.Subtitle, &p.Views)
+		err := rows.Scan(postFields(p)...)
 		if err != nil {
-			log.Println(err)
+			logError(ctx, "Failed to list posts", err)
 			return nil, err
 		}
 		posts = append(posts, p)
@@ -331,8 +551,26 @@ func (pr *postRepository) GetAll() ([]*models.Post, error) {
 	return posts, nil
 }
 
+// Matches the posts after the cursor given as $n (creation time) and $n+1 (ID) in the order of the
+// listings. The zero cursor matches every post.
+func afterCursor(n int) string {
+	return fmt.Sprintf("($%[2]d::int = 0 OR (created_at, id) < ($%[1]d::timestamptz, $%[2]d::int))", n, n+1)
+}
+
+// FindCursor returns the position in the listings of the post with the given ID, including hidden
+// and trashed posts
+func (pr *postRepository) FindCursor(ctx context.Context, id int) (models.PostCursor, error) {
+	cursor := models.PostCursor{ID: id}
+	err := pr.Pool.QueryRow(ctx, "SELECT created_at FROM post_schema.post WHERE id=$1", id).Scan(&cursor.CreatedAt)
+	if err != nil {
+		logError(ctx, "Failed to find post cursor", err)
+		return models.PostCursor{}, err
+	}
+	return cursor, nil
+}
+
 // Paginate returns the keyset page of posts in the database
-func (pr *postRepository) Paginate(maxID int, perPage int, tags []string) ([]*models.Post, int, error) {
+func (pr *postRepository) Paginate(ctx context.Context, after models.PostCursor, perPage int, tags []string) ([]*models.Post, int, error) {
 	var posts []*models.Post
 
 	var rows pgx.Rows
@@ -340,39 +578,33 @@ func (pr *postRepository) Paginate(maxID int, perPage int, tags []string) ([]*mo
 
 	// For some reason, can't use same query w/ tags in latest pgx update
 	if len(tags) == 0 {
-		rows, err = pr.Pool.Query(context.Background(), "SELECT * FROM post_schema.post WHERE NOT hidden AND id < $1 ORDER BY created_at DESC, id DESC LIMIT $2", maxID, perPage)
+		rows, err = pr.Pool.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND "+afterCursor(1)+" ORDER BY created_at DESC, id DESC LIMIT $3", after.CreatedAt, after.ID, perPage)
 	} else {
-		rows, err = pr.Pool.Query(context.Background(), "SELECT * FROM post_schema.post WHERE NOT hidden AND id < $1 AND tags @> $2::text[] ORDER BY created_at DESC, id DESC LIMIT $3", maxID, tags, perPage)
+		rows, err = pr.Pool.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND "+afterCursor(1)+" AND tags @> $3::text[] ORDER BY created_at DESC, id DESC LIMIT $4", after.CreatedAt, after.ID, tags, perPage)
 	}
 	defer rows.Close()
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list page of posts", err)
 		return nil, -1, err
 	}
 	var minID int
 	for rows.Next() {
 		p := new(models.Post)
-		err := rows.Scan(&p.ID, &p.Title, &p.Slug, &p.Body, &p.CreatedAt, &p.UpdatedAt, &p.Tags, &p.Hidden, &p.AuthorID, &p.FeatureImgURL, &p.Subtitle, &p.Views)
+		err := rows.Scan(postFields(p)...)
 
 		if err != nil {
-			log.Println(err)
+			logError(ctx, "Failed to list page of posts", err)
 			return nil, -1, err
 		}
 
-		// Limit p.Body to 250 characters
-		limit := len(p.Body)
-		if len(p.Body) > 250 {
-			limit = 250
-		}
-
-		p.Body = p.Body[:limit]
+		toListing(p)
 
 		posts = append(posts, p)
 
 		minID = p.ID
 	}
 	if err := rows.Err(); err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list page of posts", err)
 		return nil, -1, err
 	}
 
@@ -386,7 +618,7 @@ func (pr *postRepository) Paginate(maxID int, perPage int, tags []string) ([]*mo
 }
 
 // Paginate returns the keyset page of posts (including hidden) in the database
-func (pr *postRepository) PaginateAdmin(maxID int, perPage int, tags []string) ([]*models.Post, int, error) {
+func (pr *postRepository) PaginateAdmin(ctx context.Context, after models.PostCursor, perPage int, tags []string) ([]*models.Post, int, error) {
 	var posts []*models.Post
 
 	var rows pgx.Rows
@@ -394,39 +626,33 @@ func (pr *postRepository) PaginateAdmin(maxID int, perPage int, tags []string) (
 
 	// For some reason, can't use same query w/ tags in latest pgx update
 	if len(tags) == 0 {
-		rows, err = pr.Pool.Query(context.Background(), "SELECT * FROM post_schema.post WHERE id < $1 ORDER BY created_at DESC, id DESC LIMIT $2", maxID, perPage)
+		rows, err = pr.Pool.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND "+afterCursor(1)+" ORDER BY created_at DESC, id DESC LIMIT $3", after.CreatedAt, after.ID, perPage)
 	} else {
-		rows, err = pr.Pool.Query(context.Background(), "SELECT * FROM post_schema.post WHERE id < $1 AND tags @> $2::text[] ORDER BY created_at DESC, id DESC LIMIT $3", maxID, tags, perPage)
+		rows, err = pr.Pool.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND "+afterCursor(1)+" AND tags @> $3::text[] ORDER BY created_at DESC, id DESC LIMIT $4", after.CreatedAt, after.ID, tags, perPage)
 	}
 	defer rows.Close()
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list page of posts", err)
 		return nil, -1, err
 	}
 	var minID int
 	for rows.Next() {
 		p := new(models.Post)
-		err := rows.Scan(&p.ID, &p.Title, &p.Slug, &p.Body, &p.CreatedAt, &p.UpdatedAt, &p.Tags, &p.Hidden, &p.AuthorID, &p.FeatureImgURL, &p.Subtitle, &p.Views)
+		err := rows.Scan(postFields(p)...)
 
 		if err != nil {
-			log.Println(err)
+			logError(ctx, "Failed to list page of posts", err)
 			return nil, -1, err
 		}
 
-		// Limit p.Body to 250 characters
-		limit := len(p.Body)
-		if len(p.Body) > 250 {
-			limit = 250
-		}
-
-		p.Body = p.Body[:limit]
+		toListing(p)
 
 		posts = append(posts, p)
 
 		minID = p.ID
 	}
 	if err := rows.Err(); err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list page of posts", err)
 		return nil, -1, err
 	}
 
@@ -434,11 +660,11 @@ func (pr *postRepository) PaginateAdmin(maxID int, perPage int, tags []string) (
 }
 
 // ResetSeq resets the post id sequence in the database
-func (pr *postRepository) ResetSeq() error {
-	row, err := pr.Pool.Query(context.Background(), "SELECT setval(pg_get_serial_sequence('post_schema.post', 'id'), coalesce(max(id),0)+ 1, false) FROM post_schema.post")
+func (pr *postRepository) ResetSeq(ctx context.Context) error {
+	row, err := pr.Pool.Query(ctx, "SELECT setval(pg_get_serial_sequence('post_schema.post', 'id'), coalesce(max(id),0)+ 1, false) FROM post_schema.post")
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to reset post id sequence", err)
 		return err
 	}
 	defer row.Close()
@@ -446,11 +672,11 @@ func (pr *postRepository) ResetSeq() error {
 }
 
 // GetLastID gets the last (highest) ID non-hidden post in the database
-func (pr *postRepository) GetLastID() (int, error) {
+func (pr *postRepository) GetLastID(ctx context.Context) (int, error) {
 	var lastID int
-	err := pr.Pool.QueryRow(context.Background(), "SELECT id FROM post_schema.post WHERE NOT hidden ORDER BY created_at DESC LIMIT 1").Scan(&lastID)
+	err := pr.Pool.QueryRow(ctx, "SELECT id FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden ORDER BY created_at DESC LIMIT 1").Scan(&lastID)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find latest post", err)
 		return -1, err
 	}
 
@@ -458,11 +684,11 @@ func (pr *postRepository) GetLastID() (int, error) {
 }
 
 // GetLastIDAdmin gets the last (highest) ID post (including hidden) in the database
-func (pr *postRepository) GetLastIDAdmin() (int, error) {
+func (pr *postRepository) GetLastIDAdmin(ctx context.Context) (int, error) {
 	var lastID int
-	err := pr.Pool.QueryRow(context.Background(), "SELECT id FROM post_schema.post ORDER BY created_at DESC LIMIT 1").Scan(&lastID)
+	err := pr.Pool.QueryRow(ctx, "SELECT id FROM post_schema.post WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT 1").Scan(&lastID)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find latest post", err)
 		return -1, err
 	}
 
@@ -470,7 +696,7 @@ func (pr *postRepository) GetLastIDAdmin() (int, error) {
 }
 
 // SearchQuery searches using title and tags. Returns results ordered by view count in descending order.
-func (pr *postRepository) SearchQuery(title string, tags []string) ([]*models.Post, error) {
+func (pr *postRepository) SearchQuery(ctx context.Context, title string, tags []string) ([]*models.Post, error) {
 	var posts []*models.Post
 
 	var rows pgx.Rows
@@ -478,45 +704,500 @@ func (pr *postRepository) SearchQuery(title string, tags []string) ([]*models.Po
 
 	// For some reason it needs a separate query for tags to return rows
 	if len(tags) == 0 {
-		rows, err = pr.Pool.Query(context.Background(),
-			"SELECT * FROM post_schema.post WHERE NOT hidden AND LOWER(title) LIKE LOWER('%' || $1 || '%') ORDER BY views DESC LIMIT 5",
+		rows, err = pr.Pool.Query(ctx,
+			"SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND LOWER(title) LIKE LOWER('%' || $1 || '%') ORDER BY views DESC LIMIT 5",
 			title,
 		)
 	} else {
-		rows, err = pr.Pool.Query(context.Background(),
-			"SELECT * FROM post_schema.post WHERE NOT hidden AND LOWER(title) LIKE LOWER('%' || $1 || '%') AND tags @> $2 ORDER BY views DESC LIMIT 5",
+		rows, err = pr.Pool.Query(ctx,
+			"SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND LOWER(title) LIKE LOWER('%' || $1 || '%') AND tags @> $2 ORDER BY views DESC LIMIT 5",
 			title, tags,
 		)
 	}
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to search posts", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		p := new(models.Post)
-		err := rows.Scan(&p.ID, &p.Title, &p.Slug, &p.Body, &p.CreatedAt, &p.UpdatedAt, &p.Tags, &p.Hidden, &p.AuthorID, &p.FeatureImgURL, &p.Subtitle, &p.Views)
+		err := rows.Scan(postFields(p)...)
 		if err != nil {
-			log.Println(err)
+			logError(ctx, "Failed to search posts", err)
 			return nil, err
 		}
 
-		// Limit p.Body to 250 characters
-		limit := len(p.Body)
-		if len(p.Body) > 250 {
-			limit = 250
+		toListing(p)
+
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetArchive returns the number of non-hidden posts grouped by year and month, newest first
+func (pr *postRepository) GetArchive(ctx context.Context) ([]*models.ArchiveYear, error) {
+	var years []*models.ArchiveYear
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT date_part('year', created_at)::int AS year, date_part('month', created_at)::int AS month, COUNT(*) "+
+			"FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden GROUP BY year, month ORDER BY year DESC, month DESC",
+	)
+	if err != nil {
+		logError(ctx, "Failed to list archive months", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var current *models.ArchiveYear
+	for rows.Next() {
+		var year int
+		m := new(models.ArchiveMonth)
+		err := rows.Scan(&year, &m.Month, &m.Count)
+		if err != nil {
+			logError(ctx, "Failed to list archive months", err)
+			return nil, err
+		}
+
+		if current == nil || current.Year != year {
+			current = &models.ArchiveYear{Year: year, Months: []*models.ArchiveMonth{}}
+			years = append(years, current)
+		}
+		current.Count += m.Count
+		current.Months = append(current.Months, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list archive months", err)
+		return nil, err
+	}
+
+	return years, nil
+}
+
+// GetArchivePostCount returns the number of non-hidden posts in the given year and month (0 for the whole year)
+func (pr *postRepository) GetArchivePostCount(ctx context.Context, year int, month int) (int, error) {
+	var count int
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND date_part('year', created_at)::int = $1 AND ($2 = 0 OR date_part('month', created_at)::int = $2)",
+		year, month,
+	).Scan(&count)
+	if err != nil {
+		logError(ctx, "Failed to count archive posts", err)
+		return -1, err
+	}
+
+	return count, nil
+}
+
+// PaginateArchive returns the keyset page of non-hidden posts in the given year and month (0 for the whole year)
+func (pr *postRepository) PaginateArchive(ctx context.Context, after models.PostCursor, perPage int, year int, month int) ([]*models.Post, int, error) {
+	var posts []*models.Post
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND "+afterCursor(1)+" AND date_part('year', created_at)::int = $3 "+
+			"AND ($4 = 0 OR date_part('month', created_at)::int = $4) ORDER BY created_at DESC, id DESC LIMIT $5",
+		after.CreatedAt, after.ID, year, month, perPage,
+	)
+	if err != nil {
+		logError(ctx, "Failed to list page of archive posts", err)
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	var minID int
+	for rows.Next() {
+		p := new(models.Post)
+		err := rows.Scan(postFields(p)...)
+
+		if err != nil {
+			logError(ctx, "Failed to list page of archive posts", err)
+			return nil, -1, err
+		}
+
+		toListing(p)
+
+		posts = append(posts, p)
+
+		minID = p.ID
+	}
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list page of archive posts", err)
+		return nil, -1, err
+	}
+
+	return posts, minID, nil
+}
+
+// Matches the posts where $1 is the primary author or a listed contributor
+const byAuthorCondition = "(authorid = $1 OR id IN (SELECT post_id FROM post_schema.post_author WHERE user_id = $1))"
+
+// GetAuthorPostCount returns the number of non-hidden posts by the given author
+func (pr *postRepository) GetAuthorPostCount(ctx context.Context, authorID string) (int, error) {
+	var count int
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND "+byAuthorCondition, authorID,
+	).Scan(&count)
+	if err != nil {
+		logError(ctx, "Failed to count author posts", err)
+		return -1, err
+	}
+
+	return count, nil
+}
+
+// PaginateByAuthor returns the keyset page of non-hidden posts by the given author
+func (pr *postRepository) PaginateByAuthor(ctx context.Context, after models.PostCursor, perPage int, authorID string) ([]*models.Post, int, error) {
+	var posts []*models.Post
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT "+postColumns+" FROM post_schema.post WHERE deleted_at IS NULL AND NOT hidden AND "+afterCursor(2)+" AND "+byAuthorCondition+" ORDER BY created_at DESC, id DESC LIMIT $4",
+		authorID, after.CreatedAt, after.ID, perPage,
+	)
+	if err != nil {
+		logError(ctx, "Failed to list page of author posts", err)
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	var minID int
+	for rows.Next() {
+		p := new(models.Post)
+		err := rows.Scan(postFields(p)...)
+
+		if err != nil {
+			logError(ctx, "Failed to list page of author posts", err)
+			return nil, -1, err
 		}
 
-		p.Body = p.Body[:limit]
+		toListing(p)
 
 		posts = append(posts, p)
+
+		minID = p.ID
+	}
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list page of author posts", err)
+		return nil, -1, err
+	}
+
+	return posts, minID, nil
+}
+
+// GetSlugsAndTagsByAuthor returns the slugs and distinct tags of every post by the given author,
+// which are the cache keys that contain the author
+func (pr *postRepository) GetSlugsAndTagsByAuthor(ctx context.Context, authorID string) ([]string, []string, error) {
+	var slugs []string
+	var tags []string
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT COALESCE(array_agg(slug), '{}'), "+
+			"COALESCE((SELECT array_agg(DISTINCT tag) FROM post_schema.post, unnest(tags) AS tag WHERE "+byAuthorCondition+"), '{}') "+
+			"FROM post_schema.post WHERE "+byAuthorCondition,
+		authorID,
+	).Scan(&slugs, &tags)
+	if err != nil {
+		logError(ctx, "Failed to list author post slugs and tags", err)
+		return nil, nil, err
+	}
+
+	return slugs, tags, nil
+}
+
+// GetAuthors returns the contributors of the post in order, without the ones in the trash
+func (pr *postRepository) GetAuthors(ctx context.Context, postID int) ([]*models.PostAuthor, error) {
+	var authors []*models.PostAuthor
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT u.id::text, u.name, u.username, u.avatar_url, pa.role FROM post_schema.post_author pa "+
+			"JOIN user_schema.\"user\" u ON u.id = pa.user_id WHERE u.deleted_at IS NULL AND pa.post_id = $1 ORDER BY pa.position",
+		postID,
+	)
+	if err != nil {
+		logError(ctx, "Failed to list post contributors", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := new(models.PostAuthor)
+		if err := rows.Scan(&a.ID, &a.Name, &a.Username, &a.AvatarURL, &a.Role); err != nil {
+			logError(ctx, "Failed to list post contributors", err)
+			return nil, err
+		}
+		authors = append(authors, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list post contributors", err)
+		return nil, err
+	}
+
+	return authors, nil
+}
+
+// GetAuthorsByPosts returns the contributors of each of the given posts in a single query.
+// Contributors in the trash are left out, and posts without contributors are not in the map.
+func (pr *postRepository) GetAuthorsByPosts(ctx context.Context, postIDs []int) (map[int][]*models.PostAuthor, error) {
+	authors := make(map[int][]*models.PostAuthor, len(postIDs))
+
+	rows, err := pr.Pool.Query(ctx,
+		"SELECT pa.post_id, u.id::text, u.name, u.username, u.avatar_url, pa.role FROM post_schema.post_author pa "+
+			"JOIN user_schema.\"user\" u ON u.id = pa.user_id WHERE u.deleted_at IS NULL AND pa.post_id = ANY($1::int[]) ORDER BY pa.post_id, pa.position",
+		postIDs,
+	)
+	if err != nil {
+		logError(ctx, "Failed to list post contributors", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		a := new(models.PostAuthor)
+		if err := rows.Scan(&postID, &a.ID, &a.Name, &a.Username, &a.AvatarURL, &a.Role); err != nil {
+			logError(ctx, "Failed to list post contributors", err)
+			return nil, err
+		}
+		authors[postID] = append(authors[postID], a)
 	}
 
 	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list post contributors", err)
+		return nil, err
+	}
+
+	return authors, nil
+}
+
+// SetAuthors replaces the contributors of the post in a single transaction. The first contributor
+// with the author role becomes the primary author of the post. Returns the post's new version.
+func (pr *postRepository) SetAuthors(ctx context.Context, postID int, authors []*models.PostAuthor) (int, error) {
+	tx, err := pr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to set post contributors", err)
+		return -1, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM post_schema.post_author WHERE post_id=$1", postID)
+	if err != nil {
+		logError(ctx, "Failed to set post contributors", err)
+		return -1, err
+	}
+
+	primary := ""
+	for i, author := range authors {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO post_schema.post_author (post_id, user_id, role, position) VALUES ($1, $2, $3, $4)",
+			postID, author.ID, author.Role, i,
+		)
+		if err != nil {
+			logError(ctx, "Failed to set post contributors", err)
+			return -1, err
+		}
+		if primary == "" && author.Role == models.RoleAuthor {
+			primary = author.ID
+		}
+	}
+
+	var version int
+	err = tx.QueryRow(ctx,
+		"UPDATE post_schema.post SET authorid=COALESCE(NULLIF($1, '')::uuid, authorid), version=version+1 WHERE id=$2 RETURNING version",
+		primary, postID,
+	).Scan(&version)
+	if err != nil {
+		logError(ctx, "Failed to set post contributors", err)
+		return -1, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logError(ctx, "Failed to set post contributors", err)
+		return -1, err
+	}
+
+	return version, nil
+}
+
+// IsAuthor checks if the user is the primary author or a listed contributor of the post
+func (pr *postRepository) IsAuthor(ctx context.Context, postID int, userID string) bool {
+	var isAuthor bool
+	err := pr.Pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM post_schema.post WHERE deleted_at IS NULL AND id=$2 AND "+byAuthorCondition+")", userID, postID,
+	).Scan(&isAuthor)
+	if err != nil {
+		logError(ctx, "Failed to check post author", err)
+		return false
+	}
+
+	return isAuthor
+}
+
+// BulkUpdate applies the action to every post matching the filter in one transaction and returns
+// the matched posts (without bodies) as they were before the action. Nothing is changed in a dry run.
+func (pr *postRepository) BulkUpdate(ctx context.Context, filter *models.PostFilter, action *models.BulkAction, dryRun bool) ([]*models.Post, error) {
+	tx, err := pr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to apply bulk action to posts", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := filterCondition(filter)
+	rows, err := tx.Query(ctx, "SELECT "+postColumns+" FROM post_schema.post WHERE "+where+" ORDER BY id FOR UPDATE", args...)
+	if err != nil {
+		logError(ctx, "Failed to apply bulk action to posts", err)
+		return nil, err
+	}
+
+	var posts []*models.Post
+	var ids []int
+	for rows.Next() {
+		p := new(models.Post)
+		err := rows.Scan(postFields(p)...)
+		if err != nil {
+			rows.Close()
+			logError(ctx, "Failed to apply bulk action to posts", err)
+			return nil, err
+		}
+		toListing(p)
+		posts = append(posts, p)
+		ids = append(ids, p.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to apply bulk action to posts", err)
+		return nil, err
+	}
+
+	if dryRun || len(ids) == 0 {
+		return posts, nil
+	}
+
+	switch action.Action {
+	case models.BulkHide:
+		_, err = tx.Exec(ctx, "UPDATE post_schema.post SET hidden=true, version=version+1 WHERE id = ANY($1)", ids)
+	case models.BulkPublish:
+		_, err = tx.Exec(ctx, "UPDATE post_schema.post SET hidden=false, version=version+1 WHERE id = ANY($1)", ids)
+	case models.BulkAddTags:
+		_, err = tx.Exec(ctx,
+			"UPDATE post_schema.post SET tags = tags || ARRAY(SELECT t FROM unnest($2::text[]) WITH ORDINALITY AS n(t, i) WHERE t <> ALL(tags) ORDER BY i), "+
+				"version=version+1 WHERE id = ANY($1)",
+			ids, action.Tags,
+		)
+	case models.BulkRemoveTags:
+		_, err = tx.Exec(ctx,
+			"UPDATE post_schema.post SET tags = ARRAY(SELECT t FROM unnest(tags) WITH ORDINALITY AS n(t, i) WHERE t <> ALL($2::text[]) ORDER BY i), "+
+				"version=version+1 WHERE id = ANY($1)",
+			ids, action.Tags,
+		)
+	case models.BulkSetAuthor:
+		err = setPrimaryAuthor(ctx, tx, ids, action.AuthorID)
+	case models.BulkDelete:
+		_, err = tx.Exec(ctx, "UPDATE post_schema.post SET deleted_at=$2, version=version+1 WHERE id = ANY($1)", ids, time.Now().UTC())
+	default:
+		err = errors.New("unknown bulk action: " + action.Action)
+	}
+	if err != nil {
+		logError(ctx, "Failed to apply bulk action to posts", err)
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logError(ctx, "Failed to apply bulk action to posts", err)
 		return nil, err
 	}
 
 	return posts, nil
 }
+
+// Returns the SQL condition matching the posts of the filter and its arguments. Posts in the trash
+// never match.
+func filterCondition(filter *models.PostFilter) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.IDs != nil {
+		conditions = append(conditions, "id = ANY("+arg(filter.IDs)+"::int[])")
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, arg(filter.Tag)+" = ANY(tags)")
+	}
+	if filter.AuthorID != "" {
+		n := arg(filter.AuthorID)
+		conditions = append(conditions, "(authorid::text = "+n+" OR id IN (SELECT post_id FROM post_schema.post_author WHERE user_id::text = "+n+"))")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(filter.From.UTC()))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(filter.To.UTC()))
+	}
+	if filter.Hidden != nil {
+		conditions = append(conditions, "hidden = "+arg(*filter.Hidden))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// Makes the user the primary author of the posts. The previous authors are removed and the other
+// contributors keep their order after the new author.
+func setPrimaryAuthor(ctx context.Context, tx pgx.Tx, ids []int, authorID string) error {
+	for _, id := range ids {
+		rows, err := tx.Query(ctx,
+			"SELECT user_id::text, role FROM post_schema.post_author WHERE post_id=$1 AND role <> $2 AND user_id::text <> $3 ORDER BY position",
+			id, models.RoleAuthor, authorID,
+		)
+		if err != nil {
+			return err
+		}
+		var others []*models.PostAuthor
+		for rows.Next() {
+			a := new(models.PostAuthor)
+			if err := rows.Scan(&a.ID, &a.Role); err != nil {
+				rows.Close()
+				return err
+			}
+			others = append(others, a)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM post_schema.post_author WHERE post_id=$1", id)
+		if err != nil {
+			return err
+		}
+		authors := append([]*models.PostAuthor{{AuthorSummary: models.AuthorSummary{ID: authorID}, Role: models.RoleAuthor}}, others...)
+		for i, a := range authors {
+			_, err = tx.Exec(ctx,
+				"INSERT INTO post_schema.post_author (post_id, user_id, role, position) VALUES ($1, $2, $3, $4)",
+				id, a.ID, a.Role, i,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := tx.Exec(ctx, "UPDATE post_schema.post SET authorid=$2, version=version+1 WHERE id = ANY($1)", ids, authorID)
+	return err
+}
+
+// Drops the body from a post in a listing, which only needs the excerpt and reading stats
+func toListing(p *models.Post) {
+	p.Body = ""
+	p.BodyHTML = ""
+	p.TOC = nil
+}
@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// SeriesRepository interface
+type SeriesRepository interface {
+	Create(ctx context.Context, s *models.Series, postIDs []int) error
+	Update(ctx context.Context, s *models.Series, postIDs []int) error
+	Delete(ctx context.Context, id int) error
+	GetAll(ctx context.Context) ([]*models.Series, error)
+	FindByID(ctx context.Context, id int) (*models.Series, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Series, error)
+	Exists(ctx context.Context, slug string) bool
+	GetParts(ctx context.Context, seriesID int, includeHidden bool) ([]*models.SeriesPart, error)
+	GetPartSlugs(ctx context.Context, seriesID int) ([]string, error)
+	FindNavByPostID(ctx context.Context, postID int) (*models.SeriesNav, error)
+}
+
+type seriesRepository struct {
+	*database.Postgres
+}
+
+// NewSeriesRepository - creates a series repository instance
+func NewSeriesRepository(db *database.Postgres) SeriesRepository {
+	return &seriesRepository{db}
+}
+
+// Create creates a new series with the posts as its parts in a single transaction
+func (sr *seriesRepository) Create(ctx context.Context, s *models.Series, postIDs []int) error {
+	tx, err := sr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to insert series", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var sID int
+	err = tx.QueryRow(ctx,
+		"INSERT INTO post_schema.series (title, slug, description, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		s.Title, s.Slug, s.Description, s.CreatedAt.UTC(),
+	).Scan(&sID)
+	if err != nil {
+		logError(ctx, "Failed to insert series", err)
+		return err
+	}
+
+	if err := setParts(ctx, tx, sID, postIDs); err != nil {
+		logError(ctx, "Failed to set series parts", err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logError(ctx, "Failed to insert series", err)
+		return err
+	}
+
+	s.ID = sID
+
+	return nil
+}
+
+// Update updates the series with the given ID and replaces its parts with the posts in a single
+// transaction. The parts are kept if postIDs is nil.
+func (sr *seriesRepository) Update(ctx context.Context, s *models.Series, postIDs []int) error {
+	tx, err := sr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to update series", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		"UPDATE post_schema.series SET title=$1, slug=$2, description=$3, updated_at=$4 WHERE id=$5",
+		s.Title, s.Slug, s.Description, s.UpdatedAt, s.ID,
+	)
+	if err != nil {
+		logError(ctx, "Failed to update series", err)
+		return err
+	}
+
+	if postIDs != nil {
+		if err := setParts(ctx, tx, s.ID, postIDs); err != nil {
+			logError(ctx, "Failed to set series parts", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logError(ctx, "Failed to update series", err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the series with the given ID in the database. The posts in it are kept.
+func (sr *seriesRepository) Delete(ctx context.Context, id int) error {
+	_, err := sr.Pool.Exec(ctx, "DELETE FROM post_schema.series WHERE id=$1", id)
+	if err != nil {
+		logError(ctx, "Failed to delete series", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetAll returns all series without their parts
+func (sr *seriesRepository) GetAll(ctx context.Context) ([]*models.Series, error) {
+	var series []*models.Series
+
+	rows, err := sr.Pool.Query(ctx,
+		"SELECT id, title, slug, description, created_at, updated_at FROM post_schema.series ORDER BY created_at DESC, id DESC",
+	)
+	if err != nil {
+		logError(ctx, "Failed to list series", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s := new(models.Series)
+		err := rows.Scan(&s.ID, &s.Title, &s.Slug, &s.Description, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			logError(ctx, "Failed to list series", err)
+			return nil, err
+		}
+		series = append(series, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list series", err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// FindByID returns the series with the given ID
+func (sr *seriesRepository) FindByID(ctx context.Context, id int) (*models.Series, error) {
+	s := models.Series{}
+
+	err := sr.Pool.QueryRow(ctx,
+		"SELECT id, title, slug, description, created_at, updated_at FROM post_schema.series WHERE id = $1", id,
+	).Scan(&s.ID, &s.Title, &s.Slug, &s.Description, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// FindBySlug returns the series with the given slug
+func (sr *seriesRepository) FindBySlug(ctx context.Context, slug string) (*models.Series, error) {
+	s := models.Series{}
+
+	err := sr.Pool.QueryRow(ctx,
+		"SELECT id, title, slug, description, created_at, updated_at FROM post_schema.series WHERE slug = $1", slug,
+	).Scan(&s.ID, &s.Title, &s.Slug, &s.Description, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Exists checks if a series with the slug already exists in the database
+func (sr *seriesRepository) Exists(ctx context.Context, slug string) bool {
+	var exists bool
+	err := sr.Pool.QueryRow(ctx, "SELECT EXISTS (SELECT id FROM post_schema.series WHERE slug=$1)", slug).Scan(&exists)
+	if err != nil {
+		logError(ctx, "Failed to check series slug", err)
+		return true
+	}
+
+	return exists
+}
+
+// Replaces the parts of the series in the transaction. A post can only belong to one series, so
+// inserting a post that is part of another series fails.
+func setParts(ctx context.Context, tx pgx.Tx, seriesID int, postIDs []int) error {
+	_, err := tx.Exec(ctx, "DELETE FROM post_schema.series_post WHERE series_id=$1", seriesID)
+	if err != nil {
+		return err
+	}
+
+	for i, postID := range postIDs {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO post_schema.series_post (series_id, post_id, position) VALUES ($1, $2, $3)",
+			seriesID, postID, i+1,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetParts returns the posts in the series ordered by their position
+func (sr *seriesRepository) GetParts(ctx context.Context, seriesID int, includeHidden bool) ([]*models.SeriesPart, error) {
+	var parts []*models.SeriesPart
+
+	rows, err := sr.Pool.Query(ctx,
+		"SELECT p.id, p.title, p.slug, p.subtitle, sp.position, p.hidden FROM post_schema.series_post sp "+
+			"JOIN post_schema.post p ON p.id = sp.post_id WHERE sp.series_id = $1 AND p.deleted_at IS NULL AND ($2 OR NOT p.hidden) ORDER BY sp.position",
+		seriesID, includeHidden,
+	)
+	if err != nil {
+		logError(ctx, "Failed to list series parts", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		part := new(models.SeriesPart)
+		err := rows.Scan(&part.ID, &part.Title, &part.Slug, &part.Subtitle, &part.Position, &part.Hidden)
+		if err != nil {
+			logError(ctx, "Failed to list series parts", err)
+			return nil, err
+		}
+		// Renumber the public parts so hidden drafts don't leave gaps
+		if !includeHidden {
+			part.Position = len(parts) + 1
+		}
+		parts = append(parts, part)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list series parts", err)
+		return nil, err
+	}
+
+	return parts, nil
+}
+
+// GetPartSlugs returns the slugs of every post (including hidden) in the series
+func (sr *seriesRepository) GetPartSlugs(ctx context.Context, seriesID int) ([]string, error) {
+	parts, err := sr.GetParts(ctx, seriesID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := make([]string, len(parts))
+	for i, part := range parts {
+		slugs[i] = part.Slug
+	}
+
+	return slugs, nil
+}
+
+// FindNavByPostID returns the series the post belongs to with its previous and next public parts.
+// Returns nil if the post isn't part of a series.
+func (sr *seriesRepository) FindNavByPostID(ctx context.Context, postID int) (*models.SeriesNav, error) {
+	nav := models.SeriesNav{}
+
+	err := sr.Pool.QueryRow(ctx,
+		"SELECT s.id, s.title, s.slug FROM post_schema.series s JOIN post_schema.series_post sp ON sp.series_id = s.id WHERE sp.post_id = $1",
+		postID,
+	).Scan(&nav.ID, &nav.Title, &nav.Slug)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logError(ctx, "Failed to find series of post", err)
+		return nil, err
+	}
+
+	parts, err := sr.GetParts(ctx, nav.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	nav.Total = len(parts)
+	for i, part := range parts {
+		if part.ID != postID {
+			continue
+		}
+		nav.Position = part.Position
+		if i > 0 {
+			nav.Previous = parts[i-1]
+		}
+		if i < len(parts)-1 {
+			nav.Next = parts[i+1]
+		}
+		break
+	}
+
+	return &nav, nil
+}
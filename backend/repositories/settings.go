@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+)
+
+// SettingsRepository interface
+type SettingsRepository interface {
+	GetAll(ctx context.Context) (map[string][]byte, *time.Time, error)
+	Save(ctx context.Context, values map[string][]byte, updatedAt time.Time) error
+}
+
+type settingsRepository struct {
+	*database.Postgres
+}
+
+// NewSettingsRepository - creates a site settings repository instance
+func NewSettingsRepository(db *database.Postgres) SettingsRepository {
+	return &settingsRepository{db}
+}
+
+// GetAll returns the stored JSON value of every setting by key, and when a setting was last changed
+func (sr *settingsRepository) GetAll(ctx context.Context) (map[string][]byte, *time.Time, error) {
+	values := make(map[string][]byte)
+	var updatedAt *time.Time
+
+	rows, err := sr.Pool.Query(ctx, "SELECT key, value, updated_at FROM post_schema.site_settings")
+	if err != nil {
+		logError(ctx, "Failed to load settings", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var value []byte
+		var keyUpdatedAt time.Time
+		if err := rows.Scan(&key, &value, &keyUpdatedAt); err != nil {
+			logError(ctx, "Failed to load settings", err)
+			return nil, nil, err
+		}
+		values[key] = value
+		if updatedAt == nil || keyUpdatedAt.After(*updatedAt) {
+			updatedAt = &keyUpdatedAt
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to load settings", err)
+		return nil, nil, err
+	}
+
+	return values, updatedAt, nil
+}
+
+// Save creates or updates the given settings in a single transaction
+func (sr *settingsRepository) Save(ctx context.Context, values map[string][]byte, updatedAt time.Time) error {
+	tx, err := sr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to save settings", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for key, value := range values {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO post_schema.site_settings (key, value, updated_at) VALUES ($1, $2, $3) "+
+				"ON CONFLICT (key) DO UPDATE SET value=$2, updated_at=$3",
+			key, string(value), updatedAt,
+		)
+		if err != nil {
+			logError(ctx, "Failed to save settings", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logError(ctx, "Failed to save settings", err)
+		return err
+	}
+
+	return nil
+}
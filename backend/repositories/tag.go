@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/models"
+)
+
+// TagRepository interface
+type TagRepository interface {
+	GetAll(ctx context.Context, includeHidden bool) ([]*models.Tag, error)
+	FindByName(ctx context.Context, name string, includeHidden bool) (*models.Tag, error)
+	Save(ctx context.Context, t *models.Tag) error
+	Delete(ctx context.Context, name string) error
+	Merge(ctx context.Context, from []string, to string) ([]string, error)
+}
+
+type tagRepository struct {
+	*database.Postgres
+}
+
+// NewTagRepository - creates a tag repository instance
+func NewTagRepository(db *database.Postgres) TagRepository {
+	return &tagRepository{db}
+}
+
+// Selects every tag that is used by a post or has metadata. $1 is whether hidden posts are counted.
+const tagSelectQuery = "SELECT COALESCE(c.name, t.name), COALESCE(t.display_name, ''), COALESCE(t.description, ''), " +
+	"COALESCE(t.color, ''), COALESCE(t.cover_image_url, ''), COALESCE(c.count, 0), t.updated_at " +
+	"FROM (SELECT tag.name, COUNT(*) AS count FROM post_schema.post p CROSS JOIN LATERAL unnest(p.tags) AS tag(name) " +
+	"WHERE p.deleted_at IS NULL AND ($1 OR NOT p.hidden) GROUP BY tag.name) c FULL OUTER JOIN post_schema.tag t ON t.name = c.name"
+
+// GetAll returns all tags ordered by their post count
+func (tr *tagRepository) GetAll(ctx context.Context, includeHidden bool) ([]*models.Tag, error) {
+	var tags []*models.Tag
+
+	rows, err := tr.Pool.Query(ctx, tagSelectQuery+" ORDER BY 6 DESC, 1", includeHidden)
+	if err != nil {
+		logError(ctx, "Failed to list tags", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t := new(models.Tag)
+		err := rows.Scan(&t.Name, &t.DisplayName, &t.Description, &t.Color, &t.CoverImageURL, &t.PostCount, &t.UpdatedAt)
+		if err != nil {
+			logError(ctx, "Failed to list tags", err)
+			return nil, err
+		}
+		if t.DisplayName == "" {
+			t.DisplayName = t.Name
+		}
+		tags = append(tags, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list tags", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// FindByName returns the tag with the given name. Returns pgx.ErrNoRows if no post uses it and it has no metadata.
+func (tr *tagRepository) FindByName(ctx context.Context, name string, includeHidden bool) (*models.Tag, error) {
+	t := models.Tag{}
+
+	err := tr.Pool.QueryRow(ctx,
+		tagSelectQuery+" WHERE COALESCE(c.name, t.name) = $2", includeHidden, name,
+	).Scan(&t.Name, &t.DisplayName, &t.Description, &t.Color, &t.CoverImageURL, &t.PostCount, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if t.DisplayName == "" {
+		t.DisplayName = t.Name
+	}
+
+	return &t, nil
+}
+
+// Save creates or updates the metadata of the tag
+func (tr *tagRepository) Save(ctx context.Context, t *models.Tag) error {
+	_, err := tr.Pool.Exec(ctx,
+		"INSERT INTO post_schema.tag (name, display_name, description, color, cover_image_url, updated_at) VALUES ($1, $2, $3, $4, $5, $6) "+
+			"ON CONFLICT (name) DO UPDATE SET display_name=$2, description=$3, color=$4, cover_image_url=$5, updated_at=$6",
+		t.Name, t.DisplayName, t.Description, t.Color, t.CoverImageURL, t.UpdatedAt,
+	)
+	if err != nil {
+		logError(ctx, "Failed to save tag", err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the metadata of the tag. Posts using the tag are not changed.
+func (tr *tagRepository) Delete(ctx context.Context, name string) error {
+	_, err := tr.Pool.Exec(ctx, "DELETE FROM post_schema.tag WHERE name=$1", name)
+	if err != nil {
+		logError(ctx, "Failed to delete tag", err)
+		return err
+	}
+
+	return nil
+}
+
+// Merge replaces the from tags with the to tag on every post in a single transaction. If the to tag
+// has no metadata yet, it takes the metadata of the first from tag that has some.
+// Returns the slugs of the posts that were changed.
+func (tr *tagRepository) Merge(ctx context.Context, from []string, to string) ([]string, error) {
+	tx, err := tr.Pool.Begin(ctx)
+	if err != nil {
+		logError(ctx, "Failed to merge tags", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var slugs []string
+	for _, name := range from {
+		rows, err := tx.Query(ctx,
+			"UPDATE post_schema.post SET tags = CASE WHEN $2 = ANY(tags) THEN array_remove(tags, $1) ELSE array_replace(tags, $1, $2) END, version=version+1 "+
+				"WHERE $1 = ANY(tags) RETURNING slug",
+			name, to,
+		)
+		if err != nil {
+			logError(ctx, "Failed to merge tags", err)
+			return nil, err
+		}
+		for rows.Next() {
+			var slug string
+			if err := rows.Scan(&slug); err != nil {
+				rows.Close()
+				logError(ctx, "Failed to merge tags", err)
+				return nil, err
+			}
+			slugs = append(slugs, slug)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			logError(ctx, "Failed to merge tags", err)
+			return nil, err
+		}
+
+		_, err = tx.Exec(ctx,
+			"UPDATE post_schema.tag SET name=$2 WHERE name=$1 AND NOT EXISTS (SELECT 1 FROM post_schema.tag WHERE name=$2)",
+			name, to,
+		)
+		if err != nil {
+			logError(ctx, "Failed to merge tags", err)
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM post_schema.tag WHERE name = ANY($1)", from)
+	if err != nil {
+		logError(ctx, "Failed to merge tags", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logError(ctx, "Failed to merge tags", err)
+		return nil, err
+	}
+
+	return slugs, nil
+}
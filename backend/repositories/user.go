@@ -2,7 +2,8 @@ package repositories
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/alanqchen/Bear-Post/backend/database"
 	"github.com/alanqchen/Bear-Post/backend/models"
@@ -12,31 +13,46 @@ import (
 
 // UserRepository interface defines all functions that interact with the database
 type UserRepository interface {
-	Create(u *models.User) error
-	CreateFirstAdmin(u *models.User) (bool, error)
-	GetAll() ([]*models.User, error)
-	GetAllDetailed() ([]*models.AuthUser, error)
-	FindByID(id string) (*models.User, error)
-	FindByIDDetailed(id string) (*models.User, error)
-	FindByEmail(email string) (*models.User, error)
-	FindByUsername(username string) (*models.User, error)
-	Exists(email string) bool
-	ExistsUsername(username string) bool
-	Delete(id string) error
-	Update(u *models.User) error
+	Create(ctx context.Context, u *models.User) error
+	CreateFirstAdmin(ctx context.Context, u *models.User) (bool, error)
+	GetAll(ctx context.Context) ([]*models.User, error)
+	GetAllDetailed(ctx context.Context) ([]*models.AuthUser, error)
+	FindByID(ctx context.Context, id string) (*models.User, error)
+	FindByIDDetailed(ctx context.Context, id string) (*models.User, error)
+	FindByIDs(ctx context.Context, ids []string) ([]*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	Exists(ctx context.Context, email string) bool
+	ExistsUsername(ctx context.Context, username string) bool
+	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, u *models.User) error
+	UpdateProfile(ctx context.Context, u *models.User) error
+	GetTrash(ctx context.Context) ([]*models.User, error)
+	FindDeletedByID(ctx context.Context, id string) (*models.User, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
 }
 
 type userRepository struct {
 	*database.Postgres
 }
 
+// Columns of the public profile of a user, in the order of profileFields
+const profileColumns = "bio, avatar_url, website, social_handles"
+
+// Returns the scan destinations of a user's profile, in the order of profileColumns
+func profileFields(u *models.User) []interface{} {
+	return []interface{}{&u.Bio, &u.AvatarURL, &u.Website, &u.SocialHandles}
+}
+
 // NewUserRespository returns a new user repository
 func NewUserRespository(db *database.Postgres) UserRepository {
 	return &userRepository{db}
 }
 
 // Create creates a new user in the database for the given user model
-func (ur *userRepository) Create(u *models.User) error {
+func (ur *userRepository) Create(ctx context.Context, u *models.User) error {
 
 	// Check if an user already exists with the email
 	// Prepare statement for inserting data
@@ -49,30 +65,30 @@ func (ur *userRepository) Create(u *models.User) error {
 	*/
 
 	//defer ur.Conn.Close(context.Background())
-	_, err := ur.Pool.Exec(context.Background(),
+	_, err := ur.Pool.Exec(ctx,
 		"INSERT INTO user_schema.\"user\"(id, name, email, password, created_at, admin, username) VALUES ($1, $2, $3, $4, $5, $6, $7)",
 		u.ID, u.Name, u.Email, u.Password, (u.CreatedAt.UTC()), u.Admin, u.Username,
 	)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to insert user", err)
 		return err
 	}
 
 	return nil
 }
 
-func (ur *userRepository) CreateFirstAdmin(u *models.User) (bool, error) {
-	users, err := ur.GetAll()
+func (ur *userRepository) CreateFirstAdmin(ctx context.Context, u *models.User) (bool, error) {
+	// Users in the trash still count, so emptying the user list doesn't reopen the first admin signup
+	var numUsers int
+	err := ur.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM user_schema.\"user\"").Scan(&numUsers)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to create first admin", err)
 		return false, err
 	}
-	numUsers := len(users)
 	if numUsers != 0 {
-		log.Println("[WARN] Invalid request to create first admin")
-		log.Println(err)
-		return false, err
+		slog.WarnContext(ctx, "Invalid request to create first admin")
+		return false, nil
 	}
 	// There are no users yet, create admin
 	/*
@@ -83,12 +99,12 @@ func (ur *userRepository) CreateFirstAdmin(u *models.User) (bool, error) {
 		}
 	*/
 
-	_, err = ur.Pool.Exec(context.Background(),
+	_, err = ur.Pool.Exec(ctx,
 		"INSERT INTO user_schema.\"user\"(id, name, email, password, created_at, admin, username) VALUES ($1, $2, $3, $4, $5, $6, $7)",
 		u.ID, u.Name, u.Email, u.Password, (u.CreatedAt.UTC()), u.Admin, u.Username,
 	)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to create first admin", err)
 		return false, err
 	}
 
@@ -97,7 +113,7 @@ func (ur *userRepository) CreateFirstAdmin(u *models.User) (bool, error) {
 }
 
 // Update a user in the database
-func (ur *userRepository) Update(u *models.User) error {
+func (ur *userRepository) Update(ctx context.Context, u *models.User) error {
 	// Check if an user already exists with the email
 	// Prepare statement for inserting data
 	/*
@@ -111,18 +127,32 @@ func (ur *userRepository) Update(u *models.User) error {
 	var err error
 
 	if u.Password == "" {
-		_, err = ur.Pool.Exec(context.Background(),
-			"UPDATE user_schema.user SET name=$1, email=$2, updated_at=$3, username=$4, admin=$5 WHERE id=$6",
+		_, err = ur.Pool.Exec(ctx,
+			"UPDATE user_schema.user SET name=$1, email=$2, updated_at=$3, username=$4, admin=$5 WHERE id=$6 AND deleted_at IS NULL",
 			u.Name, u.Email, u.UpdatedAt, u.Username, u.Admin, u.ID,
 		)
 	} else {
-		_, err = ur.Pool.Exec(context.Background(),
-			"UPDATE user_schema.user SET name=$1, email=$2, password=$3, updated_at=$4, username=$5, admin=$6 WHERE id=$7",
+		_, err = ur.Pool.Exec(ctx,
+			"UPDATE user_schema.user SET name=$1, email=$2, password=$3, updated_at=$4, username=$5, admin=$6 WHERE id=$7 AND deleted_at IS NULL",
 			u.Name, u.Email, u.Password, u.UpdatedAt, u.Username, u.Admin, u.ID,
 		)
 	}
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to update user", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateProfile updates the public profile of the user in the database
+func (ur *userRepository) UpdateProfile(ctx context.Context, u *models.User) error {
+	_, err := ur.Pool.Exec(ctx,
+		"UPDATE user_schema.user SET bio=$1, avatar_url=$2, website=$3, social_handles=$4, updated_at=$5 WHERE id=$6 AND deleted_at IS NULL",
+		u.Bio, u.AvatarURL, u.Website, u.SocialHandles, u.UpdatedAt, u.ID,
+	)
+	if err != nil {
+		logError(ctx, "Failed to update user profile", err)
 		return err
 	}
 
@@ -130,12 +160,12 @@ func (ur *userRepository) Update(u *models.User) error {
 }
 
 // GetAll returns all users' basic information from the database
-func (ur *userRepository) GetAll() ([]*models.User, error) {
+func (ur *userRepository) GetAll(ctx context.Context) ([]*models.User, error) {
 	var users []*models.User
 
-	rows, err := ur.Pool.Query(context.Background(), "SELECT id, name, admin, created_at, updated_at FROM user_schema.\"user\"")
+	rows, err := ur.Pool.Query(ctx, "SELECT id, name, admin, created_at, updated_at FROM user_schema.\"user\" WHERE deleted_at IS NULL")
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list users", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -145,7 +175,7 @@ func (ur *userRepository) GetAll() ([]*models.User, error) {
 		err := rows.Scan(&u.ID, &u.Name, &u.Admin, &u.CreatedAt, &u.UpdatedAt)
 
 		if err != nil {
-			log.Println(err)
+			logError(ctx, "Failed to list users", err)
 			return nil, err
 		}
 		u.Email = ""
@@ -153,7 +183,7 @@ func (ur *userRepository) GetAll() ([]*models.User, error) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list users", err)
 		return nil, err
 	}
 
@@ -161,12 +191,12 @@ func (ur *userRepository) GetAll() ([]*models.User, error) {
 }
 
 // GetAllDetailed returns all users' information from the database
-func (ur *userRepository) GetAllDetailed() ([]*models.AuthUser, error) {
+func (ur *userRepository) GetAllDetailed(ctx context.Context) ([]*models.AuthUser, error) {
 	var users []*models.AuthUser
 
-	rows, err := ur.Pool.Query(context.Background(), "SELECT id, name, email, admin, created_at, updated_at, username FROM user_schema.\"user\"")
+	rows, err := ur.Pool.Query(ctx, "SELECT id, name, email, admin, created_at, updated_at, username FROM user_schema.\"user\" WHERE deleted_at IS NULL")
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list users", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -181,14 +211,14 @@ func (ur *userRepository) GetAllDetailed() ([]*models.AuthUser, error) {
 		)
 
 		if err != nil {
-			log.Println(err)
+			logError(ctx, "Failed to list users", err)
 			return nil, err
 		}
 		users = append(users, authUser)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to list users", err)
 		return nil, err
 	}
 
@@ -196,11 +226,11 @@ func (ur *userRepository) GetAllDetailed() ([]*models.AuthUser, error) {
 }
 
 // FindByEmail is not used anymore in latest version. Kept for compatibility
-func (ur *userRepository) FindByEmail(email string) (*models.User, error) {
+func (ur *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	user := models.User{}
 
-	err := ur.Pool.QueryRow(context.Background(), "SELECT * FROM user_schema.\"user\" WHERE email = $1", email).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Password, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username,
+	err := ur.Pool.QueryRow(ctx, "SELECT id, name, email, password, admin, created_at, updated_at, username, "+profileColumns+" FROM user_schema.\"user\" WHERE deleted_at IS NULL AND email = $1", email).Scan(
+		append([]interface{}{&user.ID, &user.Name, &user.Email, &user.Password, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username}, profileFields(&user)...)...,
 	)
 
 	if err != nil && err != pgx.ErrNoRows {
@@ -211,11 +241,11 @@ func (ur *userRepository) FindByEmail(email string) (*models.User, error) {
 }
 
 // FindByUsername returns the user's information with the given username from the database
-func (ur *userRepository) FindByUsername(username string) (*models.User, error) {
+func (ur *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	user := models.User{}
 
-	err := ur.Pool.QueryRow(context.Background(), "SELECT * FROM user_schema.\"user\" WHERE username = $1", username).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Password, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username,
+	err := ur.Pool.QueryRow(ctx, "SELECT id, name, email, password, admin, created_at, updated_at, username, "+profileColumns+" FROM user_schema.\"user\" WHERE deleted_at IS NULL AND username = $1", username).Scan(
+		append([]interface{}{&user.ID, &user.Name, &user.Email, &user.Password, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username}, profileFields(&user)...)...,
 	)
 
 	if err != nil && err != pgx.ErrNoRows {
@@ -226,15 +256,15 @@ func (ur *userRepository) FindByUsername(username string) (*models.User, error)
 }
 
 // FindByID returns the user's basic information with the given ID from the database
-func (ur *userRepository) FindByID(id string) (*models.User, error) {
+func (ur *userRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
 	user := models.User{}
 
-	err := ur.Pool.QueryRow(context.Background(),
-		"SELECT id, name, admin, created_at, updated_at FROM user_schema.\"user\" WHERE id = $1", id,
-	).Scan(&user.ID, &user.Name, &user.Admin, &user.CreatedAt, &user.UpdatedAt)
+	err := ur.Pool.QueryRow(ctx,
+		"SELECT id, name, admin, created_at, updated_at, username, "+profileColumns+" FROM user_schema.\"user\" WHERE deleted_at IS NULL AND id = $1", id,
+	).Scan(append([]interface{}{&user.ID, &user.Name, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username}, profileFields(&user)...)...)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find user by id", err)
 		return nil, err
 	}
 	user.Email = ""
@@ -242,51 +272,165 @@ func (ur *userRepository) FindByID(id string) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByIDs returns the basic information of the users with the given IDs in a single query.
+// IDs of missing users are skipped.
+func (ur *userRepository) FindByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	var users []*models.User
+
+	rows, err := ur.Pool.Query(ctx,
+		"SELECT id, name, admin, created_at, updated_at, username, "+profileColumns+" FROM user_schema.\"user\" WHERE deleted_at IS NULL AND id::text = ANY($1::text[])", ids,
+	)
+	if err != nil {
+		logError(ctx, "Failed to find users by id", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := new(models.User)
+		err := rows.Scan(append([]interface{}{&user.ID, &user.Name, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username}, profileFields(user)...)...)
+		if err != nil {
+			logError(ctx, "Failed to find users by id", err)
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to find users by id", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
 // FindByIDDetailed returns the user's information with the given ID from the database
-func (ur *userRepository) FindByIDDetailed(id string) (*models.User, error) {
+func (ur *userRepository) FindByIDDetailed(ctx context.Context, id string) (*models.User, error) {
 	user := models.User{}
 
-	err := ur.Pool.QueryRow(context.Background(),
-		"SELECT id, name, email, admin, created_at, updated_at, username FROM user_schema.\"user\" WHERE id = $1", id,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username)
+	err := ur.Pool.QueryRow(ctx,
+		"SELECT id, name, email, admin, created_at, updated_at, username, "+profileColumns+" FROM user_schema.\"user\" WHERE deleted_at IS NULL AND id = $1", id,
+	).Scan(append([]interface{}{&user.ID, &user.Name, &user.Email, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username}, profileFields(&user)...)...)
 
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to find user by id", err)
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-// Exists is not used anymore in latest version. Kept for compatibility
-func (ur *userRepository) Exists(email string) bool {
+// Exists is not used anymore in latest version. Kept for compatibility.
+// Users in the trash keep their email so they can be restored.
+func (ur *userRepository) Exists(ctx context.Context, email string) bool {
 	var exists pgtype.Bool
-	err := ur.Pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT user_schema.\"user\".email FROM user_schema.\"user\" WHERE email = $1)", email).Scan(&exists)
+	err := ur.Pool.QueryRow(ctx, "SELECT EXISTS(SELECT user_schema.\"user\".email FROM user_schema.\"user\" WHERE email = $1)", email).Scan(&exists)
 	if err != nil && err != pgx.ErrNoRows {
-		log.Println(err)
+		logError(ctx, "Failed to check user email", err)
 		return true
 	}
 	return exists.Bool
 }
 
-// ExistsUsername checks if a user with the given username exists in the database
-func (ur *userRepository) ExistsUsername(username string) bool {
+// ExistsUsername checks if a user with the given username exists in the database.
+// Users in the trash keep their username so they can be restored.
+func (ur *userRepository) ExistsUsername(ctx context.Context, username string) bool {
 	var exists pgtype.Bool
-	err := ur.Pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT user_schema.\"user\".username FROM user_schema.\"user\" WHERE username = $1)", username).Scan(&exists)
+	err := ur.Pool.QueryRow(ctx, "SELECT EXISTS(SELECT user_schema.\"user\".username FROM user_schema.\"user\" WHERE username = $1)", username).Scan(&exists)
 	if err != nil && err != pgx.ErrNoRows {
-		log.Println(err)
+		logError(ctx, "Failed to check username", err)
 		return true
 	}
 	return exists.Bool
 }
 
-// Delete deletes the user with the given ID from the database
-func (ur *userRepository) Delete(id string) error {
-	_, err := ur.Pool.Exec(context.Background(), "DELETE FROM user_schema.\"user\" WHERE id = $1", id)
+// Delete moves the user with the given ID to the trash
+func (ur *userRepository) Delete(ctx context.Context, id string) error {
+	_, err := ur.Pool.Exec(ctx, "UPDATE user_schema.\"user\" SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", time.Now().UTC(), id)
 	if err != nil {
-		log.Println(err)
+		logError(ctx, "Failed to move user to trash", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetTrash returns the basic information of the users in the trash, most recently deleted first
+func (ur *userRepository) GetTrash(ctx context.Context) ([]*models.User, error) {
+	var users []*models.User
+
+	rows, err := ur.Pool.Query(ctx,
+		"SELECT id, name, admin, created_at, updated_at, username, deleted_at FROM user_schema.\"user\" WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC",
+	)
+	if err != nil {
+		logError(ctx, "Failed to list users in trash", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := new(models.User)
+		err := rows.Scan(&u.ID, &u.Name, &u.Admin, &u.CreatedAt, &u.UpdatedAt, &u.Username, &u.DeletedAt)
+		if err != nil {
+			logError(ctx, "Failed to list users in trash", err)
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError(ctx, "Failed to list users in trash", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FindDeletedByID returns the user's basic information with the given ID if the user is in the trash
+func (ur *userRepository) FindDeletedByID(ctx context.Context, id string) (*models.User, error) {
+	user := models.User{}
+
+	err := ur.Pool.QueryRow(ctx,
+		"SELECT id, name, admin, created_at, updated_at, username, deleted_at FROM user_schema.\"user\" WHERE deleted_at IS NOT NULL AND id = $1", id,
+	).Scan(&user.ID, &user.Name, &user.Admin, &user.CreatedAt, &user.UpdatedAt, &user.Username, &user.DeletedAt)
+	if err != nil {
+		logError(ctx, "Failed to find user in trash", err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Restore takes the user with the given ID out of the trash
+func (ur *userRepository) Restore(ctx context.Context, id string) error {
+	_, err := ur.Pool.Exec(ctx, "UPDATE user_schema.\"user\" SET deleted_at = NULL WHERE id = $1", id)
+	if err != nil {
+		logError(ctx, "Failed to restore user", err)
 		return err
 	}
 
 	return nil
 }
+
+// Purge permanently deletes the user with the given ID if the user is in the trash
+func (ur *userRepository) Purge(ctx context.Context, id string) error {
+	_, err := ur.Pool.Exec(ctx, "DELETE FROM user_schema.\"user\" WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		logError(ctx, "Failed to purge user", err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes the users that were moved to the trash before the cutoff.
+// Returns the number of users deleted.
+func (ur *userRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := ur.Pool.Exec(ctx, "DELETE FROM user_schema.\"user\" WHERE deleted_at < $1", cutoff.UTC())
+	if err != nil {
+		logError(ctx, "Failed to purge users from trash", err)
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
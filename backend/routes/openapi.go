@@ -0,0 +1,757 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/controllers"
+	"github.com/alanqchen/Bear-Post/backend/graph"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/services"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgtype"
+)
+
+// APIPrefix is the path of the API routes, and the server of the OpenAPI document
+const APIPrefix = "/api/v1"
+
+// Authentication an operation requires
+const (
+	authNone = iota
+	authUser
+	authAdmin
+	authRefresh
+	// A token is only needed for some requests
+	authOptional
+)
+
+// Kinds of fields, named after the JSONData getter that reads them
+const (
+	kindString      = "string"
+	kindInt         = "integer"
+	kindBool        = "boolean"
+	kindStringArray = "string[]"
+	kindIntArray    = "integer[]"
+	kindObject      = "object"
+	kindFile        = "file"
+	// A post's contributors as {id, role}
+	kindContributorArray = "contributor[]"
+)
+
+// A query parameter, header or request body field
+type field struct {
+	name        string
+	kind        string
+	required    bool
+	description string
+}
+
+// An extra response of an operation. Its data is sent in an APIResponse, or an APIError if nil.
+type response struct {
+	status      int
+	description string
+	data        interface{}
+}
+
+// An operation documents a route. Responses are APIResponses with data of the type of the data
+// value, unless the operation produces another content type.
+type operation struct {
+	method      string
+	path        string
+	tag         string
+	summary     string
+	description string
+	auth        int
+	// Path parameters are integers when named id, year or month unless listed here
+	params   []field
+	query    []field
+	headers  []field
+	body     []field
+	form     []field
+	bodyType interface{}
+	data     interface{}
+	paginate bool
+	produces string
+	extra    []response
+	// Served outside the API prefix
+	root bool
+}
+
+// Matches the parameters of an OpenAPI path
+var pathParamRegex = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Response data of the authentication routes
+var tokenData = struct {
+	Tokens *services.Tokens `json:"tokens"`
+	User   *models.AuthUser `json:"user"`
+}{}
+
+// Fields of a post's body shared by Create and Update
+var postFields = []field{
+	{"title", kindString, true, "At least 4 characters"},
+	{"subtitle", kindString, true, ""},
+	{"body", kindString, true, "Markdown"},
+	{"excerpt", kindString, false, "Generated from the body if empty"},
+	{"hidden", kindBool, false, ""},
+	{"tags", kindStringArray, false, ""},
+	{"featureImgUrl", kindString, false, "The default feature image if empty"},
+	{"slug", kindString, false, "Custom slug, admins only"},
+	{"slugPinned", kindBool, false, "Keep the slug when the title changes, admins only"},
+	{"authors", kindContributorArray, false, "1 to 10 contributors, the role defaults to author and at least one must be an author"},
+}
+
+// Fields of a page's body shared by Create and Update
+var pageFields = []field{
+	{"title", kindString, true, ""},
+	{"slug", kindString, false, "Generated from the title if empty"},
+	{"body", kindString, true, "Markdown"},
+	{"parentId", kindInt, false, ""},
+	{"sortOrder", kindInt, false, ""},
+	{"template", kindString, false, ""},
+	{"hidden", kindBool, false, ""},
+}
+
+// Query parameters of the paginated post lists
+var pageQuery = []field{
+	{"cursor", kindString, false, "Cursor of the previous page, the posts created before it are returned"},
+	{"maxID", kindInt, false, "Used without a cursor, minID of the previous page or -1 for the first page"},
+	{"num", kindInt, false, "Posts per page, within the site's bounds"},
+}
+
+// Fields of a user's body shared by Create and CreateFirstAdmin
+var userFields = []field{
+	{"name", kindString, true, "2 to 32 characters"},
+	{"email", kindString, true, ""},
+	{"username", kindString, true, ""},
+	{"password", kindString, true, ""},
+}
+
+// Operations lists every route of the API. The router test fails if a route is missing.
+var operations = []operation{
+	{method: http.MethodGet, path: "/", tag: "Status", summary: "Check that the API is online", produces: "text/plain", root: true},
+	{method: http.MethodGet, path: "/healthz", tag: "Status", summary: "Check that the process is alive", root: true},
+	{method: http.MethodGet, path: "/readyz", tag: "Status", summary: "Check that the API and its dependencies are ready", root: true,
+		description: "Checks the Postgres pool, Redis, the schema version and the upload directories. Reports starting or stopping while the API starts or shuts down.", data: &models.Readiness{},
+		extra: []response{{http.StatusServiceUnavailable, "A check failed or the API is starting or stopping", &models.Readiness{}}}},
+	{method: http.MethodGet, path: "/openapi.json", tag: "Status", summary: "This OpenAPI document", produces: "application/json"},
+	{method: http.MethodGet, path: "/docs", tag: "Status", summary: "Swagger UI for this document", produces: "text/html",
+		description: "Swagger UI is served from public/swagger-ui.",
+		extra:       []response{{http.StatusNotFound, "Swagger UI is not installed", nil}}},
+
+	// Uploads
+	{method: http.MethodPost, path: "/images/upload", tag: "Uploads", summary: "Upload an image", auth: authUser,
+		description: "JPEG, PNG, GIF or WebP up to 4MB. A WebP copy is made of other formats.",
+		form:        []field{{"image", kindFile, true, ""}}, data: controllers.UploadImageResponse{}},
+	{method: http.MethodPost, path: "/videos/upload", tag: "Uploads", summary: "Upload a video", auth: authUser,
+		description: "MP4 up to 200MB.",
+		form:        []field{{"video", kindFile, true, ""}}, data: controllers.UploadVideoResponse{}},
+
+	// Users
+	{method: http.MethodGet, path: "/users", tag: "Users", summary: "List users", data: []*models.User{}},
+	{method: http.MethodGet, path: "/users/detailed", tag: "Users", summary: "List users with their role", auth: authUser, data: []*models.AuthUser{}},
+	{method: http.MethodPost, path: "/users", tag: "Users", summary: "Create a user", auth: authAdmin,
+		body: append(userFields, field{"admin", kindBool, false, ""})},
+	{method: http.MethodPost, path: "/users/setup", tag: "Users", summary: "Create the first admin",
+		description: "Only works while there are no users.", body: userFields},
+	{method: http.MethodPut, path: "/users/profile", tag: "Users", summary: "Update your profile", auth: authUser,
+		body: []field{
+			{"bio", kindString, false, ""},
+			{"avatarUrl", kindString, false, "An uploaded image or a http(s) URL"},
+			{"website", kindString, false, "A http(s) URL"},
+			{"socialHandles", kindObject, false, "Handles by social network name"},
+		}, data: &models.User{}},
+	{method: http.MethodGet, path: "/users/{id}", tag: "Users", summary: "Get a user", params: []field{uuidParam}, data: &models.User{}},
+	{method: http.MethodGet, path: "/users/{id}/detailed", tag: "Users", summary: "Get a user with their email and role", auth: authAdmin, params: []field{uuidParam}, data: &models.AuthUser{}},
+	{method: http.MethodDelete, path: "/users/{id}", tag: "Users", summary: "Move a user to the trash", auth: authAdmin, params: []field{uuidParam}, data: ""},
+	{method: http.MethodGet, path: "/protected", tag: "Users", summary: "Get your user ID", auth: authUser, data: ""},
+
+	// Authors
+	{method: http.MethodGet, path: "/authors/{username}", tag: "Authors", summary: "Get an author's profile and posts",
+		query: []field{
+			{"cursor", kindString, false, "Cursor of the previous page, the posts created before it are returned"},
+			{"maxID", kindInt, false, "Used without a cursor, minID of the previous page"},
+			{"num", kindInt, false, "Posts per page, within the site's bounds"},
+		},
+		data: struct {
+			Author *models.AuthorProfile `json:"author"`
+			Posts  []*models.Post        `json:"posts"`
+		}{}, paginate: true},
+
+	// Posts
+	{method: http.MethodGet, path: "/posts/get", tag: "Posts", summary: "List public posts, newest first",
+		query: append(pageQuery,
+			field{"tags", kindStringArray, false, "Only posts with one of the tags"},
+			field{"getAuthorID", kindBool, false, "Include the authors"},
+		), data: []*models.Post{}, paginate: true},
+	{method: http.MethodGet, path: "/posts/admin/get", tag: "Posts", summary: "List posts including hidden ones", auth: authUser,
+		query: append(pageQuery,
+			field{"tags", kindStringArray, false, "Only posts with one of the tags"},
+			field{"getAuthorID", kindBool, false, "Include the authors"},
+		), data: []*models.Post{}, paginate: true},
+	{method: http.MethodGet, path: "/posts/search", tag: "Posts", summary: "Search public posts by title",
+		query: []field{
+			{"title", kindString, true, ""},
+			{"tags", kindStringArray, false, "Only posts with one of the tags"},
+		}, data: []*models.Post{}},
+	{method: http.MethodGet, path: "/posts/{id}", tag: "Posts", summary: "Get a public post", data: &models.Post{}},
+	{method: http.MethodGet, path: "/posts/admin/{id}", tag: "Posts", summary: "Get a post for editing", auth: authUser,
+		description: "The post's version is sent as the ETag.", data: &models.Post{}},
+	{method: http.MethodGet, path: "/posts/admin/{slug}", tag: "Posts", summary: "Get a post for editing by slug", auth: authUser,
+		description: "The post's version is sent as the ETag.",
+		query:       []field{{"getAuthorID", kindBool, false, "Include the authors"}}, data: &models.Post{}},
+	{method: http.MethodGet, path: "/posts/{id}/lock", tag: "Posts", summary: "Get who is editing a post", auth: authUser, data: &models.EditLock{}},
+	{method: http.MethodGet, path: "/posts/{slug}", tag: "Posts", summary: "Get a public post by slug",
+		query: []field{{"getAuthorID", kindBool, false, "Include the authors"}}, data: &models.Post{},
+		extra: []response{{http.StatusMovedPermanently, "The post's slug changed, Location has the new path", struct {
+			Redirect string `json:"redirect"`
+		}{}}}},
+	{method: http.MethodPost, path: "/posts", tag: "Posts", summary: "Create a post", auth: authUser, body: postFields, data: &models.Post{}},
+	{method: http.MethodPost, path: "/posts/bulk", tag: "Posts", summary: "Change many posts at once", auth: authAdmin,
+		description: "Applies the action to the posts with the ids and matching the filter in one transaction. " +
+			"With dryRun the matched posts are returned without changing them.",
+		bodyType: struct {
+			IDs    []int              `json:"ids"`
+			Filter *models.PostFilter `json:"filter"`
+			models.BulkAction
+			DryRun bool `json:"dryRun"`
+		}{}, data: &models.BulkResult{}},
+	{method: http.MethodPut, path: "/posts/{id}", tag: "Posts", summary: "Update a post", auth: authUser,
+		description: "The post's version is required as If-Match or version. The updated post's version is sent as the ETag.",
+		headers:     []field{{"If-Match", kindString, false, "ETag of the post being edited"}},
+		body:        append([]field{{"version", kindInt, false, "Version of the post being edited"}}, postFields...),
+		data:        &models.Post{},
+		extra: []response{
+			{http.StatusConflict, "The post was changed by someone else, data has the current post", &models.Post{}},
+			{http.StatusPreconditionRequired, "The post's version is missing", nil},
+		}},
+	{method: http.MethodDelete, path: "/posts/delete/{id}", tag: "Posts", summary: "Move a post to the trash", auth: authUser,
+		description: "Only the post's authors and admins can delete it.", data: 0,
+		extra: []response{{http.StatusForbidden, "The user is not one of the post's authors or an admin", nil}}},
+	{method: http.MethodPost, path: "/posts/{id}/lock", tag: "Posts", summary: "Lock a post for editing, or refresh your lock", auth: authUser,
+		data:  &models.EditLock{},
+		extra: []response{{http.StatusConflict, "Someone else is editing the post, data has their lock", &models.EditLock{}}}},
+	{method: http.MethodDelete, path: "/posts/{id}/lock", tag: "Posts", summary: "Release your lock on a post", auth: authUser, data: 0},
+
+	// Series
+	{method: http.MethodGet, path: "/series", tag: "Series", summary: "List series", data: []*models.Series{}},
+	{method: http.MethodGet, path: "/series/admin/{id}", tag: "Series", summary: "Get a series including hidden parts", auth: authUser, data: &models.Series{}},
+	{method: http.MethodGet, path: "/series/{slug}", tag: "Series", summary: "Get a series", data: &models.Series{}},
+	{method: http.MethodPost, path: "/series", tag: "Series", summary: "Create a series", auth: authUser,
+		body: []field{
+			{"title", kindString, true, ""},
+			{"description", kindString, false, ""},
+			{"posts", kindIntArray, false, "IDs of the parts in order"},
+		}, data: &models.Series{},
+		extra: []response{{http.StatusConflict, "A post is already part of another series", nil}}},
+	{method: http.MethodPut, path: "/series/{id}", tag: "Series", summary: "Update a series", auth: authUser,
+		body: []field{
+			{"title", kindString, true, ""},
+			{"description", kindString, false, ""},
+			{"posts", kindIntArray, false, "IDs of the parts in order"},
+		}, data: &models.Series{},
+		extra: []response{{http.StatusConflict, "A post is already part of another series", nil}}},
+	{method: http.MethodDelete, path: "/series/{id}", tag: "Series", summary: "Delete a series", auth: authUser, data: 0},
+
+	// Tags
+	{method: http.MethodGet, path: "/tags", tag: "Tags", summary: "List tags of public posts", data: []*models.Tag{}},
+	{method: http.MethodGet, path: "/tags/admin", tag: "Tags", summary: "List tags including hidden posts", auth: authUser, data: []*models.Tag{}},
+	{method: http.MethodPost, path: "/tags/rename", tag: "Tags", summary: "Rename a tag on every post", auth: authAdmin,
+		body: []field{
+			{"from", kindString, true, ""},
+			{"to", kindString, true, ""},
+		}, data: tagMergeData},
+	{method: http.MethodPost, path: "/tags/merge", tag: "Tags", summary: "Merge tags into one", auth: authAdmin,
+		body: []field{
+			{"from", kindStringArray, true, ""},
+			{"to", kindString, true, ""},
+		}, data: tagMergeData},
+	{method: http.MethodGet, path: "/tags/{tag}", tag: "Tags", summary: "Get a tag", data: &models.Tag{}},
+	{method: http.MethodPut, path: "/tags/{tag}", tag: "Tags", summary: "Update a tag's details", auth: authUser,
+		body: []field{
+			{"displayName", kindString, false, ""},
+			{"description", kindString, false, ""},
+			{"color", kindString, false, "Hex color such as #ff8800"},
+			{"coverImageUrl", kindString, false, ""},
+		}, data: &models.Tag{}},
+	{method: http.MethodDelete, path: "/tags/{tag}", tag: "Tags", summary: "Delete a tag's details", auth: authAdmin, data: ""},
+
+	// Archive
+	{method: http.MethodGet, path: "/archive", tag: "Archive", summary: "Count public posts by year and month", data: []*models.ArchiveYear{}},
+	{method: http.MethodGet, path: "/archive/{year}", tag: "Archive", summary: "List public posts of a year",
+		query: pageQuery, data: []*models.Post{}, paginate: true},
+	{method: http.MethodGet, path: "/archive/{year}/{month}", tag: "Archive", summary: "List public posts of a month",
+		query: pageQuery, data: []*models.Post{}, paginate: true},
+
+	// Pages
+	{method: http.MethodGet, path: "/pages", tag: "Pages", summary: "Get the tree of public pages", data: []*models.Page{}},
+	{method: http.MethodGet, path: "/pages/admin", tag: "Pages", summary: "Get the tree of pages including hidden ones", auth: authUser, data: []*models.Page{}},
+	{method: http.MethodGet, path: "/pages/admin/{id}", tag: "Pages", summary: "Get a page for editing", auth: authUser, data: &models.Page{}},
+	{method: http.MethodGet, path: "/pages/search", tag: "Pages", summary: "Search public pages by title",
+		query: []field{{"title", kindString, true, ""}}, data: []*models.Page{}},
+	{method: http.MethodGet, path: "/pages/{slug}", tag: "Pages", summary: "Get a public page", data: &models.Page{}},
+	{method: http.MethodPost, path: "/pages", tag: "Pages", summary: "Create a page", auth: authUser, body: pageFields, data: &models.Page{}},
+	{method: http.MethodPut, path: "/pages/{id}", tag: "Pages", summary: "Update a page", auth: authUser, body: pageFields, data: &models.Page{}},
+	{method: http.MethodDelete, path: "/pages/{id}", tag: "Pages", summary: "Delete a page", auth: authUser, data: 0},
+
+	// Site settings
+	{method: http.MethodGet, path: "/settings", tag: "Settings", summary: "Get the public site settings", data: &models.PublicSiteSettings{}},
+	{method: http.MethodGet, path: "/settings/admin", tag: "Settings", summary: "Get every site setting", auth: authAdmin, data: &models.SiteSettings{}},
+	{method: http.MethodPut, path: "/settings", tag: "Settings", summary: "Update site settings", auth: authAdmin,
+		description: "Settings that are not given keep their value.", bodyType: &models.SiteSettings{}, data: &models.SiteSettings{}},
+
+	// Menus
+	{method: http.MethodGet, path: "/menus", tag: "Menus", summary: "List menus",
+		description: "Items linking to hidden or deleted posts, pages and tags are left out. Posts whose slug changed link to their current slug.", data: []*models.Menu{}},
+	{method: http.MethodPut, path: "/menus/{name}", tag: "Menus", summary: "Create or replace a menu", auth: authAdmin,
+		bodyType: struct {
+			Items []*models.MenuItem `json:"items"`
+		}{}, data: &models.Menu{}},
+	{method: http.MethodDelete, path: "/menus/{name}", tag: "Menus", summary: "Delete a menu", auth: authAdmin, data: ""},
+
+	// Trash
+	{method: http.MethodGet, path: "/trash/posts", tag: "Trash", summary: "List posts in the trash", auth: authAdmin, data: []*models.Post{}},
+	{method: http.MethodPost, path: "/trash/posts/{id}/restore", tag: "Trash", summary: "Restore a post", auth: authAdmin, data: 0},
+	{method: http.MethodDelete, path: "/trash/posts/{id}", tag: "Trash", summary: "Permanently delete a post", auth: authAdmin,
+		query: []field{confirmQuery}, data: 0},
+	{method: http.MethodGet, path: "/trash/users", tag: "Trash", summary: "List users in the trash", auth: authAdmin, data: []*models.User{}},
+	{method: http.MethodPost, path: "/trash/users/{id}/restore", tag: "Trash", summary: "Restore a user", auth: authAdmin, params: []field{uuidParam}, data: ""},
+	{method: http.MethodDelete, path: "/trash/users/{id}", tag: "Trash", summary: "Permanently delete a user", auth: authAdmin,
+		params: []field{uuidParam}, query: []field{confirmQuery}, data: ""},
+
+	// Export and import
+	{method: http.MethodGet, path: "/export", tag: "Export", summary: "Download a zip archive of the posts, users and media", auth: authAdmin, produces: "application/zip"},
+	{method: http.MethodPost, path: "/import", tag: "Export", summary: "Import posts from WordPress, Ghost or Markdown files", auth: authAdmin,
+		description: "Posts whose slug exists are skipped, so an import can be run again.",
+		form: []field{
+			{"file", kindFile, true, "WordPress export (.xml), Ghost export (.json) or zip of Markdown files (.zip)"},
+			{"format", kindString, false, "wxr, ghost or markdown, by default from the file extension"},
+			{"dryRun", kindBool, false, "Report what would be imported without saving"},
+			{"siteUrl", kindString, false, "URL of the Ghost site, used to download its media"},
+		}, data: &models.ImportReport{}},
+
+	// GraphQL
+	{method: http.MethodPost, path: "/graphql", tag: "GraphQL", summary: "Run a GraphQL query or mutation", auth: authOptional, root: true,
+		description: fmt.Sprintf("Queries are public and mutations need a bearer token. Queries nested deeper than %v levels are rejected and "+
+			"fields return an error once a query costs more than %v. The response is a GraphQL response with data and errors.", graph.MaxDepth, graph.MaxComplexity),
+		body: []field{
+			{"query", kindString, true, ""},
+			{"operationName", kindString, false, ""},
+			{"variables", kindObject, false, ""},
+		}, produces: "application/json"},
+
+	// Authentication
+	{method: http.MethodPost, path: "/auth/login", tag: "Authentication", summary: "Log in",
+		body: []field{
+			{"username", kindString, true, ""},
+			{"password", kindString, true, ""},
+		}, data: tokenData},
+	{method: http.MethodGet, path: "/auth/refresh", tag: "Authentication", summary: "Get new tokens", auth: authRefresh, data: tokenData},
+	{method: http.MethodPut, path: "/auth/update", tag: "Authentication", summary: "Update a user's account", auth: authAdmin,
+		body: []field{
+			{"uid", kindString, true, ""},
+			{"name", kindString, true, ""},
+			{"email", kindString, true, ""},
+			{"password", kindString, false, "New password"},
+			{"oldpassword", kindString, false, "Required to change your own password"},
+			{"admin", kindBool, false, ""},
+		}, data: &models.AuthUser{}},
+	{method: http.MethodGet, path: "/auth/logout", tag: "Authentication", summary: "Log out", auth: authUser},
+	{method: http.MethodGet, path: "/auth/logout/all", tag: "Authentication", summary: "Log out every session", auth: authAdmin},
+	{method: http.MethodPost, path: "/auth/verify", tag: "Authentication", summary: "Verify a reCAPTCHA token",
+		body: []field{{"token", kindString, true, ""}}},
+}
+
+var (
+	uuidParam    = field{"id", "uuid", true, ""}
+	confirmQuery = field{"confirm", kindBool, true, "Must be true"}
+)
+
+// Response data of the tag rename and merge routes
+var tagMergeData = struct {
+	From  []string `json:"from"`
+	To    string   `json:"to"`
+	Posts int      `json:"posts"`
+}{}
+
+// OpenAPI returns the OpenAPI 3 document of the API
+func OpenAPI() map[string]interface{} {
+	s := &schemaBuilder{components: map[string]interface{}{}}
+	s.components["APIError"] = object(map[string]interface{}{
+		"success": map[string]interface{}{"type": "boolean"},
+		"message": map[string]interface{}{"type": "string"},
+		"status":  map[string]interface{}{"type": "integer"},
+	})
+	s.components["APIPagination"] = object(map[string]interface{}{
+		"total":   map[string]interface{}{"type": "integer"},
+		"perPage": map[string]interface{}{"type": "integer"},
+		"minID":   map[string]interface{}{"type": "integer", "description": "ID of the last post on the page, the maxID of the next page"},
+		"cursor":  map[string]interface{}{"type": "string", "description": "Position of the last post on the page, the cursor of the next page"},
+	})
+	s.components["APIResponse"] = object(map[string]interface{}{
+		"success":    map[string]interface{}{"type": "boolean"},
+		"message":    map[string]interface{}{"type": "string"},
+		"data":       map[string]interface{}{},
+		"pagination": ref("APIPagination"),
+	})
+
+	paths := map[string]interface{}{}
+	for _, op := range operations {
+		item, ok := paths[op.path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			// Not under the API prefix
+			if op.root {
+				item["servers"] = []interface{}{map[string]interface{}{"url": "/"}}
+			}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = s.operation(op)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Bear Post API",
+			"version":     "1",
+			"description": "Every JSON response is an APIResponse, or an APIError when success is false.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": APIPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": s.components,
+			"securitySchemes": map[string]interface{}{
+				"accessToken": map[string]interface{}{
+					"type": "http", "scheme": "bearer", "bearerFormat": "JWT",
+				},
+				"refreshToken": map[string]interface{}{
+					"type": "http", "scheme": "bearer", "bearerFormat": "JWT",
+					"description": "The refresh token from login",
+				},
+			},
+		},
+	}
+}
+
+// Returns the OpenAPI operation object
+func (s *schemaBuilder) operation(op operation) map[string]interface{} {
+	o := map[string]interface{}{
+		"tags":    []string{op.tag},
+		"summary": op.summary,
+	}
+	description := op.description
+	if op.auth == authAdmin {
+		description = strings.TrimSpace("Admins only. " + description)
+	}
+	if description != "" {
+		o["description"] = description
+	}
+
+	params := []interface{}{}
+	for _, match := range pathParamRegex.FindAllStringSubmatch(op.path, -1) {
+		p := field{match[1], kindString, true, ""}
+		if p.name == "id" || p.name == "year" || p.name == "month" {
+			p.kind = kindInt
+		}
+		for _, override := range op.params {
+			if override.name == p.name {
+				p = override
+			}
+		}
+		params = append(params, parameter("path", p))
+	}
+	for _, f := range op.query {
+		params = append(params, parameter("query", f))
+	}
+	for _, f := range op.headers {
+		params = append(params, parameter("header", f))
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+
+	switch {
+	case op.body != nil:
+		o["requestBody"] = requestBody("application/json", fieldsSchema(op.body))
+	case op.form != nil:
+		o["requestBody"] = requestBody("multipart/form-data", fieldsSchema(op.form))
+	case op.bodyType != nil:
+		o["requestBody"] = requestBody("application/json", s.schema(reflect.TypeOf(op.bodyType)))
+	}
+
+	responses := map[string]interface{}{}
+	if op.produces != "" {
+		responses["200"] = map[string]interface{}{
+			"description": "OK",
+			"content":     map[string]interface{}{op.produces: map[string]interface{}{"schema": producedSchema(op.produces)}},
+		}
+	} else {
+		responses["200"] = s.response("OK", op.data, op.paginate)
+	}
+	switch op.auth {
+	case authUser, authRefresh:
+		responses["401"] = errorResponse("Missing or invalid token")
+		o["security"] = []interface{}{map[string]interface{}{securityScheme(op.auth): []string{}}}
+	case authOptional:
+		responses["401"] = errorResponse("Invalid token")
+		o["security"] = []interface{}{map[string]interface{}{}, map[string]interface{}{securityScheme(op.auth): []string{}}}
+	case authAdmin:
+		responses["401"] = errorResponse("Missing or invalid token")
+		responses["403"] = errorResponse("Not an admin")
+		o["security"] = []interface{}{map[string]interface{}{securityScheme(op.auth): []string{}}}
+	}
+	for _, extra := range op.extra {
+		if extra.data == nil {
+			responses[strconv.Itoa(extra.status)] = errorResponse(extra.description)
+		} else {
+			responses[strconv.Itoa(extra.status)] = s.response(extra.description, extra.data, false)
+		}
+	}
+	responses["default"] = errorResponse("Error")
+	o["responses"] = responses
+	return o
+}
+
+// Returns an APIResponse with data of the type of the value
+func (s *schemaBuilder) response(description string, data interface{}, paginate bool) map[string]interface{} {
+	schema := ref("APIResponse")
+	if data != nil || paginate {
+		properties := map[string]interface{}{}
+		if data != nil {
+			properties["data"] = s.schema(reflect.TypeOf(data))
+		}
+		if paginate {
+			properties["pagination"] = ref("APIPagination")
+		}
+		schema = map[string]interface{}{"allOf": []interface{}{ref("APIResponse"), object(properties)}}
+	}
+	return map[string]interface{}{
+		"description": description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+// Builds JSON schemas of Go types, adding named structs to the components
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+// JSON fields that are never sent, by type
+var hiddenFields = map[reflect.Type][]string{
+	reflect.TypeOf(models.User{}): {"password", "admin"},
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	timestamptzType = reflect.TypeOf(pgtype.Timestamptz{})
+	uuidType        = reflect.TypeOf(uuid.UUID{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// Returns the schema of the type as it's marshalled to JSON
+func (s *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case t == timeType:
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == timestamptzType:
+		schema = map[string]interface{}{"type": "string", "format": "date-time", "nullable": true}
+	case t == uuidType:
+		schema = map[string]interface{}{"type": "string", "format": "uuid"}
+	case t == rawMessageType:
+		schema = map[string]interface{}{}
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			schema = map[string]interface{}{"type": "boolean"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema = map[string]interface{}{"type": "integer"}
+		case reflect.Float32, reflect.Float64:
+			schema = map[string]interface{}{"type": "number"}
+		case reflect.String:
+			schema = map[string]interface{}{"type": "string"}
+		case reflect.Slice, reflect.Array:
+			schema = map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+		case reflect.Map:
+			schema = map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+		case reflect.Struct:
+			if t.Name() == "" {
+				schema = s.structSchema(t)
+			} else {
+				if _, ok := s.components[t.Name()]; !ok {
+					// Set first so recursive types end
+					s.components[t.Name()] = map[string]interface{}{}
+					s.components[t.Name()] = s.structSchema(t)
+				}
+				schema = ref(t.Name())
+			}
+		default:
+			schema = map[string]interface{}{}
+		}
+	}
+
+	if nullable {
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+// Returns the object schema of a struct's JSON fields. Fields of embedded structs are promoted
+// unless the struct has a field of the same name.
+func (s *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	hidden := map[string]bool{}
+	for _, name := range hiddenFields[t] {
+		hidden[name] = true
+	}
+
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			embedded = append(embedded, ft)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if !hidden[name] {
+			properties[name] = s.schema(f.Type)
+		}
+	}
+	for _, et := range embedded {
+		promoted := s.structSchema(et)["properties"].(map[string]interface{})
+		for name, schema := range promoted {
+			if _, ok := properties[name]; !ok && !hidden[name] {
+				properties[name] = schema
+			}
+		}
+	}
+	return object(properties)
+}
+
+// Returns the object schema of request fields
+func fieldsSchema(fields []field) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, f := range fields {
+		schema := kindSchema(f.kind)
+		if f.description != "" {
+			schema["description"] = f.description
+		}
+		properties[f.name] = schema
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+	schema := object(properties)
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Returns the OpenAPI parameter of a field
+func parameter(in string, f field) map[string]interface{} {
+	p := map[string]interface{}{
+		"name":     f.name,
+		"in":       in,
+		"required": f.required,
+		"schema":   kindSchema(f.kind),
+	}
+	if f.description != "" {
+		p["description"] = f.description
+	}
+	if f.kind == kindStringArray && in == "query" {
+		p["explode"] = true
+	}
+	return p
+}
+
+// Returns the schema of a field kind
+func kindSchema(kind string) map[string]interface{} {
+	switch kind {
+	case kindStringArray:
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	case kindIntArray:
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}}
+	case kindFile:
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	case "uuid":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case kindObject:
+		return map[string]interface{}{}
+	case kindContributorArray:
+		contributor := object(map[string]interface{}{
+			"id":   map[string]interface{}{"type": "string", "format": "uuid"},
+			"role": map[string]interface{}{"type": "string", "enum": []string{"author", "editor", "illustrator"}},
+		})
+		contributor["required"] = []string{"id"}
+		return map[string]interface{}{"type": "array", "minItems": 1, "maxItems": 10, "items": contributor}
+	}
+	return map[string]interface{}{"type": kind}
+}
+
+// Returns the schema of a response that isn't JSON
+func producedSchema(contentType string) map[string]interface{} {
+	if contentType == "application/json" {
+		return map[string]interface{}{"type": "object"}
+	}
+	if contentType == "application/zip" {
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+func requestBody(contentType string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"required": true,
+		"content":  map[string]interface{}{contentType: map[string]interface{}{"schema": schema}},
+	}
+}
+
+func errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("APIError")}},
+	}
+}
+
+func securityScheme(auth int) string {
+	if auth == authRefresh {
+		return "refreshToken"
+	}
+	return "accessToken"
+}
+
+func object(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
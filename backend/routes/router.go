@@ -1,11 +1,16 @@
 package routes
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 
 	"github.com/alanqchen/Bear-Post/backend/app"
 	"github.com/alanqchen/Bear-Post/backend/controllers"
+	"github.com/alanqchen/Bear-Post/backend/graph"
+	"github.com/alanqchen/Bear-Post/backend/logging"
+	"github.com/alanqchen/Bear-Post/backend/metrics"
 	"github.com/alanqchen/Bear-Post/backend/middleware"
 	"github.com/alanqchen/Bear-Post/backend/repositories"
 	"github.com/alanqchen/Bear-Post/backend/services"
@@ -13,25 +18,59 @@ import (
 )
 
 // NewRouter creates the routes for the API
-// See the API documentation on the GitHub repo wiki for a user-friendly list
+// Every route is documented in the OpenAPI document built from operations in openapi.go
 func NewRouter(a *app.App) *mux.Router {
 	r := mux.NewRouter()
-	log.Println("Loaded router")
+	slog.Debug("Loaded router")
 	// Repositories
 	ur := repositories.NewUserRespository(a.Database)
 	pr := repositories.NewPostRepository(a.Database)
-	log.Println("Loaded Repositories")
+	sr := repositories.NewSeriesRepository(a.Database)
+	tr := repositories.NewTagRepository(a.Database)
+	pgr := repositories.NewPageRepository(a.Database)
+	mr := repositories.NewMenuRepository(a.Database)
+	slog.Debug("Loaded Repositories")
 	// Services
 	jwtAuth := services.NewJWTAuthService(&a.Config.JWT, a.Redis)
-	log.Println("Loaded Services")
+	a.Go(func(ctx context.Context) {
+		services.RunTrashPurge(ctx, pr, ur, a.Config.TrashRetention())
+	})
+	slog.Debug("Loaded Services")
 	// Controllers
 	ac := controllers.NewAuthController(a, ur, jwtAuth)
 	uc := controllers.NewUserController(a, ur, pr)
-	pc := controllers.NewPostController(a, pr, ur)
+	pc := controllers.NewPostController(a, pr, ur, sr)
+	sc := controllers.NewSeriesController(a, sr, pr)
+	tc := controllers.NewTagController(a, tr, mr)
+	pageController := controllers.NewPageController(a, pgr, ur)
+	settingsController := controllers.NewSettingsController(a, tr)
+	mc := controllers.NewMenuController(a, mr, pr, pgr, tr)
+	authorController := controllers.NewAuthorController(a, ur, pr)
+	archiveController := controllers.NewArchiveController(a, pr, ur)
+	exportController := controllers.NewExportController(a, pr, ur)
+	importController := controllers.NewImportController(a, pr, ur)
 	uploadController := controllers.NewUploadController()
 	ec := controllers.NewErrorController(a)
-	log.Println("Loaded Contollers")
+	healthController := controllers.NewHealthController(a, repositories.NewHealthRepository(a.Database))
+	spec, err := json.Marshal(OpenAPI())
+	if err != nil {
+		logging.Fatal("Failed to build the OpenAPI document", "err", err)
+	}
+	docsController := controllers.NewDocsController(spec)
+	schema, err := graph.NewSchema(pr, ur, tr, a.Settings, pc)
+	if err != nil {
+		logging.Fatal("Failed to parse the GraphQL schema", "err", err)
+	}
+	gqlController := controllers.NewGraphQLController(schema)
+	slog.Debug("Loaded Contollers")
 	r.HandleFunc("/", middleware.Logger(uc.HelloWorld)).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", middleware.Logger(healthController.Live)).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", middleware.Logger(healthController.Ready)).Methods(http.MethodGet)
+	// Metrics are served on the API's port when they don't have their own address
+	if a.Config.Metrics.Enabled() && a.Config.Metrics.Address == "" {
+		r.Handle("/metrics", metrics.Handler(a.Config.Metrics.Token)).Methods(http.MethodGet)
+		slog.Debug("Created metrics route")
+	}
 
 	// Public assets
 	r.Path("/assets/images/{format:.*\\.webp$}").Handler(http.StripPrefix("/assets/images/", middleware.SetCache(http.FileServer(http.Dir("./public/images/webp")))))
@@ -39,23 +78,32 @@ func NewRouter(a *app.App) *mux.Router {
 	r.PathPrefix("/assets/videos").Handler(http.StripPrefix("/assets/videos", middleware.SetCache(http.FileServer(http.Dir("./public/videos/")))))
 	//r.PathPrefix("/public").Handler(http.StripPrefix("/public/", http.FileServer(http.Dir("./public/images/"))))
 
-	api := r.PathPrefix("/api/v1").Subrouter()
+	api := r.PathPrefix(APIPrefix).Subrouter()
+
+	// Documentation
+	api.HandleFunc("/openapi.json", middleware.Logger(docsController.Spec)).Methods(http.MethodGet)
+	api.HandleFunc("/docs", middleware.Logger(docsController.UI)).Methods(http.MethodGet)
+	api.PathPrefix("/docs/").Handler(http.StripPrefix(APIPrefix+"/docs/", middleware.SetCache(http.FileServer(http.Dir(controllers.SwaggerUIDir)))))
+	slog.Debug("Created documentation routes")
 
 	// Uploads
 	api.HandleFunc("/images/upload", middleware.Logger(middleware.RequireAuthentication(a, uploadController.UploadImage, false))).Methods(http.MethodPost)
 	api.HandleFunc("/videos/upload", middleware.Logger(middleware.RequireAuthentication(a, uploadController.UploadVideo, false))).Methods(http.MethodPost)
-	log.Println("Created media uploads route")
+	slog.Debug("Created media uploads route")
 	// Users
 	api.HandleFunc("/users", middleware.Logger(uc.GetAll)).Methods(http.MethodGet)
 	api.HandleFunc("/users/detailed", middleware.Logger(middleware.RequireAuthentication(a, uc.GetAllDetailed, false))).Methods(http.MethodGet)
 	api.HandleFunc("/users", middleware.Logger(middleware.RequireAuthentication(a, uc.Create, true))).Methods(http.MethodPost)
 	api.HandleFunc("/users/setup", middleware.Logger(uc.CreateFirstAdmin)).Methods(http.MethodPost)
+	api.HandleFunc("/users/profile", middleware.Logger(middleware.RequireAuthentication(a, uc.UpdateProfile, false))).Methods(http.MethodPut)
 	api.HandleFunc("/users/{id}", middleware.Logger(uc.GetByID)).Methods(http.MethodGet)
 	api.HandleFunc("/users/{id}/detailed", middleware.Logger(middleware.RequireAuthentication(a, uc.GetByIDDetailed, true))).Methods(http.MethodGet)
 	api.HandleFunc("/users/{id}", middleware.Logger(middleware.RequireAuthentication(a, uc.Delete, true))).Methods(http.MethodDelete)
-	//api.HandleFunc("/users/{id}/posts", middleware.Logger(uc.FindPostsByUser)).Methods(http.MethodGet)
 	api.HandleFunc("/protected", middleware.Logger(middleware.RequireAuthentication(a, uc.Profile, false))).Methods(http.MethodGet)
-	log.Println("Created users routes")
+	slog.Debug("Created users routes")
+	// Authors
+	api.HandleFunc("/authors/{username}", middleware.Logger(authorController.GetByUsername)).Methods(http.MethodGet)
+	slog.Debug("Created authors routes")
 	// Posts
 	api.HandleFunc("/posts/get", middleware.Logger(pc.GetPage)).Methods(http.MethodGet)
 	api.HandleFunc("/posts/admin/get", middleware.Logger(middleware.RequireAuthentication(a, pc.GetPageAdmin, false))).Methods(http.MethodGet)
@@ -63,11 +111,75 @@ func NewRouter(a *app.App) *mux.Router {
 	api.HandleFunc("/posts/{id:[0-9]+}", middleware.Logger(pc.GetByID)).Methods(http.MethodGet)
 	api.HandleFunc("/posts/admin/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pc.GetByIDAdmin, false))).Methods(http.MethodGet)
 	api.HandleFunc("/posts/admin/{slug:[a-zA-Z0-9=\\-\\/]+}", middleware.Logger(middleware.RequireAuthentication(a, pc.GetBySlugAdmin, false))).Methods(http.MethodGet)
+	// Registered before the slug route, which would also match them
+	api.HandleFunc("/posts/{id:[0-9]+}/lock", middleware.Logger(middleware.RequireAuthentication(a, pc.GetLock, false))).Methods(http.MethodGet)
 	api.HandleFunc("/posts/{slug:[a-zA-Z0-9=\\-\\/]+}", middleware.Logger(pc.GetBySlug)).Methods(http.MethodGet)
 	api.HandleFunc("/posts", middleware.Logger(middleware.RequireAuthentication(a, pc.Create, false))).Methods(http.MethodPost)
+	api.HandleFunc("/posts/bulk", middleware.Logger(middleware.RequireAuthentication(a, pc.Bulk, true))).Methods(http.MethodPost)
 	api.HandleFunc("/posts/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pc.Update, false))).Methods(http.MethodPut)
 	api.HandleFunc("/posts/delete/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pc.Delete, false))).Methods(http.MethodDelete)
-	log.Println("Created posts routes")
+	api.HandleFunc("/posts/{id:[0-9]+}/lock", middleware.Logger(middleware.RequireAuthentication(a, pc.Lock, false))).Methods(http.MethodPost)
+	api.HandleFunc("/posts/{id:[0-9]+}/lock", middleware.Logger(middleware.RequireAuthentication(a, pc.Unlock, false))).Methods(http.MethodDelete)
+	slog.Debug("Created posts routes")
+	// Series
+	api.HandleFunc("/series", middleware.Logger(sc.GetAll)).Methods(http.MethodGet)
+	api.HandleFunc("/series/admin/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, sc.GetByIDAdmin, false))).Methods(http.MethodGet)
+	api.HandleFunc("/series/{slug:[a-zA-Z0-9\\-]+}", middleware.Logger(sc.GetBySlug)).Methods(http.MethodGet)
+	api.HandleFunc("/series", middleware.Logger(middleware.RequireAuthentication(a, sc.Create, false))).Methods(http.MethodPost)
+	api.HandleFunc("/series/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, sc.Update, false))).Methods(http.MethodPut)
+	api.HandleFunc("/series/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, sc.Delete, false))).Methods(http.MethodDelete)
+	slog.Debug("Created series routes")
+	// Tags
+	api.HandleFunc("/tags", middleware.Logger(tc.GetAll)).Methods(http.MethodGet)
+	api.HandleFunc("/tags/admin", middleware.Logger(middleware.RequireAuthentication(a, tc.GetAllAdmin, false))).Methods(http.MethodGet)
+	api.HandleFunc("/tags/rename", middleware.Logger(middleware.RequireAuthentication(a, tc.Rename, true))).Methods(http.MethodPost)
+	api.HandleFunc("/tags/merge", middleware.Logger(middleware.RequireAuthentication(a, tc.Merge, true))).Methods(http.MethodPost)
+	api.HandleFunc("/tags/{tag}", middleware.Logger(tc.GetByName)).Methods(http.MethodGet)
+	api.HandleFunc("/tags/{tag}", middleware.Logger(middleware.RequireAuthentication(a, tc.Update, false))).Methods(http.MethodPut)
+	api.HandleFunc("/tags/{tag}", middleware.Logger(middleware.RequireAuthentication(a, tc.Delete, true))).Methods(http.MethodDelete)
+	slog.Debug("Created tags routes")
+	// Archive
+	api.HandleFunc("/archive", middleware.Logger(archiveController.GetAll)).Methods(http.MethodGet)
+	api.HandleFunc("/archive/{year:[0-9]{4}}", middleware.Logger(archiveController.GetPage)).Methods(http.MethodGet)
+	api.HandleFunc("/archive/{year:[0-9]{4}}/{month:[0-9]{1,2}}", middleware.Logger(archiveController.GetPage)).Methods(http.MethodGet)
+	slog.Debug("Created archive routes")
+	// Pages
+	api.HandleFunc("/pages", middleware.Logger(pageController.GetAll)).Methods(http.MethodGet)
+	api.HandleFunc("/pages/admin", middleware.Logger(middleware.RequireAuthentication(a, pageController.GetAllAdmin, false))).Methods(http.MethodGet)
+	api.HandleFunc("/pages/admin/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pageController.GetByIDAdmin, false))).Methods(http.MethodGet)
+	api.HandleFunc("/pages/search", middleware.Logger(pageController.Search)).Methods(http.MethodGet)
+	api.HandleFunc("/pages/{slug:[a-zA-Z0-9\\-]+}", middleware.Logger(pageController.GetBySlug)).Methods(http.MethodGet)
+	api.HandleFunc("/pages", middleware.Logger(middleware.RequireAuthentication(a, pageController.Create, false))).Methods(http.MethodPost)
+	api.HandleFunc("/pages/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pageController.Update, false))).Methods(http.MethodPut)
+	api.HandleFunc("/pages/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pageController.Delete, false))).Methods(http.MethodDelete)
+	slog.Debug("Created pages routes")
+	// Site settings
+	api.HandleFunc("/settings", middleware.Logger(settingsController.GetPublic)).Methods(http.MethodGet)
+	api.HandleFunc("/settings/admin", middleware.Logger(middleware.RequireAuthentication(a, settingsController.GetAdmin, true))).Methods(http.MethodGet)
+	api.HandleFunc("/settings", middleware.Logger(middleware.RequireAuthentication(a, settingsController.Update, true))).Methods(http.MethodPut)
+	slog.Debug("Created settings routes")
+	// Menus
+	api.HandleFunc("/menus", middleware.Logger(mc.GetAll)).Methods(http.MethodGet)
+	api.HandleFunc("/menus/{name}", middleware.Logger(middleware.RequireAuthentication(a, mc.Update, true))).Methods(http.MethodPut)
+	api.HandleFunc("/menus/{name}", middleware.Logger(middleware.RequireAuthentication(a, mc.Delete, true))).Methods(http.MethodDelete)
+	slog.Debug("Created menus routes")
+	// Trash
+	api.HandleFunc("/trash/posts", middleware.Logger(middleware.RequireAuthentication(a, pc.GetTrash, true))).Methods(http.MethodGet)
+	api.HandleFunc("/trash/posts/{id:[0-9]+}/restore", middleware.Logger(middleware.RequireAuthentication(a, pc.Restore, true))).Methods(http.MethodPost)
+	api.HandleFunc("/trash/posts/{id:[0-9]+}", middleware.Logger(middleware.RequireAuthentication(a, pc.Purge, true))).Methods(http.MethodDelete)
+	api.HandleFunc("/trash/users", middleware.Logger(middleware.RequireAuthentication(a, uc.GetTrash, true))).Methods(http.MethodGet)
+	api.HandleFunc("/trash/users/{id}/restore", middleware.Logger(middleware.RequireAuthentication(a, uc.Restore, true))).Methods(http.MethodPost)
+	api.HandleFunc("/trash/users/{id}", middleware.Logger(middleware.RequireAuthentication(a, uc.Purge, true))).Methods(http.MethodDelete)
+	slog.Debug("Created trash routes")
+	// Export
+	api.HandleFunc("/export", middleware.Logger(middleware.RequireAuthentication(a, exportController.Export, true))).Methods(http.MethodGet)
+	slog.Debug("Created export routes")
+	// Import
+	api.HandleFunc("/import", middleware.Logger(middleware.RequireAuthentication(a, importController.Import, true))).Methods(http.MethodPost)
+	slog.Debug("Created import routes")
+	// GraphQL is served at the root, where clients look for it
+	r.HandleFunc("/graphql", middleware.Logger(middleware.OptionalAuthentication(a, gqlController.Query))).Methods(http.MethodPost)
+	slog.Debug("Created GraphQL routes")
 	// Authentication
 	auth := api.PathPrefix("/auth").Subrouter()
 	auth.HandleFunc("/login", middleware.Logger(ac.Authenticate)).Methods(http.MethodPost)
@@ -78,6 +190,6 @@ func NewRouter(a *app.App) *mux.Router {
 	auth.HandleFunc("/verify", middleware.Logger(ac.VerifyCaptcha)).Methods(http.MethodPost)
 	// No Match
 	r.NotFoundHandler = http.HandlerFunc(middleware.Logger(ec.NotFound))
-	log.Println("Created authentication routes")
+	slog.Debug("Created authentication routes")
 	return r
 }
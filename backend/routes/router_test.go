@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alanqchen/Bear-Post/backend/app"
+	"github.com/alanqchen/Bear-Post/backend/config"
+	"github.com/gorilla/mux"
+)
+
+// Builds the router without connecting to the databases
+func newTestRouter(t *testing.T) *mux.Router {
+	dir, err := ioutil.TempDir("", "bearpost-routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt := config.JWTConfig{
+		PrivateKey: filepath.Join(dir, "private.pem"),
+		PublicKey:  filepath.Join(dir, "public.pem"),
+	}
+	writePEM(t, jwt.PrivateKey, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	writePEM(t, jwt.PublicKey, "PUBLIC KEY", public)
+
+	// The trash purge would use the database
+	retention := 0
+	return NewRouter(&app.App{Config: config.Config{JWT: jwt, TrashRetentionDays: &retention}})
+}
+
+func writePEM(t *testing.T, file string, kind string, der []byte) {
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns the OpenAPI path of a route's template, without the parameters' patterns
+func openAPIPath(template string) string {
+	var b strings.Builder
+	depth := 0
+	skip := false
+	for _, c := range template {
+		switch {
+		case c == '{':
+			depth++
+			if depth == 1 {
+				b.WriteRune(c)
+				continue
+			}
+		case c == '}':
+			depth--
+			if depth == 0 {
+				skip = false
+				b.WriteRune(c)
+				continue
+			}
+		case c == ':' && depth == 1:
+			skip = true
+		}
+		if !skip {
+			b.WriteRune(c)
+		}
+	}
+
+	path := strings.TrimPrefix(b.String(), APIPrefix)
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	cases := map[string]string{
+		"/":                              "/",
+		"/api/v1/posts/{id:[0-9]+}/lock": "/posts/{id}/lock",
+		"/api/v1/archive/{year:[0-9]{4}}/{month:[0-9]{1,2}}": "/archive/{year}/{month}",
+		"/api/v1/tags/{tag}": "/tags/{tag}",
+		"/graphql":           "/graphql",
+	}
+	for template, want := range cases {
+		if got := openAPIPath(template); got != want {
+			t.Errorf("openAPIPath(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+// Every route must be in the OpenAPI document, and every operation in it must be a route
+func TestOpenAPICoversRoutes(t *testing.T) {
+	r := newTestRouter(t)
+	paths := OpenAPI()["paths"].(map[string]interface{})
+
+	routes := map[string]bool{}
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// Prefixes such as the assets and subrouters have no methods
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		path := openAPIPath(template)
+		for _, method := range methods {
+			routes[method+" "+path] = true
+			item, ok := paths[path].(map[string]interface{})
+			if !ok {
+				t.Errorf("%v %v is not in the OpenAPI document, add it to operations", method, template)
+				continue
+			}
+			if _, ok := item[strings.ToLower(method)]; !ok {
+				t.Errorf("%v %v is not in the OpenAPI document, add it to operations", method, template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range operations {
+		if !routes[op.method+" "+op.path] {
+			t.Errorf("%v %v is in the OpenAPI document but is not a route", op.method, op.path)
+		}
+	}
+}
+
+// The document must be valid JSON and only reference schemas it defines
+func TestOpenAPIReferences(t *testing.T) {
+	spec, err := json.Marshal(OpenAPI())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	const prefix = `"$ref":"#/components/schemas/`
+	for _, part := range strings.Split(string(spec), prefix)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %v is referenced but not defined", name)
+		}
+	}
+}
+
+func TestDocsRoutes(t *testing.T) {
+	r := newTestRouter(t)
+	for _, path := range []string{"/api/v1/openapi.json", "/api/v1/docs"} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var match mux.RouteMatch
+		if !r.Match(req, &match) || match.MatchErr != nil {
+			t.Errorf("%v is not routed", path)
+		}
+	}
+}
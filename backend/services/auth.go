@@ -8,13 +8,14 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/alanqchen/Bear-Post/backend/config"
 	"github.com/alanqchen/Bear-Post/backend/database"
+	"github.com/alanqchen/Bear-Post/backend/logging"
 	"github.com/alanqchen/Bear-Post/backend/models"
 	"github.com/alanqchen/Bear-Post/backend/util"
 	"github.com/dgrijalva/jwt-go"
@@ -90,7 +91,7 @@ func (jwtService *jwtAuthService) GenerateTokens(u *models.User) (*Tokens, error
 	// Gen UUID for JWT
 	uuidJWT, err := uuid.NewV4()
 	if err != nil {
-		log.Println(err)
+		slog.Error("Failed to generate token ID", "err", err)
 		return nil, err
 	}
 
@@ -110,20 +111,20 @@ func (jwtService *jwtAuthService) GenerateTokens(u *models.User) (*Tokens, error
 
 	accessTokenString, err := accessToken.SignedString([]byte(jwtService.secret))
 	if err != nil {
-		log.Println(err)
+		slog.Error("Failed to sign access token", "err", err)
 		return nil, err
 	}
 
 	err = jwtService.Redis.Set(tokenHash+"."+authClaims.Id, u.ID, TokenDuration).Err()
 	if err != nil {
-		log.Println(err)
+		slog.Error("Failed to store access token", "err", err)
 		return nil, err
 	}
 
 	// Gen UUID for refresh token
 	uuidJWT, err = uuid.NewV4()
 	if err != nil {
-		log.Println(err)
+		slog.Error("Failed to generate token ID", "err", err)
 		return nil, err
 	}
 
@@ -133,13 +134,13 @@ func (jwtService *jwtAuthService) GenerateTokens(u *models.User) (*Tokens, error
 	refreshToken.Claims = authClaims
 	refreshTokenString, err := refreshToken.SignedString(jwtService.privateKey)
 	if err != nil {
-		log.Println(err)
+		slog.Error("Failed to sign refresh token", "err", err)
 		return nil, err
 	}
 
 	err = jwtService.Redis.Set(tokenHash+"."+authClaims.Id, u.ID, RefreshTokenDuration).Err()
 	if err != nil {
-		log.Println(err)
+		slog.Error("Failed to store refresh token", "err", err)
 		return nil, err
 	}
 
@@ -366,7 +367,7 @@ func ContextWithUserID(ctx context.Context, uID string) context.Context {
 func UserIDFromContext(ctx context.Context) (string, error) {
 	uID, ok := ctx.Value(userIDCtxKey).(string)
 	if !ok {
-		log.Println("Context missing userID")
+		slog.DebugContext(ctx, "Context missing userID")
 		return "", errors.New("[SERVICE]: Context missing userID")
 	}
 
@@ -382,7 +383,7 @@ func ContextWithUser(ctx context.Context, u *models.User) context.Context {
 func UserFromContext(ctx context.Context) (*models.User, error) {
 	u, ok := ctx.Value(userCtxKey).(*models.User)
 	if !ok {
-		log.Println("Context missing user")
+		slog.DebugContext(ctx, "Context missing user")
 		return nil, errors.New("[SERVICE]: Context missing user")
 	}
 
@@ -393,7 +394,7 @@ func UserFromContext(ctx context.Context) (*models.User, error) {
 func getPrivateKey(jwtConfig *config.JWTConfig) *rsa.PrivateKey {
 	privateKeyFile, err := os.Open(jwtConfig.PrivateKey)
 	if err != nil {
-		log.Fatal("Failed to open private key:", err)
+		logging.Fatal("Failed to open private key", "err", err)
 	}
 
 	pemfileinfo, _ := privateKeyFile.Stat()
@@ -403,7 +404,7 @@ func getPrivateKey(jwtConfig *config.JWTConfig) *rsa.PrivateKey {
 	buffer := bufio.NewReader(privateKeyFile)
 	_, err = buffer.Read(pembytes)
 	if err != nil {
-		log.Fatal("Failed to read private key file:", err)
+		logging.Fatal("Failed to read private key file", "err", err)
 	}
 	// Decode
 	data, _ := pem.Decode([]byte(pembytes))
@@ -413,13 +414,13 @@ func getPrivateKey(jwtConfig *config.JWTConfig) *rsa.PrivateKey {
 	privateKeyImported, err := x509.ParsePKCS1PrivateKey(data.Bytes)
 
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to parse private key", "err", err)
 	}
 	//rsaPrivate, ok := privateKeyImported.(*rsa.PrivateKey)
 	//if !ok {
 	//	log.Fatal(err)
 	//}
-	log.Println("Private key imported successfully")
+	slog.Info("Private key imported successfully")
 	return privateKeyImported
 }
 
@@ -427,7 +428,7 @@ func getPrivateKey(jwtConfig *config.JWTConfig) *rsa.PrivateKey {
 func getPublicKey(jwtConfig *config.JWTConfig) *rsa.PublicKey {
 	publicKeyFile, err := os.Open(jwtConfig.PublicKey)
 	if err != nil {
-		log.Fatal("Failed to open public key:", err)
+		logging.Fatal("Failed to open public key", "err", err)
 	}
 
 	pemfileinfo, _ := publicKeyFile.Stat()
@@ -437,7 +438,7 @@ func getPublicKey(jwtConfig *config.JWTConfig) *rsa.PublicKey {
 	buffer := bufio.NewReader(publicKeyFile)
 	_, err = buffer.Read(pembytes)
 	if err != nil {
-		log.Fatal("Failed to read public key file:", err)
+		logging.Fatal("Failed to read public key file", "err", err)
 	}
 
 	data, _ := pem.Decode([]byte(pembytes))
@@ -449,14 +450,14 @@ func getPublicKey(jwtConfig *config.JWTConfig) *rsa.PublicKey {
 	publicKeyImported, err := x509.ParsePKIXPublicKey(data.Bytes)
 
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Failed to parse public key", "err", err)
 	}
-	log.Println("Public key imported successfully")
+	slog.Info("Public key imported successfully")
 
 	rsaPub, ok := publicKeyImported.(*rsa.PublicKey)
 
 	if !ok {
-		log.Fatal(err)
+		logging.Fatal("Public key is not an RSA key")
 	}
 
 	return rsaPub
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/jackc/pgtype"
+	"gopkg.in/yaml.v3"
+)
+
+// Directory the uploaded media is served from
+const publicDir = "./public"
+
+// Number of posts read from the database at a time
+const exportBatchSize = 100
+
+// Matches the uploaded media linked from posts, as served by the /assets routes
+var mediaURLRegex = regexp.MustCompile(`/assets/(images|videos)/([a-zA-Z0-9_\-]+\.[a-zA-Z0-9]+)`)
+
+// ExportArchive writes a zip archive of the posts and users to w as it's built. Every post is a
+// Markdown file with YAML front matter in posts/, users.json lists the users without their
+// passwords, media/ has the uploaded media the posts link to and manifest.json lists the contents.
+// Posts in the trash are not exported.
+func ExportArchive(ctx context.Context, w io.Writer, pr repositories.PostRepository, ur repositories.UserRepository) error {
+	z := zip.NewWriter(w)
+
+	manifest := &models.ExportManifest{
+		Version:    models.ExportFormatVersion,
+		ExportedAt: time.Now().UTC(),
+		Posts:      []*models.ExportedPost{},
+		Users:      "users.json",
+		Media:      []string{},
+		Missing:    []string{},
+	}
+
+	users, err := exportUsers(ctx, z, ur)
+	if err != nil {
+		return err
+	}
+
+	media := make(map[string]bool)
+	for afterID := 0; ; {
+		posts, err := pr.GetBatch(ctx, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			break
+		}
+		afterID = posts[len(posts)-1].ID
+
+		ids := make([]int, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		contributors, err := pr.GetAuthorsByPosts(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			file := "posts/" + strconv.Itoa(post.ID) + "-" + strings.ReplaceAll(post.Slug, "/", "-") + ".md"
+			if err := writePost(z, file, post, contributors[post.ID], users); err != nil {
+				return err
+			}
+			manifest.Posts = append(manifest.Posts, &models.ExportedPost{ID: post.ID, Slug: post.Slug, File: file})
+
+			for _, match := range mediaURLRegex.FindAllStringSubmatch(post.FeatureImgURL+"\n"+post.Body, -1) {
+				media[match[0]] = true
+			}
+		}
+	}
+
+	urls := make([]string, 0, len(media))
+	for url := range media {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		file, found, err := exportMedia(z, url)
+		if err != nil {
+			return err
+		}
+		if !found {
+			manifest.Missing = append(manifest.Missing, url)
+			continue
+		}
+		manifest.Media = append(manifest.Media, file)
+	}
+
+	if err := writeJSON(z, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Exported posts", "posts", len(manifest.Posts), "users", len(users), "media", len(manifest.Media))
+	return z.Close()
+}
+
+// Writes users.json and returns the usernames by user ID
+func exportUsers(ctx context.Context, z *zip.Writer, ur repositories.UserRepository) (map[string]string, error) {
+	all, err := ur.GetAllDetailed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*models.AuthUser{}
+	usernames := make(map[string]string, len(all))
+	for _, u := range all {
+		// The listing has no profile. Neither has the password hash.
+		user, err := ur.FindByIDDetailed(ctx, u.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &models.AuthUser{User: user, Admin: user.Admin})
+		usernames[user.ID.String()] = user.Username
+	}
+
+	return usernames, writeJSON(z, "users.json", users)
+}
+
+// Writes the post as Markdown with YAML front matter
+func writePost(z *zip.Writer, file string, post *models.Post, contributors []*models.PostAuthor, usernames map[string]string) error {
+	front := &models.PostFrontMatter{
+		Title:        post.Title,
+		Subtitle:     post.Subtitle,
+		Slug:         post.Slug,
+		Tags:         post.Tags,
+		Hidden:       post.Hidden,
+		CreatedAt:    post.CreatedAt.UTC(),
+		Author:       usernames[post.AuthorID],
+		FeatureImage: post.FeatureImgURL,
+	}
+	if post.UpdatedAt.Status == pgtype.Present {
+		updatedAt := post.UpdatedAt.Time.UTC()
+		front.UpdatedAt = &updatedAt
+	}
+	for _, contributor := range contributors {
+		front.Contributors = append(front.Contributors, &models.ExportedContributor{Username: contributor.Username, Role: contributor.Role})
+	}
+	if post.CustomExcerpt {
+		front.Excerpt = post.Excerpt
+	}
+	if front.Tags == nil {
+		front.Tags = []string{}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	if err := yaml.NewEncoder(&buf).Encode(front); err != nil {
+		return err
+	}
+	buf.WriteString("---\n\n")
+	buf.WriteString(post.Body)
+
+	f, err := z.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Deflate, Modified: post.CreatedAt})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	return err
+}
+
+// Copies the media file of the URL into the archive. Returns its path in the archive and false if
+// the file doesn't exist.
+func exportMedia(z *zip.Writer, url string) (string, bool, error) {
+	match := mediaURLRegex.FindStringSubmatch(url)
+	kind, name := match[1], match[2]
+
+	dir := filepath.Join(publicDir, "videos")
+	if kind == "images" {
+		dir = filepath.Join(publicDir, "images", "original")
+		if strings.HasSuffix(name, ".webp") {
+			dir = filepath.Join(publicDir, "images", "webp")
+		}
+	}
+
+	src, err := os.Open(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		slog.Warn("Missing media file", "url", url)
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer src.Close()
+
+	file := "media/" + kind + "/" + name
+	// Media is already compressed
+	f, err := z.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Store})
+	if err != nil {
+		return "", false, err
+	}
+	_, err = io.Copy(f, src)
+	return file, true, err
+}
+
+// Writes the value as an indented JSON file
+func writeJSON(z *zip.Writer, file string, v interface{}) error {
+	f, err := z.Create(file)
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(f)
+	e.SetIndent("", "  ")
+	return e.Encode(v)
+}
@@ -0,0 +1,555 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgtype"
+)
+
+// Largest media file the importer downloads
+const maxImportMediaSize = 20 << 20
+
+// Largest post or users file the importer reads from an archive, an archive's size says little
+// about how large its files are once decompressed
+const maxImportFileSize = 10 << 20
+
+// Placeholder Ghost uses for its own URL in exported links
+const ghostURLPlaceholder = "__GHOST_URL__"
+
+var (
+	markdownImageRegex  = regexp.MustCompile(`(!\[[^\]]*\]\()([^)\s]+)((?:\s+"[^"]*")?\))`)
+	mediaExtensionRegex = regexp.MustCompile(`^\.[a-zA-Z0-9]{1,5}$`)
+)
+
+// MediaSource opens the media files of an import by their path relative to the import's root
+type MediaSource interface {
+	Open(name string) (io.ReadCloser, error)
+}
+
+// DirSource reads media from a directory
+type DirSource string
+
+// Open opens the file relative to the directory
+func (d DirSource) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// ZipSource reads media from a zip archive
+type ZipSource struct {
+	*zip.Reader
+}
+
+// Open opens the file in the archive
+func (z ZipSource) Open(name string) (io.ReadCloser, error) {
+	for _, f := range z.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// Importer saves posts read from other blogs. Authors are matched by username, and placeholder
+// accounts are created for the ones that don't exist. Posts whose slug is taken are skipped, so
+// running an import again only adds what is missing.
+type Importer struct {
+	posts         repositories.PostRepository
+	users         repositories.UserRepository
+	defaultAuthor *models.User
+	defaultImage  string
+	dryRun        bool
+	// Relative media paths are read from the source, nil if there are none
+	source MediaSource
+	// Replaces Ghost's URL placeholder so its media can be downloaded
+	SiteURL string
+	client  *http.Client
+
+	report  *models.ImportReport
+	authors map[string]*models.User
+	media   map[string]string
+	slugs   map[string]bool
+	tags    map[string]bool
+}
+
+// NewImporter returns an importer that saves posts without an author as defaultAuthor and posts
+// without a feature image with defaultImage. Nothing is saved or downloaded in a dry run.
+func NewImporter(pr repositories.PostRepository, ur repositories.UserRepository, defaultAuthor *models.User, defaultImage string, source MediaSource, dryRun bool) *Importer {
+	return &Importer{
+		posts:         pr,
+		users:         ur,
+		defaultAuthor: defaultAuthor,
+		defaultImage:  defaultImage,
+		dryRun:        dryRun,
+		source:        source,
+		client:        newDownloadClient(),
+	}
+}
+
+// Import saves the posts and returns a report of what was done, or what would be done in a dry run
+func (im *Importer) Import(ctx context.Context, format string, posts []*models.ImportPost) *models.ImportReport {
+	im.report = &models.ImportReport{
+		Format:   format,
+		DryRun:   im.dryRun,
+		Posts:    []*models.ImportResult{},
+		Users:    []string{},
+		Tags:     []string{},
+		Media:    []string{},
+		Warnings: []string{},
+	}
+	im.authors = make(map[string]*models.User)
+	im.media = make(map[string]string)
+	im.slugs = make(map[string]bool)
+	im.tags = make(map[string]bool)
+
+	for _, p := range posts {
+		result := im.importPost(ctx, p)
+		switch result.Status {
+		case models.ImportCreated, models.ImportWouldCreate:
+			im.report.Created++
+		case models.ImportSkipped:
+			im.report.Skipped++
+		case models.ImportFailed:
+			im.report.Failed++
+		}
+		im.report.Posts = append(im.report.Posts, result)
+	}
+
+	slog.InfoContext(ctx, "Imported posts", "format", format, "created", im.report.Created,
+		"skipped", im.report.Skipped, "failed", im.report.Failed, "dryRun", im.dryRun)
+	return im.report
+}
+
+// Saves a single post and returns what happened to it
+func (im *Importer) importPost(ctx context.Context, p *models.ImportPost) *models.ImportResult {
+	title := strings.TrimSpace(p.Title)
+	slug := importSlug(p.Slug, title, p.CreatedAt)
+	result := &models.ImportResult{Title: title, Slug: slug}
+	fail := func(reason string) *models.ImportResult {
+		result.Status = models.ImportFailed
+		result.Reason = reason
+		return result
+	}
+
+	if title == "" {
+		return fail("Title is required")
+	}
+	if slug == "" {
+		return fail("Could not make a slug from the title")
+	}
+	if strings.TrimSpace(p.Body) == "" {
+		return fail("Body is empty")
+	}
+	if im.slugs[slug] || im.posts.Exists(ctx, slug) {
+		result.Status = models.ImportSkipped
+		result.Reason = "A post with this slug already exists"
+		return result
+	}
+
+	author, err := im.author(ctx, p.Author)
+	if err != nil {
+		return fail(err.Error())
+	}
+	contributors, err := im.contributors(ctx, p.Contributors, author)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	body := markdownImageRegex.ReplaceAllStringFunc(p.Body, func(match string) string {
+		parts := markdownImageRegex.FindStringSubmatch(match)
+		return parts[1] + im.storeMedia(parts[2], p.MediaBase) + parts[3]
+	})
+	image := im.defaultImage
+	if p.FeatureImage != "" {
+		image = im.storeMedia(p.FeatureImage, p.MediaBase)
+	}
+
+	rendered, err := util.RenderMarkdown(body)
+	if err != nil {
+		return fail("Could not render body")
+	}
+	excerpt := strings.TrimSpace(p.Excerpt)
+	customExcerpt := excerpt != ""
+	if !customExcerpt {
+		excerpt = rendered.Excerpt
+	}
+
+	createdAt := p.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	post := &models.Post{
+		Title:         title,
+		Subtitle:      p.Subtitle,
+		Slug:          slug,
+		Body:          body,
+		BodyHTML:      rendered.HTML,
+		TOC:           rendered.TOC,
+		Excerpt:       excerpt,
+		CustomExcerpt: customExcerpt,
+		WordCount:     rendered.WordCount,
+		ReadingTime:   rendered.ReadingTime,
+		CreatedAt:     createdAt,
+		UpdatedAt:     pgtype.Timestamptz{Status: pgtype.Null},
+		Tags:          im.cleanTags(p.Tags),
+		Hidden:        p.Hidden,
+		AuthorID:      author.ID.String(),
+		FeatureImgURL: image,
+		// The original slug is kept when the title is edited
+		SlugPinned: true,
+	}
+	im.slugs[slug] = true
+
+	if im.dryRun {
+		result.Status = models.ImportWouldCreate
+		return result
+	}
+
+	err = im.posts.Create(ctx, post)
+	if err != nil {
+		return fail("Could not create post")
+	}
+	_, err = im.posts.SetAuthors(ctx, post.ID, contributors)
+	if err != nil {
+		im.warn("Could not set the contributors of post " + post.Slug)
+	}
+
+	result.ID = post.ID
+	result.Slug = post.Slug
+	result.Status = models.ImportCreated
+	return result
+}
+
+// Returns the contributors of the post, or only the author if the source has none. Accounts are
+// created for the missing contributors like for authors.
+func (im *Importer) contributors(ctx context.Context, contributors []*models.ImportContributor, author *models.User) ([]*models.PostAuthor, error) {
+	authors := make([]*models.PostAuthor, 0, len(contributors))
+	seen := make(map[string]bool, len(contributors))
+	for _, c := range contributors {
+		if c.Role != models.RoleAuthor && c.Role != models.RoleEditor && c.Role != models.RoleIllustrator {
+			return nil, errors.New("Contributor role must be author, editor or illustrator")
+		}
+		user, err := im.author(ctx, c.Author)
+		if err != nil {
+			return nil, err
+		}
+		if seen[user.ID.String()] {
+			continue
+		}
+		seen[user.ID.String()] = true
+		authors = append(authors, &models.PostAuthor{AuthorSummary: *user.Summary(), Role: c.Role})
+	}
+	if len(authors) == 0 {
+		authors = append(authors, &models.PostAuthor{AuthorSummary: *author.Summary(), Role: models.RoleAuthor})
+	}
+	return authors, nil
+}
+
+// Returns the user for the author, creating a placeholder account if nobody has their username
+func (im *Importer) author(ctx context.Context, a *models.ImportAuthor) (*models.User, error) {
+	if a == nil || (a.Username == "" && a.Name == "") {
+		if im.defaultAuthor == nil {
+			return nil, errors.New("Post has no author")
+		}
+		return im.defaultAuthor, nil
+	}
+
+	username := strings.TrimSpace(a.Username)
+	if username == "" {
+		username = util.GenerateSlug(a.Name)
+	}
+	if user, ok := im.authors[username]; ok {
+		return user, nil
+	}
+
+	user, err := im.users.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, errors.New("Could not find author " + username)
+	}
+	if user.Username != "" {
+		im.authors[username] = user
+		return user, nil
+	}
+	if im.users.ExistsUsername(ctx, username) {
+		return nil, errors.New("Author " + username + " is in the trash")
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(a.Name)
+	if name == "" {
+		name = username
+	}
+	user = &models.User{
+		ID:        id,
+		Name:      name,
+		Email:     strings.TrimSpace(a.Email),
+		CreatedAt: time.Now(),
+		Username:  username,
+	}
+
+	if !im.dryRun {
+		// Placeholder accounts can't log in until an admin sets their password
+		password := make([]byte, 32)
+		if _, err := rand.Read(password); err != nil {
+			return nil, err
+		}
+		user.SetPassword(hex.EncodeToString(password))
+
+		if err := im.users.Create(ctx, user); err != nil {
+			return nil, errors.New("Could not create author " + username)
+		}
+	}
+
+	im.authors[username] = user
+	im.report.Users = append(im.report.Users, username)
+	return user, nil
+}
+
+// Copies or downloads the media into the media store and returns its URL there. The URL is
+// returned unchanged if the media can't be stored or in a dry run.
+func (im *Importer) storeMedia(ref string, base string) string {
+	if stored, ok := im.media[ref]; ok {
+		return stored
+	}
+
+	stored, err := im.copyMedia(ref, base)
+	if err != nil {
+		im.warn(fmt.Sprintf("Could not store media %v: %v", ref, err))
+		stored = ref
+	} else if stored != ref || strings.HasPrefix(ref, "/assets/") {
+		im.report.Media = append(im.report.Media, stored)
+	}
+	im.media[ref] = stored
+	return stored
+}
+
+// Stores the media and returns its URL. Files are named after their source so importing again
+// reuses them.
+func (im *Importer) copyMedia(ref string, base string) (string, error) {
+	src := ref
+	if strings.HasPrefix(src, ghostURLPlaceholder) {
+		if im.SiteURL == "" {
+			return "", errors.New("the Ghost site URL is required to download it")
+		}
+		src = strings.TrimRight(im.SiteURL, "/") + strings.TrimPrefix(src, ghostURLPlaceholder)
+	}
+
+	// Media of this site, such as in an export archive
+	if match := mediaURLRegex.FindStringSubmatch(src); match != nil && strings.HasPrefix(src, "/assets/") {
+		kind, name := match[1], match[2]
+		file := mediaFile(kind, name)
+		if _, err := os.Stat(file); err == nil || im.dryRun {
+			return src, nil
+		}
+		return src, im.copyFromSource("media/"+kind+"/"+name, file)
+	}
+
+	if strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "#") {
+		return ref, nil
+	}
+
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "//") {
+		if strings.HasPrefix(src, "//") {
+			src = "https:" + src
+		}
+		name := mediaName(src, path.Ext(strings.SplitN(src, "?", 2)[0]))
+		url := "/assets/images/" + name
+		file := mediaFile("images", name)
+		if _, err := os.Stat(file); err == nil || im.dryRun {
+			return url, nil
+		}
+		return url, im.download(src, file)
+	}
+
+	// Relative to the post in a Markdown import
+	if im.source == nil {
+		return "", errors.New("relative media needs a directory or archive")
+	}
+	rel := path.Clean(path.Join(base, src))
+	if strings.HasPrefix(src, "/") {
+		rel = path.Clean(strings.TrimPrefix(src, "/"))
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", errors.New("path is outside of the import")
+	}
+	name := mediaName(rel, path.Ext(rel))
+	url := "/assets/images/" + name
+	file := mediaFile("images", name)
+	if _, err := os.Stat(file); err == nil || im.dryRun {
+		return url, nil
+	}
+	return url, im.copyFromSource(rel, file)
+}
+
+// Copies a file of the import source into the media store
+func (im *Importer) copyFromSource(name string, file string) error {
+	if im.source == nil {
+		return os.ErrNotExist
+	}
+	src, err := im.source.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return writeMediaFile(file, io.LimitReader(src, maxImportMediaSize+1))
+}
+
+// Returns a client that only connects to public addresses, so a post can't make the server fetch
+// from itself or its private network. The check runs on the resolved address of every connection,
+// which covers redirects and host names that resolve to private addresses.
+func newDownloadClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errors.New("download from a private address " + host + " is not allowed")
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		// No proxy, which would be dialed instead of the download's host
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+	}
+}
+
+// Returns true if the IP isn't loopback, private, link-local, multicast or unspecified
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// Downloads an image into the media store
+func (im *Importer) download(url string, file string) error {
+	res, err := im.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status %v", res.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); !strings.HasPrefix(mediaType, "image/") {
+		return errors.New("download is not an image")
+	}
+
+	return writeMediaFile(file, io.LimitReader(res.Body, maxImportMediaSize+1))
+}
+
+// Returns the tags without duplicates and the ones that can't be used
+func (im *Importer) cleanTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	cleaned := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !util.CheckTag(tag) {
+			im.warn("Skipped tag " + tag)
+			continue
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+		if !im.tags[tag] {
+			im.tags[tag] = true
+			im.report.Tags = append(im.report.Tags, tag)
+		}
+	}
+	return cleaned
+}
+
+// Adds a warning to the report
+func (im *Importer) warn(warning string) {
+	slog.Warn("Import warning", "warning", warning)
+	im.report.Warnings = append(im.report.Warnings, warning)
+}
+
+// Returns the slug of an imported post. The original slug is kept if it's valid.
+func importSlug(slug string, title string, createdAt time.Time) string {
+	slug = strings.Trim(strings.TrimSpace(slug), "/")
+	if !util.IsSlug(slug) {
+		slug = util.GenerateSlug(slug)
+	}
+	if slug == "" {
+		slug = util.GenerateSlug(title)
+	}
+	// Would be shadowed by other posts routes
+	if slug != "" && !util.CheckSlug(slug) {
+		slug = createdAt.Format("2006/01/") + slug
+	}
+	return slug
+}
+
+// Returns the file name of media from the source, named after its hash
+func mediaName(source string, ext string) string {
+	hash := sha1.Sum([]byte(source))
+	if !mediaExtensionRegex.MatchString(ext) {
+		ext = ".jpg"
+	}
+	return hex.EncodeToString(hash[:8]) + strings.ToLower(ext)
+}
+
+// Returns the path of a media file in the store
+func mediaFile(kind string, name string) string {
+	if kind == "videos" {
+		return filepath.Join(publicDir, "videos", name)
+	}
+	if strings.HasSuffix(name, ".webp") {
+		return filepath.Join(publicDir, "images", "webp", name)
+	}
+	return filepath.Join(publicDir, "images", "original", name)
+}
+
+// Writes a media file, removing it again if it's too large or can't be written
+func writeMediaFile(file string, src io.Reader) error {
+	dst, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > maxImportMediaSize {
+		err = errors.New("file is too large")
+	}
+	if err != nil {
+		os.Remove(file)
+		return err
+	}
+	return nil
+}
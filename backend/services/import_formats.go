@@ -0,0 +1,595 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/util"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// Shortcodes WordPress adds around media, their content is kept
+	wpShortcodeRegex = regexp.MustCompile(`\[/?(caption|gallery|embed|video|audio|wp_caption)[^\]]*\]`)
+	wpBlankLineRegex = regexp.MustCompile(`\n\s*\n`)
+	frontMatterRegex = regexp.MustCompile(`(?s)\A---[ \t]*\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n|\z)`)
+)
+
+// Layouts of the dates read from import sources
+var importDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// Parses a date in one of the import layouts. Dates without a zone are in UTC.
+func parseImportDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "0000-00-00") {
+		return time.Time{}, false
+	}
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+/*
+ * WordPress
+ */
+
+type wxrDocument struct {
+	Channel struct {
+		Authors []struct {
+			Login       string `xml:"author_login"`
+			Email       string `xml:"author_email"`
+			DisplayName string `xml:"author_display_name"`
+		} `xml:"author"`
+		Items []*wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title    string       `xml:"title"`
+	PubDate  string       `xml:"pubDate"`
+	Creator  string       `xml:"creator"`
+	Encoded  []wxrEncoded `xml:"encoded"`
+	PostID   string       `xml:"post_id"`
+	Date     string       `xml:"post_date"`
+	DateGMT  string       `xml:"post_date_gmt"`
+	Name     string       `xml:"post_name"`
+	Status   string       `xml:"status"`
+	PostType string       `xml:"post_type"`
+	URL      string       `xml:"attachment_url"`
+	// Categories and tags
+	Categories []struct {
+		Domain string `xml:"domain,attr"`
+		Name   string `xml:",chardata"`
+	} `xml:"category"`
+	Meta []struct {
+		Key   string `xml:"meta_key"`
+		Value string `xml:"meta_value"`
+	} `xml:"postmeta"`
+}
+
+// content:encoded and excerpt:encoded only differ by their namespace
+type wxrEncoded struct {
+	XMLName xml.Name `xml:"encoded"`
+	Value   string   `xml:",chardata"`
+}
+
+// ReadWXR reads the posts of a WordPress export. Categories and tags become tags, drafts are
+// hidden and the feature image is read from the post's thumbnail.
+func ReadWXR(r io.Reader) ([]*models.ImportPost, error) {
+	var doc wxrDocument
+	decoder := xml.NewDecoder(r)
+	// WordPress exports declare UTF-8 but some older ones don't
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	authors := make(map[string]*models.ImportAuthor)
+	for _, a := range doc.Channel.Authors {
+		authors[a.Login] = &models.ImportAuthor{Username: a.Login, Name: a.DisplayName, Email: a.Email}
+	}
+	attachments := make(map[string]string)
+	for _, item := range doc.Channel.Items {
+		if item.PostType == "attachment" && item.URL != "" {
+			attachments[item.PostID] = item.URL
+		}
+	}
+
+	posts := []*models.ImportPost{}
+	for _, item := range doc.Channel.Items {
+		if item.PostType != "post" || item.Status == "trash" || item.Status == "auto-draft" {
+			continue
+		}
+
+		var content, excerpt string
+		for _, e := range item.Encoded {
+			if strings.Contains(e.XMLName.Space, "excerpt") {
+				excerpt = e.Value
+			} else {
+				content = e.Value
+			}
+		}
+		body, err := util.HTMLToMarkdown(wpautop(wpShortcodeRegex.ReplaceAllString(content, "")))
+		if err != nil {
+			return nil, err
+		}
+
+		post := &models.ImportPost{
+			Title:   item.Title,
+			Slug:    item.Name,
+			Body:    body,
+			Excerpt: strings.TrimSpace(excerpt),
+			Tags:    []string{},
+			Hidden:  item.Status != "publish",
+		}
+		if t, ok := parseImportDate(item.DateGMT); ok {
+			post.CreatedAt = t
+		} else if t, ok := parseImportDate(item.Date); ok {
+			post.CreatedAt = t
+		} else if t, ok := parseImportDate(item.PubDate); ok {
+			post.CreatedAt = t
+		}
+		if author, ok := authors[item.Creator]; ok {
+			post.Author = author
+		} else if item.Creator != "" {
+			post.Author = &models.ImportAuthor{Username: item.Creator}
+		}
+		for _, c := range item.Categories {
+			if c.Domain == "category" || c.Domain == "post_tag" {
+				post.Tags = append(post.Tags, c.Name)
+			}
+		}
+		for _, m := range item.Meta {
+			if m.Key == "_thumbnail_id" {
+				post.FeatureImage = attachments[m.Value]
+			}
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// Wraps the paragraphs of content saved by the classic editor, which has no <p> tags
+func wpautop(content string) string {
+	content = strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	if content == "" || strings.Contains(content, "<p") {
+		return content
+	}
+	var b strings.Builder
+	for _, paragraph := range wpBlankLineRegex.Split(content, -1) {
+		b.WriteString("<p>" + strings.ReplaceAll(strings.TrimSpace(paragraph), "\n", "<br>\n") + "</p>\n")
+	}
+	return b.String()
+}
+
+/*
+ * Ghost
+ */
+
+// Ghost IDs are strings in newer exports and numbers in older ones
+type ghostID string
+
+func (id *ghostID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = ghostID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = ghostID(n.String())
+	return nil
+}
+
+type ghostData struct {
+	Posts []struct {
+		ID            ghostID `json:"id"`
+		Title         string  `json:"title"`
+		Slug          string  `json:"slug"`
+		Mobiledoc     string  `json:"mobiledoc"`
+		HTML          string  `json:"html"`
+		FeatureImage  string  `json:"feature_image"`
+		Status        string  `json:"status"`
+		Type          string  `json:"type"`
+		Page          bool    `json:"page"`
+		CustomExcerpt string  `json:"custom_excerpt"`
+		PublishedAt   string  `json:"published_at"`
+		CreatedAt     string  `json:"created_at"`
+		AuthorID      ghostID `json:"author_id"`
+	} `json:"posts"`
+	Users []struct {
+		ID    ghostID `json:"id"`
+		Name  string  `json:"name"`
+		Slug  string  `json:"slug"`
+		Email string  `json:"email"`
+	} `json:"users"`
+	Tags []struct {
+		ID   ghostID `json:"id"`
+		Name string  `json:"name"`
+	} `json:"tags"`
+	PostsTags []struct {
+		PostID    ghostID `json:"post_id"`
+		TagID     ghostID `json:"tag_id"`
+		SortOrder int     `json:"sort_order"`
+	} `json:"posts_tags"`
+	PostsAuthors []struct {
+		PostID    ghostID `json:"post_id"`
+		AuthorID  ghostID `json:"author_id"`
+		SortOrder int     `json:"sort_order"`
+	} `json:"posts_authors"`
+}
+
+// ReadGhost reads the posts of a Ghost JSON export. Pages and internal tags are skipped, and the
+// Markdown card is used as the body when the post has one.
+func ReadGhost(r io.Reader) ([]*models.ImportPost, error) {
+	var export struct {
+		DB   []struct{ Data ghostData } `json:"db"`
+		Data *ghostData                 `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+	var data ghostData
+	if len(export.DB) > 0 {
+		data = export.DB[0].Data
+	} else if export.Data != nil {
+		data = *export.Data
+	} else {
+		return nil, errors.New("no Ghost data in the export")
+	}
+
+	authors := make(map[ghostID]*models.ImportAuthor)
+	for _, u := range data.Users {
+		authors[u.ID] = &models.ImportAuthor{Username: u.Slug, Name: u.Name, Email: u.Email}
+	}
+	tags := make(map[ghostID]string)
+	for _, t := range data.Tags {
+		// Internal tags start with a hash
+		if !strings.HasPrefix(t.Name, "#") {
+			tags[t.ID] = t.Name
+		}
+	}
+	sort.SliceStable(data.PostsTags, func(i, j int) bool { return data.PostsTags[i].SortOrder < data.PostsTags[j].SortOrder })
+	postTags := make(map[ghostID][]string)
+	for _, pt := range data.PostsTags {
+		if name, ok := tags[pt.TagID]; ok {
+			postTags[pt.PostID] = append(postTags[pt.PostID], name)
+		}
+	}
+	// The primary author has the lowest sort order
+	sort.SliceStable(data.PostsAuthors, func(i, j int) bool { return data.PostsAuthors[i].SortOrder < data.PostsAuthors[j].SortOrder })
+	postAuthors := make(map[ghostID]ghostID)
+	for _, pa := range data.PostsAuthors {
+		if _, ok := postAuthors[pa.PostID]; !ok {
+			postAuthors[pa.PostID] = pa.AuthorID
+		}
+	}
+
+	posts := []*models.ImportPost{}
+	for _, p := range data.Posts {
+		if p.Type == "page" || p.Page {
+			continue
+		}
+
+		body, ok := ghostMarkdownCard(p.Mobiledoc)
+		if !ok {
+			var err error
+			if body, err = util.HTMLToMarkdown(p.HTML); err != nil {
+				return nil, err
+			}
+		}
+
+		post := &models.ImportPost{
+			Title:        p.Title,
+			Slug:         p.Slug,
+			Body:         body,
+			Excerpt:      p.CustomExcerpt,
+			Tags:         postTags[p.ID],
+			Hidden:       p.Status != "published",
+			FeatureImage: p.FeatureImage,
+		}
+		if t, ok := parseImportDate(p.PublishedAt); ok {
+			post.CreatedAt = t
+		} else if t, ok := parseImportDate(p.CreatedAt); ok {
+			post.CreatedAt = t
+		}
+		authorID, ok := postAuthors[p.ID]
+		if !ok {
+			authorID = p.AuthorID
+		}
+		post.Author = authors[authorID]
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// Returns the Markdown of a post written in a single Markdown card
+func ghostMarkdownCard(mobiledoc string) (string, bool) {
+	if mobiledoc == "" {
+		return "", false
+	}
+	var doc struct {
+		Cards [][]json.RawMessage `json:"cards"`
+	}
+	if err := json.Unmarshal([]byte(mobiledoc), &doc); err != nil || len(doc.Cards) != 1 || len(doc.Cards[0]) != 2 {
+		return "", false
+	}
+	var name string
+	var card struct {
+		Markdown string `json:"markdown"`
+	}
+	if json.Unmarshal(doc.Cards[0][0], &name) != nil || name != "markdown" && name != "card-markdown" {
+		return "", false
+	}
+	if json.Unmarshal(doc.Cards[0][1], &card) != nil {
+		return "", false
+	}
+	return card.Markdown, true
+}
+
+/*
+ * Markdown
+ */
+
+// Tags can be a list or a comma separated string
+type frontMatterList []string
+
+func (l *frontMatterList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = strings.Split(value.Value, ",")
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Front matter keys of this site's exports and of common static site generators
+type markdownFrontMatter struct {
+	Title        string          `yaml:"title"`
+	Subtitle     string          `yaml:"subtitle"`
+	Slug         string          `yaml:"slug"`
+	Tags         frontMatterList `yaml:"tags"`
+	Categories   frontMatterList `yaml:"categories"`
+	Hidden       *bool           `yaml:"hidden"`
+	Draft        *bool           `yaml:"draft"`
+	CreatedAt    string          `yaml:"createdAt"`
+	Date         string          `yaml:"date"`
+	Author       string          `yaml:"author"`
+	FeatureImage string          `yaml:"featureImage"`
+	Image        string          `yaml:"image"`
+	Excerpt      string          `yaml:"excerpt"`
+	Description  string          `yaml:"description"`
+
+	Contributors []*models.ExportedContributor `yaml:"contributors"`
+}
+
+// ReadMarkdownDir reads the Markdown files in the directory and its subdirectories
+func ReadMarkdownDir(dir string) ([]*models.ImportPost, error) {
+	posts := []*models.ImportPost{}
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isMarkdownFile(file) {
+			return nil
+		}
+		source, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		post, err := readMarkdownPost(filepath.ToSlash(rel), source)
+		if err != nil {
+			return err
+		}
+		posts = append(posts, post)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := ioutil.ReadFile(filepath.Join(dir, "users.json"))
+	if err == nil {
+		setExportedAuthors(posts, users)
+	}
+	return posts, nil
+}
+
+// ReadMarkdownZip reads the Markdown files in a zip archive, such as an export archive
+func ReadMarkdownZip(z *zip.Reader) ([]*models.ImportPost, error) {
+	posts := []*models.ImportPost{}
+	var users []byte
+	for _, f := range z.File {
+		if strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		if !isMarkdownFile(f.Name) && f.Name != "users.json" {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		source, err := ioutil.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(source) > maxImportFileSize {
+			return nil, errors.New(f.Name + " is larger than 10MB")
+		}
+
+		if f.Name == "users.json" {
+			users = source
+			continue
+		}
+		post, err := readMarkdownPost(f.Name, source)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	if users != nil {
+		setExportedAuthors(posts, users)
+	}
+	return posts, nil
+}
+
+// Reads a Markdown file with optional front matter. Without a title the file name is used.
+func readMarkdownPost(name string, source []byte) (*models.ImportPost, error) {
+	source = bytes.TrimPrefix(source, []byte("\xef\xbb\xbf"))
+	var front markdownFrontMatter
+	body := source
+	if match := frontMatterRegex.FindSubmatchIndex(source); match != nil {
+		if err := yaml.Unmarshal(source[match[2]:match[3]], &front); err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+		body = source[match[1]:]
+	}
+
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	post := &models.ImportPost{
+		Title:        front.Title,
+		Subtitle:     front.Subtitle,
+		Slug:         front.Slug,
+		Body:         strings.TrimSpace(string(body)) + "\n",
+		Excerpt:      front.Excerpt,
+		Tags:         append(front.Tags, front.Categories...),
+		FeatureImage: front.FeatureImage,
+		MediaBase:    path.Dir(name),
+	}
+	if post.Title == "" {
+		post.Title = base
+	}
+	if post.Excerpt == "" {
+		post.Excerpt = front.Description
+	}
+	if post.FeatureImage == "" {
+		post.FeatureImage = front.Image
+	}
+	if front.Hidden != nil {
+		post.Hidden = *front.Hidden
+	} else if front.Draft != nil {
+		post.Hidden = *front.Draft
+	}
+	if t, ok := parseImportDate(front.CreatedAt); ok {
+		post.CreatedAt = t
+	} else if t, ok := parseImportDate(front.Date); ok {
+		post.CreatedAt = t
+	}
+	if front.Author != "" {
+		post.Author = &models.ImportAuthor{Username: front.Author}
+	}
+	for _, c := range front.Contributors {
+		post.Contributors = append(post.Contributors, &models.ImportContributor{Author: &models.ImportAuthor{Username: c.Username}, Role: c.Role})
+	}
+	return post, nil
+}
+
+// Adds the names and emails of an export archive's users.json to the posts' authors
+func setExportedAuthors(posts []*models.ImportPost, source []byte) {
+	var users []struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Username string `json:"username"`
+	}
+	if json.Unmarshal(source, &users) != nil {
+		return
+	}
+	for _, post := range posts {
+		authors := make([]*models.ImportAuthor, 0, len(post.Contributors)+1)
+		if post.Author != nil {
+			authors = append(authors, post.Author)
+		}
+		for _, c := range post.Contributors {
+			authors = append(authors, c.Author)
+		}
+		for _, author := range authors {
+			for _, u := range users {
+				if u.Username == author.Username {
+					author.Name = u.Name
+					author.Email = u.Email
+				}
+			}
+		}
+	}
+}
+
+// Returns true if the file has a Markdown extension
+func isMarkdownFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// ImportFormat returns the import format of a file by its extension, or an empty string
+func ImportFormat(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".xml":
+		return models.ImportWXR
+	case ".json":
+		return models.ImportGhost
+	case ".zip":
+		return models.ImportMarkdown
+	}
+	return ""
+}
+
+// ReadImport reads the posts of an import in the given format
+func ReadImport(format string, r io.ReaderAt, size int64) ([]*models.ImportPost, MediaSource, error) {
+	switch format {
+	case models.ImportWXR:
+		posts, err := ReadWXR(io.NewSectionReader(r, 0, size))
+		return posts, nil, err
+	case models.ImportGhost:
+		posts, err := ReadGhost(io.NewSectionReader(r, 0, size))
+		return posts, nil, err
+	case models.ImportMarkdown:
+		z, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		posts, err := ReadMarkdownZip(z)
+		return posts, ZipSource{z}, err
+	default:
+		return nil, nil, errors.New("unknown import format " + strconv.Quote(format))
+	}
+}
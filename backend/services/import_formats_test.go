@@ -0,0 +1,409 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+)
+
+func TestParseImportDate(t *testing.T) {
+	cases := map[string]string{
+		"2019-03-04T05:06:07Z":            "2019-03-04T05:06:07Z",
+		"2019-03-04T05:06:07.000+02:00":   "2019-03-04T03:06:07Z",
+		"2019-03-04 05:06:07":             "2019-03-04T05:06:07Z",
+		"2019-03-04T05:06:07":             "2019-03-04T05:06:07Z",
+		"2019-03-04 05:06":                "2019-03-04T05:06:00Z",
+		" 2019-03-04 ":                    "2019-03-04T00:00:00Z",
+		"Mon, 04 Mar 2019 05:06:07 +0000": "2019-03-04T05:06:07Z",
+		"Mon, 04 Mar 2019 05:06:07 GMT":   "2019-03-04T05:06:07Z",
+		"0000-00-00 00:00:00":             "",
+		"":                                "",
+		"yesterday":                       "",
+	}
+	for value, want := range cases {
+		got := ""
+		if t, ok := parseImportDate(value); ok {
+			got = t.UTC().Format(time.RFC3339)
+		}
+		if got != want {
+			t.Errorf("parseImportDate(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+const testWXR = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<wp:author>
+		<wp:author_login><![CDATA[jane]]></wp:author_login>
+		<wp:author_email><![CDATA[jane@example.com]]></wp:author_email>
+		<wp:author_display_name><![CDATA[Jane Doe]]></wp:author_display_name>
+	</wp:author>
+	<item>
+		<title>cat</title>
+		<wp:post_id>10</wp:post_id>
+		<wp:post_type><![CDATA[attachment]]></wp:post_type>
+		<wp:attachment_url><![CDATA[https://example.com/wp-content/cat.jpg]]></wp:attachment_url>
+	</item>
+	<item>
+		<title>Hello World</title>
+		<pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+		<dc:creator><![CDATA[jane]]></dc:creator>
+		<content:encoded><![CDATA[First line
+second line
+
+[caption id="attachment_10"]<img src="https://example.com/wp-content/cat.jpg" alt="cat">[/caption]]]></content:encoded>
+		<excerpt:encoded><![CDATA[ Short excerpt ]]></excerpt:encoded>
+		<wp:post_id>11</wp:post_id>
+		<wp:post_date><![CDATA[2006-01-02 10:04:05]]></wp:post_date>
+		<wp:post_date_gmt><![CDATA[2006-01-02 15:04:05]]></wp:post_date_gmt>
+		<wp:post_name><![CDATA[hello-world]]></wp:post_name>
+		<wp:status><![CDATA[publish]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+		<category domain="category" nicename="news"><![CDATA[News]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[go]]></category>
+		<category domain="nav_menu" nicename="main"><![CDATA[Main]]></category>
+		<wp:postmeta>
+			<wp:meta_key><![CDATA[_thumbnail_id]]></wp:meta_key>
+			<wp:meta_value><![CDATA[10]]></wp:meta_value>
+		</wp:postmeta>
+	</item>
+	<item>
+		<title>Draft</title>
+		<dc:creator><![CDATA[bob]]></dc:creator>
+		<content:encoded><![CDATA[<p>Draft <em>body</em></p>]]></content:encoded>
+		<wp:post_date><![CDATA[2020-05-06 07:08]]></wp:post_date>
+		<wp:post_date_gmt><![CDATA[0000-00-00 00:00:00]]></wp:post_date_gmt>
+		<wp:status><![CDATA[draft]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+	</item>
+	<item>
+		<title>Trashed</title>
+		<content:encoded><![CDATA[gone]]></content:encoded>
+		<wp:status><![CDATA[trash]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+	</item>
+	<item>
+		<title>About</title>
+		<content:encoded><![CDATA[page]]></content:encoded>
+		<wp:status><![CDATA[publish]]></wp:status>
+		<wp:post_type><![CDATA[page]]></wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestReadWXR(t *testing.T) {
+	posts, err := ReadWXR(strings.NewReader(testWXR))
+	if err != nil {
+		t.Fatalf("ReadWXR failed: %v", err)
+	}
+	want := []*models.ImportPost{
+		{
+			Title:        "Hello World",
+			Slug:         "hello-world",
+			Body:         "First line  \nsecond line\n\n![cat](https://example.com/wp-content/cat.jpg)\n",
+			Excerpt:      "Short excerpt",
+			Tags:         []string{"News", "go"},
+			CreatedAt:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			Author:       &models.ImportAuthor{Username: "jane", Name: "Jane Doe", Email: "jane@example.com"},
+			FeatureImage: "https://example.com/wp-content/cat.jpg",
+		},
+		{
+			Title:     "Draft",
+			Body:      "Draft _body_\n",
+			Tags:      []string{},
+			Hidden:    true,
+			CreatedAt: time.Date(2020, 5, 6, 7, 8, 0, 0, time.UTC),
+			Author:    &models.ImportAuthor{Username: "bob"},
+		},
+	}
+	if len(posts) != len(want) {
+		t.Fatalf("ReadWXR read %v posts, want %v", len(posts), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(posts[i], want[i]) {
+			t.Errorf("post %v = %+v, want %+v", i, posts[i], want[i])
+		}
+	}
+
+	if _, err := ReadWXR(strings.NewReader("<rss><channel>")); err == nil {
+		t.Errorf("ReadWXR of a truncated export succeeded, want an error")
+	}
+}
+
+func TestWpautop(t *testing.T) {
+	cases := map[string]string{
+		"":                           "",
+		"one\r\ntwo\r\n\r\nthree":    "<p>one<br>\ntwo</p>\n<p>three</p>\n",
+		"  one\n\n \n two  ":         "<p>one</p>\n<p>two</p>\n",
+		"<p>has paragraphs</p>\n\nx": "<p>has paragraphs</p>\n\nx",
+	}
+	for content, want := range cases {
+		if got := wpautop(content); got != want {
+			t.Errorf("wpautop(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+const testGhost = `{"db": [{"meta": {}, "data": {
+	"posts": [
+		{"id": "p1", "title": "Markdown post", "slug": "markdown-post",
+			"mobiledoc": "{\"version\":\"0.3.1\",\"cards\":[[\"card-markdown\",{\"markdown\":\"# Hi\\n\\nBody\"}]]}",
+			"html": "<h1>ignored</h1>", "feature_image": "__GHOST_URL__/content/images/a.png", "status": "published",
+			"custom_excerpt": "Custom", "published_at": "2019-03-04T05:06:07.000Z", "created_at": "2019-03-01T00:00:00.000Z", "author_id": "u1"},
+		{"id": 2, "title": "HTML post", "slug": "html-post", "html": "<p>Hello <strong>there</strong></p>",
+			"status": "draft", "published_at": null, "created_at": "2019-04-01 10:00:00", "author_id": 1},
+		{"id": "p3", "title": "About", "slug": "about", "type": "page", "status": "published"},
+		{"id": "p4", "title": "Old page", "slug": "old", "page": true}
+	],
+	"users": [
+		{"id": 1, "name": "Jane", "slug": "jane", "email": "jane@example.com"},
+		{"id": "u2", "name": "Sam", "slug": "sam"}
+	],
+	"tags": [{"id": "t1", "name": "News"}, {"id": "t2", "name": "#internal"}, {"id": "t3", "name": "Go"}],
+	"posts_tags": [
+		{"post_id": "p1", "tag_id": "t3", "sort_order": 1},
+		{"post_id": "p1", "tag_id": "t1", "sort_order": 0},
+		{"post_id": "p1", "tag_id": "t2", "sort_order": 2}
+	],
+	"posts_authors": [
+		{"post_id": "p1", "author_id": "1", "sort_order": 1},
+		{"post_id": "p1", "author_id": "u2", "sort_order": 0}
+	]
+}}]}`
+
+func TestReadGhost(t *testing.T) {
+	posts, err := ReadGhost(strings.NewReader(testGhost))
+	if err != nil {
+		t.Fatalf("ReadGhost failed: %v", err)
+	}
+	want := []*models.ImportPost{
+		{
+			Title:        "Markdown post",
+			Slug:         "markdown-post",
+			Body:         "# Hi\n\nBody",
+			Excerpt:      "Custom",
+			Tags:         []string{"News", "Go"},
+			FeatureImage: "__GHOST_URL__/content/images/a.png",
+			CreatedAt:    time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC),
+			Author:       &models.ImportAuthor{Username: "sam", Name: "Sam"},
+		},
+		{
+			Title:     "HTML post",
+			Slug:      "html-post",
+			Body:      "Hello **there**\n",
+			Hidden:    true,
+			CreatedAt: time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC),
+			Author:    &models.ImportAuthor{Username: "jane", Name: "Jane", Email: "jane@example.com"},
+		},
+	}
+	if len(posts) != len(want) {
+		t.Fatalf("ReadGhost read %v posts, want %v", len(posts), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(posts[i], want[i]) {
+			t.Errorf("post %v = %+v, want %+v", i, posts[i], want[i])
+		}
+	}
+}
+
+func TestReadGhostExportForms(t *testing.T) {
+	cases := []struct {
+		name    string
+		export  string
+		posts   int
+		wantErr bool
+	}{
+		{"data at the top level", `{"data": {"posts": [{"id": "1", "title": "A", "html": "<p>a</p>"}]}}`, 1, false},
+		{"no data", `{"meta": {}}`, 0, true},
+		{"invalid JSON", `{"db": [`, 0, true},
+		{"invalid ID", `{"data": {"posts": [{"id": true}]}}`, 0, true},
+	}
+	for _, c := range cases {
+		posts, err := ReadGhost(strings.NewReader(c.export))
+		if (err != nil) != c.wantErr || len(posts) != c.posts {
+			t.Errorf("%v: ReadGhost read %v posts with error %v, want %v posts and error %v", c.name, len(posts), err, c.posts, c.wantErr)
+		}
+	}
+}
+
+func TestGhostMarkdownCard(t *testing.T) {
+	cases := []struct {
+		name      string
+		mobiledoc string
+		want      string
+		ok        bool
+	}{
+		{"empty", "", "", false},
+		{"invalid JSON", "{", "", false},
+		{"markdown card", `{"cards": [["markdown", {"markdown": "*hi*"}]]}`, "*hi*", true},
+		{"card-markdown card", `{"cards": [["card-markdown", {"markdown": "*hi*"}]]}`, "*hi*", true},
+		{"other card", `{"cards": [["html", {"html": "<p>hi</p>"}]]}`, "", false},
+		{"several cards", `{"cards": [["markdown", {"markdown": "a"}], ["markdown", {"markdown": "b"}]]}`, "", false},
+		{"no cards", `{"cards": []}`, "", false},
+	}
+	for _, c := range cases {
+		got, ok := ghostMarkdownCard(c.mobiledoc)
+		if got != c.want || ok != c.ok {
+			t.Errorf("%v: ghostMarkdownCard = %q, %v, want %q, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestReadMarkdownPost(t *testing.T) {
+	hidden := "---\ntitle: Hidden\nhidden: false\ndraft: true\n---\nbody"
+	cases := []struct {
+		name   string
+		file   string
+		source string
+		want   *models.ImportPost
+	}{
+		{"without front matter", "posts/hello-there.md", "# Hi\n\nBody\n\n",
+			&models.ImportPost{Title: "hello-there", Body: "# Hi\n\nBody\n", MediaBase: "posts"}},
+		{"this site's export", "2020/05/post.md", "---\ntitle: Post\nsubtitle: Sub\nslug: 2020/05/post\ntags:\n- go\n- news\n" +
+			"hidden: true\ncreatedAt: 2020-05-06T07:08:09Z\nauthor: jane\nfeatureImage: /assets/images/a.jpg\nexcerpt: Short\n" +
+			"contributors:\n- username: jane\n  role: author\n- username: sam\n  role: editor\n---\n\nBody\n",
+			&models.ImportPost{
+				Title: "Post", Subtitle: "Sub", Slug: "2020/05/post", Body: "Body\n", Excerpt: "Short",
+				Tags: []string{"go", "news"}, Hidden: true, CreatedAt: time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC),
+				Author: &models.ImportAuthor{Username: "jane"}, FeatureImage: "/assets/images/a.jpg", MediaBase: "2020/05",
+				Contributors: []*models.ImportContributor{
+					{Author: &models.ImportAuthor{Username: "jane"}, Role: "author"},
+					{Author: &models.ImportAuthor{Username: "sam"}, Role: "editor"},
+				},
+			}},
+		{"static site generator keys", "content/post.markdown", "---\ntitle: Hugo\ntags: go,news\ncategories: [dev]\n" +
+			"draft: true\ndate: 2021-01-02\nimage: cover.png\ndescription: Described\n---\nBody",
+			&models.ImportPost{
+				Title: "Hugo", Body: "Body\n", Excerpt: "Described", Tags: []string{"go", "news", "dev"}, Hidden: true,
+				CreatedAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), FeatureImage: "cover.png", MediaBase: "content",
+			}},
+		{"hidden overrides draft", "hidden.md", hidden,
+			&models.ImportPost{Title: "Hidden", Body: "body\n", MediaBase: "."}},
+		{"byte order mark and CRLF", "bom.md", "\xef\xbb\xbf---\r\ntitle: BOM\r\n---\r\nBody\r\n",
+			&models.ImportPost{Title: "BOM", Body: "Body\n", MediaBase: "."}},
+		{"thematic break isn't front matter", "rule.md", "Intro\n\n---\n\ntitle: no\n",
+			&models.ImportPost{Title: "rule", Body: "Intro\n\n---\n\ntitle: no\n", MediaBase: "."}},
+	}
+	for _, c := range cases {
+		post, err := readMarkdownPost(c.file, []byte(c.source))
+		if err != nil {
+			t.Errorf("%v: readMarkdownPost failed: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(post, c.want) {
+			t.Errorf("%v: readMarkdownPost = %+v, want %+v", c.name, post, c.want)
+		}
+	}
+
+	if _, err := readMarkdownPost("bad.md", []byte("---\ntitle: [unclosed\n---\nbody")); err == nil || !strings.HasPrefix(err.Error(), "bad.md: ") {
+		t.Errorf("readMarkdownPost of invalid front matter = %v, want an error naming the file", err)
+	}
+}
+
+// Returns a zip archive with the files
+func testZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func TestReadMarkdownZip(t *testing.T) {
+	z := testZip(t, map[string]string{
+		"posts/":                    "",
+		"posts/2020/hello.md":       "---\ntitle: Hello\nauthor: jane\ncontributors:\n- username: jane\n  role: author\n- username: sam\n  role: editor\n---\nBody",
+		"__MACOSX/posts/._hello.md": "resource fork",
+		"notes.txt":                 "not a post",
+		"users.json":                `[{"name": "Jane", "email": "jane@example.com", "username": "jane"}, {"name": "Sam", "username": "sam"}]`,
+	})
+	posts, err := ReadMarkdownZip(z)
+	if err != nil {
+		t.Fatalf("ReadMarkdownZip failed: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("ReadMarkdownZip read %v posts, want 1", len(posts))
+	}
+	post := posts[0]
+	jane := &models.ImportAuthor{Username: "jane", Name: "Jane", Email: "jane@example.com"}
+	sam := &models.ImportAuthor{Username: "sam", Name: "Sam"}
+	cases := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"title", post.Title, "Hello"},
+		{"media base", post.MediaBase, "posts/2020"},
+		{"author from users.json", post.Author, jane},
+		{"first contributor from users.json", post.Contributors[0].Author, jane},
+		{"second contributor from users.json", post.Contributors[1].Author, sam},
+	}
+	for _, c := range cases {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%v = %+v, want %+v", c.name, c.got, c.want)
+		}
+	}
+
+	large := testZip(t, map[string]string{"large.md": strings.Repeat("a", maxImportFileSize+1)})
+	if _, err := ReadMarkdownZip(large); err == nil || !strings.Contains(err.Error(), "larger than 10MB") {
+		t.Errorf("ReadMarkdownZip of a large file = %v, want a size error", err)
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	cases := map[string]string{
+		"export.xml":       models.ImportWXR,
+		"ghost.JSON":       models.ImportGhost,
+		"posts.zip":        models.ImportMarkdown,
+		"post.md":          "",
+		"no-extension":     "",
+		"archive.tar.gz":   "",
+		"dir.zip/file.txt": "",
+	}
+	for name, want := range cases {
+		if got := ImportFormat(name); got != want {
+			t.Errorf("ImportFormat(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestReadImport(t *testing.T) {
+	wxr := strings.NewReader(testWXR)
+	posts, source, err := ReadImport(models.ImportWXR, wxr, wxr.Size())
+	if err != nil || len(posts) != 2 || source != nil {
+		t.Errorf("ReadImport of WXR read %v posts and source %v with error %v, want 2 posts", len(posts), source, err)
+	}
+
+	notZip := strings.NewReader("not a zip")
+	if _, _, err := ReadImport(models.ImportMarkdown, notZip, notZip.Size()); err == nil {
+		t.Errorf("ReadImport of an invalid archive succeeded, want an error")
+	}
+	if _, _, err := ReadImport("rss", notZip, notZip.Size()); err == nil || err.Error() != `unknown import format "rss"` {
+		t.Errorf("ReadImport of an unknown format = %v, want an unknown format error", err)
+	}
+}
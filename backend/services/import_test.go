@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/gofrs/uuid"
+)
+
+// Stores posts in memory. Calls to the methods the importer doesn't use panic.
+type fakePostRepository struct {
+	repositories.PostRepository
+	slugs   map[string]bool
+	created []*models.Post
+}
+
+func (pr *fakePostRepository) Exists(ctx context.Context, slug string) bool {
+	return pr.slugs[slug]
+}
+
+func (pr *fakePostRepository) Create(ctx context.Context, p *models.Post) error {
+	pr.created = append(pr.created, p)
+	p.ID = len(pr.created)
+	pr.slugs[p.Slug] = true
+	return nil
+}
+
+func (pr *fakePostRepository) SetAuthors(ctx context.Context, postID int, authors []*models.PostAuthor) (int, error) {
+	return len(authors), nil
+}
+
+func TestImportSlug(t *testing.T) {
+	createdAt := time.Date(2020, 5, 6, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		slug  string
+		title string
+		want  string
+	}{
+		{"valid slug is kept", "hello-world", "Title", "hello-world"},
+		{"nested slug is kept", "/2019/03/hello/", "Title", "2019/03/hello"},
+		{"invalid slug is generated from itself", "Hello World!", "Title", "hello-world"},
+		{"missing slug is generated from the title", "", "Héllo Wörld", "hello-world"},
+		{"slug without letters or digits uses the title", "!!!", "Title", "title"},
+		{"numeric slug is prefixed with the date", "123", "Title", "2020/05/123"},
+		{"route name is prefixed with the date", "search", "Title", "2020/05/search"},
+		{"admin path is prefixed with the date", "admin/posts", "Title", "2020/05/admin/posts"},
+		{"no slug or title", "", "", ""},
+	}
+	for _, c := range cases {
+		if got := importSlug(c.slug, c.title, createdAt); got != c.want {
+			t.Errorf("%v: importSlug = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::248": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"fd00::1":              false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"224.0.0.1":            false,
+		"0.0.0.0":              false,
+		"::":                   false,
+	}
+	for ip, want := range cases {
+		if got := isPublicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("isPublicIP(%v) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestDownloadRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	defer server.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(server.URL+"/a.png", http.StatusFound))
+	defer redirect.Close()
+
+	cases := []struct {
+		name string
+		url  string
+	}{
+		{"loopback server", server.URL + "/a.png"},
+		{"redirect to loopback", redirect.URL},
+		{"private network", "http://10.0.0.1/a.png"},
+		{"IPv6 loopback", "http://[::1]/a.png"},
+		{"host name of a loopback address", "http://localhost/a.png"},
+		{"link-local metadata address", "http://169.254.169.254/latest/meta-data"},
+	}
+	im := NewImporter(nil, nil, nil, "", nil, false)
+	for _, c := range cases {
+		file := filepath.Join(t.TempDir(), "a.png")
+		err := im.download(c.url, file)
+		if err == nil || !strings.Contains(err.Error(), "download from a private address") {
+			t.Errorf("%v: download = %v, want a private address error", c.name, err)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	author := &models.User{ID: uuid.Must(uuid.NewV4()), Username: "admin", Name: "Admin"}
+	posts := []*models.ImportPost{
+		{Title: "First", Slug: "first", Body: "Body", Tags: []string{"go", " go ", "News"}},
+		{Title: "Same slug", Slug: "first", Body: "Body"},
+		{Title: "Taken", Slug: "taken", Body: "Body"},
+		{Title: "  ", Body: "Body"},
+		{Title: "Empty", Body: " \n "},
+		{Title: "Private media", Body: "![local](http://127.0.0.1/secret.png)"},
+	}
+	want := []struct {
+		slug   string
+		status string
+		reason string
+	}{
+		{"first", models.ImportCreated, ""},
+		{"first", models.ImportSkipped, "A post with this slug already exists"},
+		{"taken", models.ImportSkipped, "A post with this slug already exists"},
+		{"", models.ImportFailed, "Title is required"},
+		{"empty", models.ImportFailed, "Body is empty"},
+		{"private-media", models.ImportCreated, ""},
+	}
+
+	repo := &fakePostRepository{slugs: map[string]bool{"taken": true}}
+	report := NewImporter(repo, nil, author, "/default.png", nil, false).Import(context.Background(), models.ImportMarkdown, posts)
+	if len(report.Posts) != len(want) {
+		t.Fatalf("report has %v posts, want %v", len(report.Posts), len(want))
+	}
+	for i, w := range want {
+		got := report.Posts[i]
+		if got.Slug != w.slug || got.Status != w.status || got.Reason != w.reason {
+			t.Errorf("post %v = %+v, want slug %q, status %v and reason %q", i, got, w.slug, w.status, w.reason)
+		}
+	}
+	if report.Created != 2 || report.Skipped != 2 || report.Failed != 2 {
+		t.Errorf("report counts %v created, %v skipped and %v failed, want 2, 2 and 2", report.Created, report.Skipped, report.Failed)
+	}
+	if len(repo.created) != 2 {
+		t.Fatalf("created %v posts, want 2", len(repo.created))
+	}
+
+	first, private := repo.created[0], repo.created[1]
+	if strings.Join(first.Tags, ",") != "go,News" || first.AuthorID != author.ID.String() || first.FeatureImgURL != "/default.png" {
+		t.Errorf("first post has tags %v, author %v and image %v, want go,News, %v and /default.png", first.Tags, first.AuthorID, first.FeatureImgURL, author.ID)
+	}
+	if private.Body != posts[5].Body {
+		t.Errorf("body with private media = %q, want it unchanged", private.Body)
+	}
+	if len(report.Warnings) != 1 || !strings.HasPrefix(report.Warnings[0], "Could not store media http://127.0.0.1/secret.png: ") ||
+		!strings.Contains(report.Warnings[0], "download from a private address 127.0.0.1") {
+		t.Errorf("warnings = %q, want one private address warning", report.Warnings)
+	}
+
+	// Importing again only skips
+	again := NewImporter(repo, nil, author, "", nil, false).Import(context.Background(), models.ImportMarkdown, posts[:3])
+	if again.Created != 0 || again.Skipped != 3 || len(repo.created) != 2 {
+		t.Errorf("importing again created %v and skipped %v posts, want 0 and 3", again.Created, again.Skipped)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	author := &models.User{ID: uuid.Must(uuid.NewV4()), Username: "admin"}
+	posts := []*models.ImportPost{
+		{Title: "One", Body: "Body", FeatureImage: "https://example.com/a.png"},
+		{Title: "One", Body: "Body"},
+	}
+
+	repo := &fakePostRepository{slugs: map[string]bool{}}
+	report := NewImporter(repo, nil, author, "", nil, true).Import(context.Background(), models.ImportGhost, posts)
+	if !report.DryRun || report.Created != 1 || report.Skipped != 1 || report.Posts[0].Status != models.ImportWouldCreate {
+		t.Errorf("dry run report = %+v, want one post that would be created and one skipped", report)
+	}
+	if len(repo.created) != 0 {
+		t.Errorf("dry run created %v posts, want 0", len(repo.created))
+	}
+	if len(report.Warnings) != 0 || len(report.Media) != 1 || !strings.HasPrefix(report.Media[0], "/assets/images/") {
+		t.Errorf("dry run media = %v with warnings %q, want one image in the media store and no warnings", report.Media, report.Warnings)
+	}
+}
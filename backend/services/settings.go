@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alanqchen/Bear-Post/backend/config"
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+	"github.com/alanqchen/Bear-Post/backend/util"
+)
+
+// SettingsReloadInterval is how long the settings are cached before they are read from the database
+// again, so changes made through another process are picked up
+const SettingsReloadInterval = time.Minute
+
+// Upper bound of the posts per page settings
+const maxPostsPerPageLimit = 50
+
+var socialLinkNameRegex = regexp.MustCompile(`^[a-z0-9\-]{1,32}$`)
+
+// SettingsService caches the site settings and validates changes to them
+type SettingsService struct {
+	repo     repositories.SettingsRepository
+	defaults models.SiteSettings
+	mu       sync.RWMutex
+	current  models.SiteSettings
+	loadedAt time.Time
+}
+
+// NewSettingsService returns a new settings service. The allowed origins in the config are used
+// until they are changed through the API.
+func NewSettingsService(sr repositories.SettingsRepository, cfg *config.Config) *SettingsService {
+	defaults := models.SiteSettings{
+		Title:                  "Bear Post",
+		Description:            "",
+		DefaultFeatureImageURL: "/assets/images/feature-default.png",
+		PostsPerPage:           5,
+		MinPostsPerPage:        1,
+		MaxPostsPerPage:        10,
+		SocialLinks:            map[string]string{},
+		AllowedOrigins:         cfg.AllowedOrigins,
+	}
+	if defaults.AllowedOrigins == nil {
+		defaults.AllowedOrigins = []string{}
+	}
+
+	s := &SettingsService{repo: sr, defaults: defaults, current: copySettings(defaults)}
+	if err := s.Reload(context.Background()); err != nil {
+		slog.Warn("Failed to load site settings, using defaults")
+	}
+	return s
+}
+
+// Get returns a copy of the current settings
+func (s *SettingsService) Get() models.SiteSettings {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > SettingsReloadInterval
+	settings := copySettings(s.current)
+	s.mu.RUnlock()
+
+	// The cache is shared by every request, so one canceled request doesn't stop the reload
+	if stale {
+		if err := s.Reload(context.Background()); err != nil {
+			slog.Warn("Failed to reload site settings")
+			return settings
+		}
+		return s.Get()
+	}
+
+	return settings
+}
+
+// Reload reads the settings from the database. Stored keys that are unknown or of the wrong type
+// are ignored, and the defaults are used if the stored settings are invalid together.
+func (s *SettingsService) Reload(ctx context.Context) error {
+	values, updatedAt, err := s.repo.GetAll(ctx)
+	if err != nil {
+		// Don't retry on every request while the database is down
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return err
+	}
+
+	settings := copySettings(s.defaults)
+	for key, value := range values {
+		if err := applySetting(&settings, key, value); err != nil {
+			slog.WarnContext(ctx, "Ignoring stored site setting", "key", key, "err", err)
+		}
+	}
+	if err := validateSettings(&settings); err != nil {
+		slog.WarnContext(ctx, "Stored site settings are invalid, using defaults", "err", err)
+		settings = copySettings(s.defaults)
+	}
+	settings.UpdatedAt = updatedAt
+
+	s.mu.Lock()
+	s.current = settings
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Apply returns the current settings with the given changes applied. Returns an error describing
+// the first unknown key or invalid value.
+func (s *SettingsService) Apply(changes map[string]json.RawMessage) (*models.SiteSettings, error) {
+	settings := s.Get()
+	for key, value := range changes {
+		if err := applySetting(&settings, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateSettings(&settings); err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// Save stores the settings that differ from the current ones and uses them right away. Unchanged
+// settings that were never saved keep following their default.
+func (s *SettingsService) Save(ctx context.Context, settings *models.SiteSettings) error {
+	now := time.Now()
+	values, err := settingValues(settings)
+	if err != nil {
+		return err
+	}
+	current := s.Get()
+	currentValues, err := settingValues(&current)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if bytes.Equal(value, currentValues[key]) {
+			delete(values, key)
+		}
+	}
+
+	if err := s.repo.Save(ctx, values, now); err != nil {
+		return err
+	}
+
+	saved := copySettings(*settings)
+	saved.UpdatedAt = &now
+
+	s.mu.Lock()
+	s.current = saved
+	s.loadedAt = now
+	s.mu.Unlock()
+
+	return nil
+}
+
+// IsAllowedOrigin returns true if the CORS origin is in the allowed origins setting
+func (s *SettingsService) IsAllowedOrigin(origin string) bool {
+	for _, allowed := range s.Get().AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets a single setting from its JSON value. Unknown keys and values of the wrong type are errors.
+func applySetting(settings *models.SiteSettings, key string, value json.RawMessage) error {
+	if key == "updatedAt" {
+		return errors.New("Unknown setting: " + key)
+	}
+
+	// Maps and slices are replaced instead of merged
+	switch key {
+	case "socialLinks":
+		settings.SocialLinks = nil
+	case "allowedOrigins":
+		settings.AllowedOrigins = nil
+	}
+
+	object, err := json.Marshal(map[string]json.RawMessage{key: value})
+	if err != nil {
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(object))
+	d.DisallowUnknownFields()
+	if err := d.Decode(settings); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			return errors.New("Unknown setting: " + key)
+		}
+		return errors.New("Invalid value for setting: " + key)
+	}
+
+	return nil
+}
+
+// Returns an error describing the first invalid setting
+func validateSettings(s *models.SiteSettings) error {
+	if s.Title == "" || utf8.RuneCountInString(s.Title) > 100 {
+		return errors.New("Title must be between 1 and 100 characters")
+	}
+	if utf8.RuneCountInString(s.Description) > 500 {
+		return errors.New("Description must be at most 500 characters")
+	}
+	if !isImageURL(s.DefaultFeatureImageURL) {
+		return errors.New("Default feature image must be a path starting with / or a http(s) URL")
+	}
+
+	if s.MinPostsPerPage < 1 || s.MaxPostsPerPage > maxPostsPerPageLimit || s.MinPostsPerPage > s.MaxPostsPerPage {
+		return fmt.Errorf("Posts per page bounds must be within [1, %v]", maxPostsPerPageLimit)
+	}
+	if s.PostsPerPage < s.MinPostsPerPage || s.PostsPerPage > s.MaxPostsPerPage {
+		return errors.New("Posts per page must be within the posts per page bounds")
+	}
+
+	if s.SocialLinks == nil {
+		s.SocialLinks = map[string]string{}
+	}
+	for name, link := range s.SocialLinks {
+		if !socialLinkNameRegex.MatchString(name) {
+			return errors.New("Social link names may only contain lowercase letters, numbers and dashes")
+		}
+		if !util.IsHTTPURL(link) {
+			return errors.New("Social link " + name + " must be a http(s) URL")
+		}
+	}
+
+	if s.AllowedOrigins == nil {
+		s.AllowedOrigins = []string{}
+	}
+	for _, origin := range s.AllowedOrigins {
+		if origin != "*" && !isOrigin(origin) {
+			return errors.New("Allowed origin " + origin + " must be * or a scheme and host such as https://example.com")
+		}
+	}
+
+	return nil
+}
+
+// Returns the JSON value of every setting by key
+func settingValues(settings *models.SiteSettings) (map[string][]byte, error) {
+	object, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(object, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "updatedAt")
+
+	values := make(map[string][]byte, len(fields))
+	for key, value := range fields {
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// Returns a deep copy of the settings so callers can't modify the cached maps and slices
+func copySettings(s models.SiteSettings) models.SiteSettings {
+	links := make(map[string]string, len(s.SocialLinks))
+	for name, link := range s.SocialLinks {
+		links[name] = link
+	}
+	s.SocialLinks = links
+	s.AllowedOrigins = append([]string{}, s.AllowedOrigins...)
+	return s
+}
+
+// Returns true if the image is a path on this server or an absolute http(s) URL
+func isImageURL(link string) bool {
+	if strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//") {
+		return true
+	}
+	return util.IsHTTPURL(link)
+}
+
+// Returns true if the origin is only a scheme and host, as sent in the Origin header
+func isOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/alanqchen/Bear-Post/backend/repositories"
+)
+
+// TrashPurgeInterval is how often posts and users past the trash retention are purged
+const TrashPurgeInterval = time.Hour
+
+// RunTrashPurge permanently deletes the posts and users that have been in the trash for longer
+// than the retention, now and then every TrashPurgeInterval until the context is done. Returns
+// right away if retention is 0.
+func RunTrashPurge(ctx context.Context, pr repositories.PostRepository, ur repositories.UserRepository, retention time.Duration) {
+	if retention <= 0 {
+		slog.InfoContext(ctx, "Trash purge is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(TrashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		purgeTrash(ctx, pr, ur, time.Now().Add(-retention))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purges the posts and users moved to the trash before the cutoff
+func purgeTrash(ctx context.Context, pr repositories.PostRepository, ur repositories.UserRepository, cutoff time.Time) {
+	posts, err := pr.PurgeDeletedBefore(ctx, cutoff)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to purge posts from the trash", "err", err)
+	} else if posts > 0 {
+		slog.InfoContext(ctx, "Purged posts from the trash", "posts", posts)
+	}
+
+	users, err := ur.PurgeDeletedBefore(ctx, cutoff)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to purge users from the trash", "err", err)
+	} else if users > 0 {
+		slog.InfoContext(ctx, "Purged users from the trash", "users", users)
+	}
+}
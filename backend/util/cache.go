@@ -0,0 +1,57 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redis keys of the caches
+const (
+	PageCacheKey      = "page-hash"
+	AdminPageCacheKey = "admin-page-hash"
+	IDCacheKey        = "ID-hash"
+	AdminSlugCacheKey = "admin-slug-hash"
+	MenusCacheKey     = "menus-cache"
+	// Followed by the ID of the locked post
+	EditLockKeyPrefix = "edit-lock:"
+	// Followed by the slug of the cached post
+	slugCacheKeyPrefix = "slug:"
+)
+
+var slugLikeTagRegex = regexp.MustCompile(`^\d{4}/\d{2}/.`)
+
+// PostCacheKeys returns the keys of the caches that contain every post, which are flushed whenever
+// a post changes
+func PostCacheKeys() []string {
+	return []string{PageCacheKey, AdminPageCacheKey, IDCacheKey, AdminSlugCacheKey}
+}
+
+// SlugCacheKey returns the key of the cached public post with the slug. Slugs are chosen by users,
+// so they are prefixed to keep them apart from the other caches and the tag pagination hashes.
+func SlugCacheKey(slug string) string {
+	return slugCacheKeyPrefix + slug
+}
+
+// SlugCacheKeys returns the keys of the cached public posts with the slugs
+func SlugCacheKeys(slugs []string) []string {
+	keys := make([]string, len(slugs))
+	for i, slug := range slugs {
+		keys[i] = SlugCacheKey(slug)
+	}
+	return keys
+}
+
+// IsReservedCacheKey returns true if the name is the key of a cache or starts with a cache's prefix
+func IsReservedCacheKey(name string) bool {
+	switch name {
+	case PageCacheKey, AdminPageCacheKey, IDCacheKey, AdminSlugCacheKey, MenusCacheKey:
+		return true
+	}
+	return strings.HasPrefix(name, EditLockKeyPrefix) || strings.HasPrefix(name, slugCacheKeyPrefix)
+}
+
+// CheckTag returns true if the tag can be used, since the pagination hash of each tag is keyed by
+// its name
+func CheckTag(tag string) bool {
+	return !IsReservedCacheKey(tag) && !slugLikeTagRegex.MatchString(tag)
+}
@@ -0,0 +1,383 @@
+package util
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	blankLinesRegex     = regexp.MustCompile(`\n{3,}`)
+	markdownEscapeRegex = regexp.MustCompile("([\\\\`*_\\[\\]#<>])")
+)
+
+// HTMLToMarkdown converts the HTML of an imported post to Markdown. Elements without a Markdown
+// equivalent are replaced by their text, and scripts, styles and comments are dropped.
+func HTMLToMarkdown(source string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", err
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	c := &markdownConverter{}
+	c.children(body)
+
+	md := blankLinesRegex.ReplaceAllString(c.b.String(), "\n\n")
+	return strings.TrimSpace(md) + "\n", nil
+}
+
+// Walks the parsed HTML and writes its Markdown
+type markdownConverter struct {
+	b strings.Builder
+	// Prefix of every line inside blockquotes and list items
+	prefix string
+}
+
+// Writes a block element, separated from the previous block by a blank line
+func (c *markdownConverter) block(n *html.Node) {
+	if n.Type == html.TextNode {
+		if text := strings.TrimSpace(n.Data); text != "" {
+			c.paragraph(n)
+		}
+		return
+	}
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title:
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level, _ := strconv.Atoi(n.Data[1:])
+		c.line(strings.Repeat("#", level) + " " + strings.ReplaceAll(c.inlineText(n), "  \n", " "))
+		c.blank()
+	case atom.P, atom.Figcaption:
+		c.paragraph(n)
+	case atom.Hr:
+		c.line("---")
+		c.blank()
+	case atom.Pre:
+		lang := ""
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.DataAtom == atom.Code {
+				lang = strings.TrimPrefix(attr(child, "class"), "language-")
+			}
+		}
+		c.line("```" + lang)
+		for _, l := range strings.Split(strings.TrimRight(textContent(n), "\n"), "\n") {
+			c.line(l)
+		}
+		c.line("```")
+		c.blank()
+	case atom.Blockquote:
+		prefix := c.prefix
+		c.prefix += "> "
+		c.children(n)
+		c.trimBlank()
+		c.prefix = prefix
+		c.blank()
+	case atom.Ul, atom.Ol:
+		c.list(n)
+		c.blank()
+	case atom.Table:
+		c.table(n)
+		c.blank()
+	case atom.Img:
+		c.line(c.inlineText(n))
+		c.blank()
+	default:
+		// Containers such as div, figure and section
+		if hasBlockChild(n) {
+			c.children(n)
+		} else {
+			c.paragraph(n)
+		}
+	}
+}
+
+// Writes the children of the node as blocks, wrapping runs of inline children in paragraphs
+func (c *markdownConverter) children(n *html.Node) {
+	var inline []*html.Node
+	flush := func() {
+		if len(inline) == 0 {
+			return
+		}
+		var text strings.Builder
+		for _, child := range inline {
+			text.WriteString(c.inline(child))
+		}
+		if t := strings.TrimSpace(text.String()); t != "" {
+			c.text(t)
+			c.blank()
+		}
+		inline = nil
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if isBlock(child) {
+			flush()
+			c.block(child)
+		} else {
+			inline = append(inline, child)
+		}
+	}
+	flush()
+}
+
+// Writes a paragraph of inline content
+func (c *markdownConverter) paragraph(n *html.Node) {
+	var text string
+	if n.Type == html.TextNode {
+		text = escapeMarkdown(collapseSpace(n.Data))
+	} else {
+		text = c.inlineText(n)
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		c.text(text)
+		c.blank()
+	}
+}
+
+// Writes a list, nesting sublists under their item
+func (c *markdownConverter) list(n *html.Node) {
+	i := 1
+	for item := n.FirstChild; item != nil; item = item.NextSibling {
+		if item.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(i) + ". "
+			i++
+		}
+
+		var text strings.Builder
+		var sublists []*html.Node
+		for child := item.FirstChild; child != nil; child = child.NextSibling {
+			if child.DataAtom == atom.Ul || child.DataAtom == atom.Ol {
+				sublists = append(sublists, child)
+				continue
+			}
+			text.WriteString(c.inline(child))
+		}
+		c.line(marker + strings.TrimSpace(text.String()))
+
+		prefix := c.prefix
+		c.prefix += strings.Repeat(" ", len(marker))
+		for _, sublist := range sublists {
+			c.list(sublist)
+		}
+		c.prefix = prefix
+	}
+}
+
+// Writes a GFM table. The first row is the header.
+func (c *markdownConverter) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.DataAtom == atom.Tr {
+			var cells []string
+			for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					cells = append(cells, strings.ReplaceAll(c.inlineText(cell), "|", "\\|"))
+				}
+			}
+			rows = append(rows, cells)
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		c.line("| " + strings.Join(row, " | ") + " |")
+		if i == 0 {
+			c.line("|" + strings.Repeat(" --- |", columns))
+		}
+	}
+}
+
+// Returns the Markdown of the node's inline content on one line
+func (c *markdownConverter) inlineText(n *html.Node) string {
+	var b strings.Builder
+	if n.DataAtom == atom.Img {
+		b.WriteString(c.inline(n))
+	} else {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			b.WriteString(c.inline(child))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Returns the Markdown of an inline node
+func (c *markdownConverter) inline(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return escapeMarkdown(collapseSpace(n.Data))
+	}
+	if n.Type != html.ElementNode {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style:
+		return ""
+	case atom.Br:
+		return "  \n"
+	case atom.Strong, atom.B:
+		return wrapInline("**", c.inlineText(n))
+	case atom.Em, atom.I:
+		return wrapInline("_", c.inlineText(n))
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline("~~", c.inlineText(n))
+	case atom.Code:
+		code := textContent(n)
+		fence := "`"
+		if strings.Contains(code, "`") {
+			fence = "``"
+		}
+		return fence + code + fence
+	case atom.A:
+		text := c.inlineText(n)
+		href := attr(n, "href")
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + markdownURL(href) + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + escapeMarkdown(attr(n, "alt")) + "](" + markdownURL(src) + ")"
+	default:
+		var b strings.Builder
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			b.WriteString(c.inline(child))
+		}
+		return b.String()
+	}
+}
+
+// Writes text, prefixing every line. Indentation is dropped so it isn't read as a code block.
+func (c *markdownConverter) text(text string) {
+	for _, l := range strings.Split(text, "\n") {
+		c.line(strings.TrimLeft(l, " "))
+	}
+}
+
+// Writes a line with the current prefix
+func (c *markdownConverter) line(l string) {
+	c.b.WriteString(c.prefix + l + "\n")
+}
+
+// Separates the next block with a blank line
+func (c *markdownConverter) blank() {
+	c.b.WriteString(strings.TrimRight(c.prefix, " ") + "\n")
+}
+
+// Removes the blank line written after the last block, so a blockquote doesn't end with an empty
+// quoted line
+func (c *markdownConverter) trimBlank() {
+	md := c.b.String()
+	if blank := strings.TrimRight(c.prefix, " ") + "\n"; strings.HasSuffix(md, "\n"+blank) {
+		c.b.Reset()
+		c.b.WriteString(strings.TrimSuffix(md, blank))
+	}
+}
+
+// Returns true if the node is rendered as a block
+func isBlock(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Ul, atom.Ol, atom.Pre,
+		atom.Blockquote, atom.Hr, atom.Table, atom.Figure, atom.Figcaption, atom.Section, atom.Article,
+		atom.Header, atom.Footer, atom.Aside, atom.Nav, atom.Main, atom.Script, atom.Style:
+		return true
+	}
+	return false
+}
+
+// Returns true if any child of the node is a block
+func hasBlockChild(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if isBlock(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the text of the node and its children as is
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// Returns the value of the node's attribute, or an empty string
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Wraps the text in the emphasis markers, keeping them out of surrounding whitespace
+func wrapInline(marker string, text string) string {
+	if text == "" {
+		return ""
+	}
+	return marker + text + marker
+}
+
+// Replaces runs of whitespace with a single space
+func collapseSpace(text string) string {
+	return whitespaceRegex.ReplaceAllString(text, " ")
+}
+
+// Escapes the characters that would be read as Markdown syntax
+func escapeMarkdown(text string) string {
+	return markdownEscapeRegex.ReplaceAllString(text, `\$1`)
+}
+
+// Escapes the characters that would end a Markdown link destination
+func markdownURL(url string) string {
+	url = strings.ReplaceAll(url, " ", "%20")
+	url = strings.ReplaceAll(url, "(", "%28")
+	return strings.ReplaceAll(url, ")", "%29")
+}
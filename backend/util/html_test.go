@@ -0,0 +1,43 @@
+package util
+
+import "testing"
+
+func TestHTMLToMarkdown(t *testing.T) {
+	cases := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"nested lists", "<ul><li>one<ul><li>nested <b>bold</b></li><li>two<ol><li>deep</li></ol></li></ul></li><li>three</li></ul>",
+			"- one\n  - nested **bold**\n  - two\n    1. deep\n- three\n"},
+		{"ordered list", "<ol><li>first</li><li>second</li></ol>", "1. first\n2. second\n"},
+		{"code block with language", "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"*hi*\")\n}\n</code></pre>",
+			"```go\nfunc main() {\n\tfmt.Println(\"*hi*\")\n}\n```\n"},
+		{"code block keeps indentation", "<pre>plain\n  indented</pre>", "```\nplain\n  indented\n```\n"},
+		{"inline code isn't escaped", "<p>Use <code>a*b</code> and <code>x`y</code></p>", "Use `a*b` and ``x`y``\n"},
+		{"links", `<p><a href="https://example.com/a b(c)">link <em>text</em></a> and <a href="https://example.com/">  </a> and <a>no href</a></p>`,
+			"[link _text_](https://example.com/a%20b%28c%29) and [https://example.com/](https://example.com/) and no href\n"},
+		{"images", `<p><img src="/media/cat.png" alt="a [cat]"> <img alt="no src"></p>`, "![a \\[cat\\]](/media/cat.png)\n"},
+		{"figure with caption", `<figure><img src="https://example.com/x.jpg" alt="x"><figcaption>Caption *here*</figcaption></figure>`,
+			"![x](https://example.com/x.jpg)\n\nCaption \\*here\\*\n"},
+		{"unknown tags keep their text", `<p>Hello <blink>unknown</blink> <custom-tag data-x="1">tag</custom-tag></p><marquee>block</marquee>`,
+			"Hello unknown tag\n\nblock\n"},
+		{"scripts, styles and comments are dropped", `<script>alert(1)</script><style>p{}</style><!-- comment --><p>kept</p>`, "kept\n"},
+		{"blockquote", `<blockquote><p>quote</p><p>two</p></blockquote><p>after</p>`, "> quote\n>\n> two\n\nafter\n"},
+		{"nested blockquote", `<blockquote><p>outer</p><blockquote><p>inner</p></blockquote></blockquote>`, "> outer\n>\n> > inner\n"},
+		{"heading", `<h2>Title  with <strong>bold</strong></h2>text after`, "## Title with **bold**\n\ntext after\n"},
+		{"table", `<table><tr><th>a</th><th>b|c</th></tr><tr><td>1</td></tr></table>`, "| a | b\\|c |\n| --- | --- |\n| 1 |  |\n"},
+		{"line break", `<p>line<br>break</p>`, "line  \nbreak\n"},
+		{"Markdown syntax in text is escaped", `# not a heading * and _`, "\\# not a heading \\* and \\_\n"},
+	}
+	for _, c := range cases {
+		got, err := HTMLToMarkdown(c.html)
+		if err != nil {
+			t.Errorf("%v: HTMLToMarkdown failed: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%v: HTMLToMarkdown = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
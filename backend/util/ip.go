@@ -0,0 +1,150 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// DefaultClientIPHeader is the header trusted proxies send the client IP in by default
+const DefaultClientIPHeader = "X-Forwarded-For"
+
+// DefaultTrustedProxies are the loopback networks, for a proxy on the same host. Proxies elsewhere
+// must be configured, since any host on a private network could otherwise spoof client IPs.
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// Resolves the client IP of requests
+type ipResolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// Set once at startup, before requests are served
+var clientIPResolver = mustNewIPResolver(DefaultTrustedProxies, DefaultClientIPHeader)
+
+// SetTrustedProxies sets the CIDRs or IPs of the proxies trusted to send the client IP, and the
+// header they send it in. X-Forwarded-For style headers can be a list of IPs, other headers a
+// single IP. A nil list trusts the DefaultTrustedProxies, and an empty header X-Forwarded-For.
+func SetTrustedProxies(proxies []string, header string) error {
+	if proxies == nil {
+		proxies = DefaultTrustedProxies
+	}
+	if header == "" {
+		header = DefaultClientIPHeader
+	}
+	resolver, err := newIPResolver(proxies, header)
+	if err != nil {
+		return err
+	}
+	clientIPResolver = resolver
+	return nil
+}
+
+func newIPResolver(proxies []string, header string) (*ipResolver, error) {
+	resolver := &ipResolver{header: http.CanonicalHeaderKey(header)}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q, must be an IP or CIDR", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			resolver.trusted = append(resolver.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q, must be an IP or CIDR", proxy)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+	return resolver, nil
+}
+
+func mustNewIPResolver(proxies []string, header string) *ipResolver {
+	resolver, err := newIPResolver(proxies, header)
+	if err != nil {
+		panic(err)
+	}
+	return resolver
+}
+
+// Checks if the IP is one of a trusted proxy
+func (ir *ipResolver) isTrusted(ip net.IP) bool {
+	for _, network := range ir.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the client IP of the request. The header is only read if the connection comes from a
+// trusted proxy. Lists are read from right to left, skipping the trusted proxies that added
+// themselves, so the IP is the one the last trusted proxy saw.
+func (ir *ipResolver) resolve(r *http.Request) net.IP {
+	peer := parseIP(r.RemoteAddr)
+	if peer == nil || !ir.isTrusted(peer) {
+		return peer
+	}
+
+	if ir.header != DefaultClientIPHeader {
+		if ip := parseIP(strings.TrimSpace(r.Header.Get(ir.header))); ip != nil {
+			return ip
+		}
+		return peer
+	}
+
+	// Proxies either append to the header or send another one
+	var hops []string
+	for _, value := range r.Header.Values(ir.header) {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// Hops before an invalid one can't be trusted
+			break
+		}
+		client = ip
+		if !ir.isTrusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// Parses an IP with or without a port, and IPv6 with or without brackets
+func parseIP(addr string) net.IP {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return net.ParseIP(host)
+	}
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return net.ParseIP(addr[1 : len(addr)-1])
+	}
+	return nil
+}
+
+// GetIP returns the client IP of the request, or "" if it is unknown. Headers are only trusted
+// when the connection comes from a trusted proxy.
+func GetIP(r *http.Request) string {
+	if ip := clientIPResolver.resolve(r); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// IsTrustedProxy returns if the connection of the request comes from a trusted proxy
+func IsTrustedProxy(r *http.Request) bool {
+	ip := parseIP(r.RemoteAddr)
+	return ip != nil && clientIPResolver.isTrusted(ip)
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseIP(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.7":             "203.0.113.7",
+		"203.0.113.7:8080":        "203.0.113.7",
+		"2001:db8::1":             "2001:db8::1",
+		"[2001:db8::1]":           "2001:db8::1",
+		"[2001:db8::1]:443":       "2001:db8::1",
+		"[::ffff:203.0.113.7]:80": "203.0.113.7",
+		"":                        "<nil>",
+		"unknown":                 "<nil>",
+		"[2001:db8::1":            "<nil>",
+	}
+	for addr, want := range cases {
+		if got := parseIP(addr).String(); got != want {
+			t.Errorf("parseIP(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestResolveForwardedFor(t *testing.T) {
+	resolver := mustNewIPResolver([]string{"127.0.0.1", "10.0.0.0/8", "2001:db8:1::/48"}, DefaultClientIPHeader)
+	cases := []struct {
+		name       string
+		remoteAddr string
+		headers    []string
+		want       string
+	}{
+		{"untrusted peer ignores the header", "198.51.100.1:5000", []string{"203.0.113.7"}, "198.51.100.1"},
+		{"private peer outside the trusted networks", "192.168.1.2:5000", []string{"203.0.113.7"}, "192.168.1.2"},
+		{"trusted peer without header", "127.0.0.1:5000", nil, "127.0.0.1"},
+		{"trusted peer", "127.0.0.1:5000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"trusted hops are skipped", "127.0.0.1:5000", []string{"203.0.113.7, 10.0.0.2, 10.0.0.3"}, "203.0.113.7"},
+		{"spoofed hops before the client are ignored", "127.0.0.1:5000", []string{"1.2.3.4, 203.0.113.7"}, "203.0.113.7"},
+		{"spoofed trusted hop before an untrusted one", "127.0.0.1:5000", []string{"10.0.0.9, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"invalid hop stops the chain", "127.0.0.1:5000", []string{"203.0.113.7, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"only trusted hops", "127.0.0.1:5000", []string{"10.0.0.2, 10.0.0.3"}, "10.0.0.2"},
+		{"headers are joined in order", "127.0.0.1:5000", []string{"1.2.3.4", "203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"bracketed IPv6 peer with port", "[2001:db8:1::5]:443", []string{"2001:db8:2::7"}, "2001:db8:2::7"},
+		{"untrusted IPv6 peer with port", "[2001:db8:2::5]:443", []string{"203.0.113.7"}, "2001:db8:2::5"},
+		{"bracketed IPv6 hops with ports", "127.0.0.1:5000", []string{"[2001:db8:2::7]:1234, [2001:db8:1::5]:80"}, "2001:db8:2::7"},
+		{"IPv4 hop with port", "127.0.0.1:5000", []string{"203.0.113.7:1234"}, "203.0.113.7"},
+	}
+	for _, c := range cases {
+		r := &http.Request{RemoteAddr: c.remoteAddr, Header: http.Header{}}
+		for _, value := range c.headers {
+			r.Header.Add(DefaultClientIPHeader, value)
+		}
+		if got := resolver.resolve(r).String(); got != c.want {
+			t.Errorf("%v: resolve = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestResolveSingleIPHeader(t *testing.T) {
+	resolver := mustNewIPResolver(DefaultTrustedProxies, "x-real-ip")
+	cases := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		want       string
+	}{
+		{"trusted peer", "127.0.0.1:5000", "203.0.113.7", "203.0.113.7"},
+		{"trusted IPv6 peer", "[::1]:5000", "[2001:db8::7]:80", "2001:db8::7"},
+		{"private peer isn't trusted by default", "10.0.0.2:5000", "203.0.113.7", "10.0.0.2"},
+		{"list isn't read", "127.0.0.1:5000", "203.0.113.7, 198.51.100.1", "127.0.0.1"},
+		{"missing header", "127.0.0.1:5000", "", "127.0.0.1"},
+	}
+	for _, c := range cases {
+		r := &http.Request{RemoteAddr: c.remoteAddr, Header: http.Header{}}
+		if c.header != "" {
+			r.Header.Set("X-Real-IP", c.header)
+		}
+		if got := resolver.resolve(r).String(); got != c.want {
+			t.Errorf("%v: resolve = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNewIPResolverRejectsInvalidProxies(t *testing.T) {
+	for _, proxy := range []string{"", "localhost", "10.0.0.0/33", "300.0.0.1"} {
+		if _, err := newIPResolver([]string{proxy}, DefaultClientIPHeader); err == nil {
+			t.Errorf("newIPResolver(%q) succeeded, want an error", proxy)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/alanqchen/Bear-Post/backend/models"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// RenderedMarkdown stores the sanitized HTML of a Markdown body along with its table of contents,
+// plain text and reading stats
+type RenderedMarkdown struct {
+	HTML        string
+	TOC         []models.TOCEntry
+	PlainText   string
+	Excerpt     string
+	WordCount   int
+	ReadingTime int
+}
+
+// Class of the anchor link appended to every heading
+const headingAnchorClass = "heading-anchor"
+
+// Raw HTML in the source is never rendered (goldmark's default), the policy is a second line of defense
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+var htmlPolicy = newHTMLPolicy()
+
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+// Returns the allow-list policy for rendered post bodies
+func newHTMLPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// Syntax highlighting classes for fenced code blocks
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[a-zA-Z0-9+#_\-]+$`)).OnElements("code")
+	// Heading anchors
+	p.AllowAttrs("class").Matching(regexp.MustCompile("^" + headingAnchorClass + "$")).OnElements("a")
+	// GFM task lists
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// RenderMarkdown renders the Markdown source to sanitized HTML with heading anchors and returns it
+// with the table of contents and plain text of the source
+func RenderMarkdown(source string) (*RenderedMarkdown, error) {
+	src := []byte(source)
+	doc := markdown.Parser().Parse(text.NewReader(src))
+
+	rendered := &RenderedMarkdown{
+		TOC:       []models.TOCEntry{},
+		PlainText: plainText(doc, src),
+	}
+	rendered.Excerpt = Excerpt(rendered.PlainText, ExcerptLength)
+	rendered.WordCount = CountWords(rendered.PlainText)
+	rendered.ReadingTime = ReadingTime(rendered.WordCount)
+
+	// Collect the headings first since anchors can't be inserted while walking
+	var headings []*ast.Heading
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			headings = append(headings, heading)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, heading := range headings {
+		id, ok := heading.AttributeString("id")
+		if !ok {
+			continue
+		}
+		idBytes, ok := id.([]byte)
+		if !ok {
+			continue
+		}
+		rendered.TOC = append(rendered.TOC, models.TOCEntry{
+			Level: heading.Level,
+			ID:    string(idBytes),
+			Text:  string(heading.Text(src)),
+		})
+
+		anchor := ast.NewLink()
+		anchor.Destination = append([]byte("#"), idBytes...)
+		anchor.SetAttributeString("class", []byte(headingAnchorClass))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+	rendered.HTML = htmlPolicy.Sanitize(buf.String())
+
+	return rendered, nil
+}
+
+// Returns the text content of the document. Code blocks, raw HTML and image descriptions are left out.
+func plainText(doc ast.Node, src []byte) string {
+	var b strings.Builder
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch node := n.(type) {
+		case *ast.Image, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				b.Write(node.Segment.Value(src))
+				if node.SoftLineBreak() || node.HardLineBreak() {
+					b.WriteByte(' ')
+				}
+			}
+		case *ast.String:
+			if entering {
+				b.Write(node.Value)
+			}
+		}
+		if !entering && n.Type() == ast.TypeBlock {
+			b.WriteByte(' ')
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(b.String(), " "))
+}
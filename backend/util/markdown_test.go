@@ -0,0 +1,69 @@
+package util
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"script block is removed", "<script>alert(1)</script>\n\ntext", "\n<p>text</p>\n"},
+		{"inline script tags are removed", "hi <script>alert(1)</script> there", "<p>hi alert(1) there</p>\n"},
+		{"javascript link is removed", "[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"mixed case javascript link is removed", "[click](JaVaScRiPt:alert(1))", "<p>click</p>\n"},
+		{"javascript image source is removed", "![alt](javascript:alert(1))", "<p><img alt=\"alt\"></p>\n"},
+		{"event handler attribute is removed", "<img src=x onerror=alert(1)>", "\n"},
+		{"language class is kept", "```go\nfmt.Println(1)\n```", "<pre><code class=\"language-go\">fmt.Println(1)\n</code></pre>\n"},
+		{"attributes after the language are dropped", "```go onclick=x\nfmt.Println(1)\n```", "<pre><code class=\"language-go\">fmt.Println(1)\n</code></pre>\n"},
+		{"task list checkbox is kept", "- [x] done", "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n</ul>\n"},
+	}
+	for _, c := range cases {
+		rendered, err := RenderMarkdown(c.source)
+		if err != nil {
+			t.Errorf("%v: RenderMarkdown failed: %v", c.name, err)
+			continue
+		}
+		if rendered.HTML != c.want {
+			t.Errorf("%v: HTML = %q, want %q", c.name, rendered.HTML, c.want)
+		}
+	}
+}
+
+func TestRenderMarkdownHeadingIDs(t *testing.T) {
+	rendered, err := RenderMarkdown("# Intro\n\n# Intro\n\n## Intro")
+	if err != nil {
+		t.Fatalf("RenderMarkdown failed: %v", err)
+	}
+	want := []string{"intro", "intro-1", "intro-2"}
+	if len(rendered.TOC) != len(want) {
+		t.Fatalf("TOC = %v, want IDs %v", rendered.TOC, want)
+	}
+	for i, entry := range rendered.TOC {
+		if entry.ID != want[i] || entry.Text != "Intro" {
+			t.Errorf("TOC[%v] = %+v, want ID %v and text Intro", i, entry, want[i])
+		}
+	}
+	wantHTML := "<h1 id=\"intro\">Intro<a href=\"#intro\" class=\"heading-anchor\" rel=\"nofollow\">#</a></h1>\n" +
+		"<h1 id=\"intro-1\">Intro<a href=\"#intro-1\" class=\"heading-anchor\" rel=\"nofollow\">#</a></h1>\n" +
+		"<h2 id=\"intro-2\">Intro<a href=\"#intro-2\" class=\"heading-anchor\" rel=\"nofollow\">#</a></h2>\n"
+	if rendered.HTML != wantHTML {
+		t.Errorf("HTML = %q, want %q", rendered.HTML, wantHTML)
+	}
+}
+
+func TestHTMLPolicy(t *testing.T) {
+	cases := map[string]string{
+		`<a href="javascript:alert(1)" onclick="x()">a</a>`:                         "a",
+		`<p onmouseover="x()">p</p>`:                                                "<p>p</p>",
+		`<script>x</script><img src="/a.png" onerror="x()">`:                        `<img src="/a.png">`,
+		`<code class="language-go">c</code>`:                                        `<code class="language-go">c</code>`,
+		`<code class="language-go evil">c</code>`:                                   "<code>c</code>",
+		`<a class="heading-anchor" href="#a">#</a><a class="other" href="#b">b</a>`: `<a class="heading-anchor" href="#a" rel="nofollow">#</a><a href="#b" rel="nofollow">b</a>`,
+	}
+	for html, want := range cases {
+		if got := htmlPolicy.Sanitize(html); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", html, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Average adult silent reading speed used for reading time estimates
+const wordsPerMinute = 200
+
+// ExcerptLength is the maximum number of characters in a generated excerpt
+const ExcerptLength = 250
+
+// CountWords returns the number of whitespace separated words in the text
+func CountWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// ReadingTime returns the estimated reading time in minutes for the number of words (at least 1)
+func ReadingTime(words int) int {
+	minutes := (words + wordsPerMinute - 1) / wordsPerMinute
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// Excerpt returns the text cut to at most maxLength characters without splitting a word.
+// An ellipsis is appended if the text was cut.
+func Excerpt(text string, maxLength int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= maxLength {
+		return string(runes)
+	}
+
+	// Leave room for the ellipsis and back up to the last word boundary
+	cut := maxLength - 1
+	for i := cut; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+
+	excerpt := strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	return excerpt + "…"
+}
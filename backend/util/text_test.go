@@ -0,0 +1,60 @@
+package util
+
+import "testing"
+
+func TestExcerpt(t *testing.T) {
+	cases := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"empty body", "", 10, ""},
+		{"only whitespace", "  \n\t ", 10, ""},
+		{"short text is kept", "short text", 20, "short text"},
+		{"surrounding whitespace is trimmed", "  padded  ", 20, "padded"},
+		{"text of the maximum length is kept", "abcde", 5, "abcde"},
+		{"cut at the word boundary", "the quick brown fox", 12, "the quick…"},
+		{"trailing punctuation is dropped", "Hello, world and more", 9, "Hello…"},
+		{"multibyte text is cut by characters", "héllo wörld ünïcode", 12, "héllo wörld…"},
+		{"text without spaces", "日本語のテキストです", 5, "日本語の…"},
+		{"single long word", "supercalifragilistic", 10, "supercali…"},
+	}
+	for _, c := range cases {
+		if got := Excerpt(c.text, c.maxLength); got != c.want {
+			t.Errorf("%v: Excerpt = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	cases := map[string]int{
+		"":                       0,
+		"  \n ":                  0,
+		"one":                    1,
+		"one two\tthree\nfour":   4,
+		"  spaced   out  words ": 3,
+		"héllo wörld":            2,
+		"日本語":                    1,
+	}
+	for text, want := range cases {
+		if got := CountWords(text); got != want {
+			t.Errorf("CountWords(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	cases := map[int]int{
+		0:    1,
+		1:    1,
+		200:  1,
+		201:  2,
+		1000: 5,
+	}
+	for words, want := range cases {
+		if got := ReadingTime(words); got != want {
+			t.Errorf("ReadingTime(%v) = %v, want %v", words, got, want)
+		}
+	}
+}